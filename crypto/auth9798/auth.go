@@ -0,0 +1,185 @@
+package auth9798
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/hash/hmac"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"gost_magma_cbc/crypto/mode"
+	"gost_magma_cbc/crypto/models"
+	"gost_magma_cbc/crypto/protocol"
+	"hash"
+	"io"
+	"time"
+)
+
+// Протоколы аутентификации сторон на общем симметричном ключе по схемам
+// ISO/IEC 9798-2 (с криптографической контрольной функцией вместо
+// шифрования, как в ISO/IEC 9798-4). A - клиент (доказывающая сторона),
+// B - сервер (проверяющая сторона):
+//
+//	1 проход:  A -> B: ID_A, TN_A, R_A, f_K(1 || TN_A || R_A || ID_B || ID_A)
+//	2 прохода: B -> A: R_B
+//	           A -> B: ID_A, f_K(2 || R_B || ID_B || ID_A)
+//	3 прохода: B -> A: R_B
+//	           A -> B: ID_A, R_A, f_K(3A || R_A || R_B || ID_B || ID_A)
+//	           B -> A: f_K(3B || R_B || R_A || ID_A || ID_B)
+//
+// Метки направления и оба идентификатора в контрольной функции
+// исключают атаку отражения.
+
+const PREFIX = "crypto:auth9798: "
+
+type Mechanism int
+
+const (
+	ONE_PASS   Mechanism = 1
+	TWO_PASS   Mechanism = 2
+	THREE_PASS Mechanism = 3
+)
+
+// Криптографическая контрольная функция.
+type MAC int
+
+const (
+	MAC_HMAC_STREEBOG256 MAC = 0
+	MAC_OMAC_KUZNYECHIK  MAC = 1
+	MAC_OMAC_MAGMA       MAC = 2
+)
+
+// Длина случайных чисел R_A, R_B.
+const NONCE_SIZE = 16
+
+// Длина общего ключа.
+const KEY_SIZE = 32
+
+// Допустимое расхождение меток времени по умолчанию.
+const DEFAULT_TIME_WINDOW = 30 * time.Second
+
+// Метки контрольной функции.
+const (
+	LABEL_ONE_PASS     = "9798 A->B 1"
+	LABEL_TWO_PASS     = "9798 A->B 2"
+	LABEL_THREE_PASS_A = "9798 A->B 3"
+	LABEL_THREE_PASS_B = "9798 B->A 3"
+)
+
+var (
+	ErrAuthFailed = errors.New(PREFIX + "authentication failed")
+	ErrReplay     = errors.New(PREFIX + "replayed value")
+	ErrStale      = errors.New(PREFIX + "timestamp out of window")
+	ErrUnexpected = errors.New(PREFIX + "unexpected message")
+)
+
+// Параметры стороны.
+type Config struct {
+	Mechanism Mechanism
+	MAC       MAC
+	// Собственный идентификатор.
+	ID []byte
+	// Источник случайных чисел; при nil используется hdrbg.Reader.
+	Rand io.Reader
+	// Часы; при nil используется time.Now.
+	Now func() time.Time
+	// Допустимое расхождение меток времени (1 проход).
+	TimeWindow time.Duration
+	// Кэш принятых значений, общий для всех сеансов стороны.
+	// Обязателен для сервера; для 1 прохода время хранения должно быть
+	// не меньше удвоенного TimeWindow (проверяется NewServer).
+	Cache *protocol.ReplayCache
+}
+
+func (c *Config) check() error {
+	if c == nil || len(c.ID) == 0 {
+		return errors.New(PREFIX + "empty identity")
+	}
+	if c.Mechanism < ONE_PASS || c.Mechanism > THREE_PASS {
+		return errors.New(PREFIX + "unknown mechanism")
+	}
+	if c.MAC < MAC_HMAC_STREEBOG256 || c.MAC > MAC_OMAC_MAGMA {
+		return errors.New(PREFIX + "unknown mac")
+	}
+	return nil
+}
+
+func (c *Config) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *Config) window() time.Duration {
+	if c.TimeWindow > 0 {
+		return c.TimeWindow
+	}
+	return DEFAULT_TIME_WINDOW
+}
+
+func (c *Config) nonce() ([]byte, error) {
+	rand := c.Rand
+	if rand == nil {
+		rand = hdrbg.Reader
+	}
+	b := make([]byte, NONCE_SIZE)
+	_, err := io.ReadFull(rand, b)
+	return b, err
+}
+
+// Ключ блочного шифра из строки байт (старший байт первым).
+func cipherKey(base models.BaseAlgorithm, k []byte) models.Key {
+	key := base.NewKey()
+	d := key.Data()
+	for i := range k {
+		d[len(k)-1-i] = k[i]
+	}
+	return key
+}
+
+// f_K(label || parts), части кодируются с длиной для однозначности.
+func (c *Config) mac(key []byte, label string, parts ...[]byte) ([]byte, error) {
+	var h hash.Hash
+	var err error
+	switch c.MAC {
+	case MAC_HMAC_STREEBOG256:
+		h = hmac.New(streebog.New256, key)
+	case MAC_OMAC_KUZNYECHIK:
+		base := kuznyechik.NewKuznyechik()
+		k := cipherKey(base, key)
+		defer k.Clear()
+		h, err = mode.NewOMAC(base, k, base.BlockLen())
+	case MAC_OMAC_MAGMA:
+		base := magma.NewMagma()
+		k := cipherKey(base, key)
+		defer k.Clear()
+		h, err = mode.NewOMAC(base, k, base.BlockLen())
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.Write([]byte(label))
+	for _, p := range parts {
+		h.Write(binary.BigEndian.AppendUint16(nil, uint16(len(p))))
+		h.Write(p)
+	}
+	return h.Sum(nil), nil
+}
+
+func (c *Config) checkMAC(key, mac []byte, label string, parts ...[]byte) error {
+	want, err := c.mac(key, label, parts...)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(want, mac) != 1 {
+		return ErrAuthFailed
+	}
+	return nil
+}
+
+func timestamp(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano()))
+}
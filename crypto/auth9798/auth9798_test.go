@@ -0,0 +1,256 @@
+package auth9798
+
+import (
+	"bytes"
+	"errors"
+	"gost_magma_cbc/crypto/protocol"
+	"net"
+	"testing"
+	"time"
+)
+
+var (
+	keyAB = bytes.Repeat([]byte{0x5a}, KEY_SIZE)
+	idA   = []byte("A")
+	idB   = []byte("B")
+)
+
+func lookupA(id []byte) ([]byte, error) {
+	if !bytes.Equal(id, idA) {
+		return nil, errors.New("unknown id")
+	}
+	return keyAB, nil
+}
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newPair(t *testing.T, m Mechanism, mac MAC, clientKey []byte) (*Client, *Server) {
+	t.Helper()
+	c, err := NewClient(&Config{Mechanism: m, MAC: mac, ID: idA}, idB, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewServer(&Config{Mechanism: m, MAC: mac, ID: idB, Cache: protocol.NewReplayCache(time.Minute)}, lookupA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, s
+}
+
+type result struct {
+	id  []byte
+	err error
+}
+
+// Обмен через net.Pipe.
+func exchange(c *Client, s *Server) (error, result) {
+	ca, cb := net.Pipe()
+	defer ca.Close()
+	defer cb.Close()
+
+	done := make(chan result)
+	go func() {
+		id, err := s.Run(cb)
+		if err != nil {
+			cb.Close()
+		}
+		done <- result{id, err}
+	}()
+	err := c.Run(ca)
+	if err != nil {
+		ca.Close()
+	}
+	return err, <-done
+}
+
+func TestExchange(t *testing.T) {
+	for _, m := range []Mechanism{ONE_PASS, TWO_PASS, THREE_PASS} {
+		for _, mac := range []MAC{MAC_HMAC_STREEBOG256, MAC_OMAC_KUZNYECHIK, MAC_OMAC_MAGMA} {
+			c, s := newPair(t, m, mac, keyAB)
+			cerr, sres := exchange(c, s)
+			if cerr != nil || sres.err != nil {
+				t.Fatalf("%d/%d: %v, %v", m, mac, cerr, sres.err)
+			}
+			if !bytes.Equal(sres.id, idA) || !c.Done() {
+				t.Errorf("%d/%d: peer is not authenticated", m, mac)
+			}
+		}
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	wrong := bytes.Repeat([]byte{0xa5}, KEY_SIZE)
+	for _, m := range []Mechanism{ONE_PASS, TWO_PASS, THREE_PASS} {
+		c, s := newPair(t, m, MAC_OMAC_KUZNYECHIK, wrong)
+		cerr, sres := exchange(c, s)
+		if !errors.Is(sres.err, ErrAuthFailed) {
+			t.Errorf("%d: server error %v", m, sres.err)
+		}
+		if sres.id != nil || s.PeerID() != nil {
+			t.Errorf("%d: peer id set after failure", m)
+		}
+		// При односторонней аутентификации клиент не узнаёт результат
+		if m == THREE_PASS && cerr == nil {
+			t.Errorf("%d: client succeeded", m)
+		}
+	}
+}
+
+func TestOnePassReplay(t *testing.T) {
+	clk := &clock{t: time.Unix(1700000000, 0)}
+	c, err := NewClient(&Config{Mechanism: ONE_PASS, ID: idA, Now: clk.now}, idB, keyAB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := protocol.NewReplayCache(20 * time.Second)
+	server := func() *Server {
+		s, err := NewServer(&Config{Mechanism: ONE_PASS, ID: idB, Now: clk.now,
+			TimeWindow: 10 * time.Second, Cache: cache}, lookupA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Start()
+		return s
+	}
+	if _, err := NewServer(&Config{Mechanism: ONE_PASS, ID: idB, TimeWindow: 10 * time.Second,
+		Cache: protocol.NewReplayCache(19 * time.Second)}, lookupA); err == nil {
+		t.Error("short cache ttl accepted")
+	}
+	token, err := c.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := server().Next(token); err != nil {
+		t.Fatal(err)
+	}
+	// Повтор токена в пределах окна
+	clk.t = clk.t.Add(5 * time.Second)
+	if _, err := server().Next(token); !errors.Is(err, ErrReplay) {
+		t.Errorf("replayed token: %v", err)
+	}
+	// Повтор после выхода из окна
+	clk.t = clk.t.Add(30 * time.Second)
+	if _, err := server().Next(token); !errors.Is(err, ErrStale) {
+		t.Errorf("stale token: %v", err)
+	}
+	// Метка времени из будущего
+	clk.t = time.Unix(1700000000, 0).Add(-time.Minute)
+	if _, err := server().Next(token); !errors.Is(err, ErrStale) {
+		t.Errorf("future token: %v", err)
+	}
+
+	// Изменённая метка времени
+	clk.t = time.Unix(1700000000, 0)
+	c2, _ := NewClient(&Config{Mechanism: ONE_PASS, ID: idA, Now: clk.now}, idB, keyAB)
+	token, _ = c2.Start()
+	token.Fields[1][7] ^= 1
+	if _, err := server().Next(token); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("modified timestamp: %v", err)
+	}
+}
+
+func TestTwoPassReplay(t *testing.T) {
+	c, s := newPair(t, TWO_PASS, MAC_HMAC_STREEBOG256, keyAB)
+	c.Start()
+	ch, _ := s.Start()
+	resp, err := c.Next(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Next(resp); err != nil {
+		t.Fatal(err)
+	}
+	// Старый ответ на новый вызов
+	_, s2 := newPair(t, TWO_PASS, MAC_HMAC_STREEBOG256, keyAB)
+	s2.Start()
+	if _, err := s2.Next(resp); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("replayed response: %v", err)
+	}
+	// Повторный вызов отклоняется клиентом с кэшем
+	cfg := &Config{Mechanism: TWO_PASS, ID: idA, Cache: protocol.NewReplayCache(time.Minute)}
+	c1, _ := NewClient(cfg, idB, keyAB)
+	c1.Start()
+	if _, err := c1.Next(ch); err != nil {
+		t.Fatal(err)
+	}
+	c2, _ := NewClient(cfg, idB, keyAB)
+	c2.Start()
+	if out, err := c2.Next(ch); !errors.Is(err, ErrReplay) || out == nil || out.Type != MSG_ABORT {
+		t.Errorf("repeated challenge: %v", err)
+	}
+}
+
+// Атака отражения: B одновременно сервер и клиент с ключом K_AB.
+// Нарушитель, выдающий себя за A, пересылает вызов сервера B клиенту B
+// и возвращает полученный ответ серверу.
+func TestReflection(t *testing.T) {
+	for _, m := range []Mechanism{TWO_PASS, THREE_PASS} {
+		server, err := NewServer(&Config{Mechanism: m, ID: idB, Cache: protocol.NewReplayCache(time.Minute)}, lookupA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := NewClient(&Config{Mechanism: m, ID: idB}, idA, keyAB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch, _ := server.Start()
+		client.Start()
+		resp, err := client.Next(ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Fields[0] = idA
+		if _, err := server.Next(resp); !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("%d: reflected response: %v", m, err)
+		}
+	}
+}
+
+func TestThreePassConfirm(t *testing.T) {
+	c, s := newPair(t, THREE_PASS, MAC_OMAC_MAGMA, keyAB)
+	c.Start()
+	ch, _ := s.Start()
+	resp, err := c.Next(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm, err := s.Next(resp)
+	if err != nil || confirm == nil || confirm.Type != MSG_CONFIRM {
+		t.Fatal(err)
+	}
+	confirm.Fields[0][0] ^= 1
+	if _, err := c.Next(confirm); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("modified confirm: %v", err)
+	}
+	if c.Done() {
+		t.Error("client done after failed confirm")
+	}
+
+	// Повтор R_A на сервере
+	_, s2 := newPair(t, THREE_PASS, MAC_OMAC_MAGMA, keyAB)
+	s2.config.Cache = s.config.Cache
+	s2.rb = s.rb
+	s2.state = stateWaitResponse
+	if out, err := s2.Next(resp); !errors.Is(err, ErrReplay) || out == nil || out.Type != MSG_ABORT {
+		t.Errorf("replayed R_A: %v", err)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	if _, err := NewServer(&Config{Mechanism: ONE_PASS, ID: idB}, lookupA); err == nil {
+		t.Error("server without cache accepted")
+	}
+	if _, err := NewClient(&Config{Mechanism: 4, ID: idA}, idB, keyAB); err == nil {
+		t.Error("unknown mechanism accepted")
+	}
+	if _, err := NewClient(&Config{Mechanism: ONE_PASS, ID: idA}, idB, keyAB[:16]); err == nil {
+		t.Error("short key accepted")
+	}
+}
@@ -0,0 +1,24 @@
+package auth9798
+
+import "gost_magma_cbc/crypto/protocol"
+
+// Типы сообщений.
+const (
+	MSG_TOKEN     byte = 1 // A -> B (1 проход): ID_A, TN_A, R_A, MAC
+	MSG_CHALLENGE byte = 2 // B -> A: R_B
+	MSG_RESPONSE  byte = 3 // A -> B: ID_A, [R_A,] MAC
+	MSG_CONFIRM   byte = 4 // B -> A (3 прохода): MAC
+	MSG_ABORT     byte = 5
+)
+
+// Максимальная длина кадра сообщения.
+const MAX_MESSAGE_SIZE = 1024
+
+type Message = protocol.Message
+
+var proto = &protocol.Protocol{
+	MaxSize:       MAX_MESSAGE_SIZE,
+	Abort:         MSG_ABORT,
+	ErrAbort:      ErrAuthFailed,
+	ErrUnexpected: ErrUnexpected,
+}
@@ -0,0 +1,346 @@
+package auth9798
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+type state int
+
+const (
+	stateStart state = iota
+	stateWaitChallenge
+	stateWaitResponse
+	stateWaitConfirm
+	stateDone
+	stateFailed
+)
+
+// Доказывающая сторона A.
+type Client struct {
+	config   *Config
+	serverID []byte
+	key      []byte
+	state    state
+	rb       []byte
+	ra       []byte
+}
+
+// serverID - идентификатор проверяющей стороны, key - общий с ней ключ.
+func NewClient(config *Config, serverID, key []byte) (*Client, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	if len(key) != KEY_SIZE || len(serverID) == 0 {
+		return nil, errors.New(PREFIX + "invalid server id or key")
+	}
+	return &Client{config: config, serverID: serverID, key: key}, nil
+}
+
+// Первое сообщение клиента: токен для 1 прохода, для остальных схем -
+// nil (клиент ожидает вызов сервера).
+func (c *Client) Start() (*Message, error) {
+	if c.state != stateStart {
+		return nil, ErrUnexpected
+	}
+	if c.config.Mechanism != ONE_PASS {
+		c.state = stateWaitChallenge
+		return nil, nil
+	}
+	ts := timestamp(c.config.now())
+	ra, err := c.config.nonce()
+	if err != nil {
+		return nil, err
+	}
+	mac, err := c.config.mac(c.key, LABEL_ONE_PASS, ts, ra, c.serverID, c.config.ID)
+	if err != nil {
+		return nil, err
+	}
+	c.state = stateDone
+	return &Message{Type: MSG_TOKEN, Fields: [][]byte{c.config.ID, ts, ra, mac}}, nil
+}
+
+// Обработка сообщения сервера. При ошибке, если сервер ожидает ответа,
+// возвращается MSG_ABORT.
+func (c *Client) Next(m *Message) (*Message, error) {
+	var out *Message
+	var err error
+	switch c.state {
+	case stateWaitChallenge:
+		out, err = c.onChallenge(m)
+	case stateWaitConfirm:
+		if err = c.onConfirm(m); err != nil {
+			c.state = stateFailed
+		}
+		return nil, err
+	default:
+		return nil, ErrUnexpected
+	}
+	if err != nil {
+		c.state = stateFailed
+		if m != nil && m.Type == MSG_ABORT {
+			return nil, err
+		}
+		return proto.AbortMessage(), err
+	}
+	return out, nil
+}
+
+func (c *Client) onChallenge(m *Message) (*Message, error) {
+	if err := proto.Expect(m, MSG_CHALLENGE, 1); err != nil {
+		return nil, err
+	}
+	if len(m.Fields[0]) != NONCE_SIZE {
+		return nil, ErrUnexpected
+	}
+	c.rb = append([]byte(nil), m.Fields[0]...)
+	if c.config.Cache != nil && !c.config.Cache.Add(c.rb, c.config.now()) {
+		return nil, ErrReplay
+	}
+	if c.config.Mechanism == TWO_PASS {
+		mac, err := c.config.mac(c.key, LABEL_TWO_PASS, c.rb, c.serverID, c.config.ID)
+		if err != nil {
+			return nil, err
+		}
+		c.state = stateDone
+		return &Message{Type: MSG_RESPONSE, Fields: [][]byte{c.config.ID, mac}}, nil
+	}
+
+	var err error
+	c.ra, err = c.config.nonce()
+	if err != nil {
+		return nil, err
+	}
+	mac, err := c.config.mac(c.key, LABEL_THREE_PASS_A, c.ra, c.rb, c.serverID, c.config.ID)
+	if err != nil {
+		return nil, err
+	}
+	c.state = stateWaitConfirm
+	return &Message{Type: MSG_RESPONSE, Fields: [][]byte{c.config.ID, c.ra, mac}}, nil
+}
+
+func (c *Client) onConfirm(m *Message) error {
+	if err := proto.Expect(m, MSG_CONFIRM, 1); err != nil {
+		return err
+	}
+	if err := c.config.checkMAC(c.key, m.Fields[0], LABEL_THREE_PASS_B, c.rb, c.ra, c.config.ID, c.serverID); err != nil {
+		return err
+	}
+	c.state = stateDone
+	return nil
+}
+
+// Завершён ли обмен; для 3 проходов - с аутентификацией сервера.
+func (c *Client) Done() bool {
+	return c.state == stateDone
+}
+
+// Выполнение обмена по соединению conn.
+func (c *Client) Run(conn io.ReadWriter) error {
+	out, err := c.Start()
+	if err != nil {
+		return err
+	}
+	return run(conn, out, c.Next, c.Done)
+}
+
+// Поиск общего ключа по идентификатору клиента.
+type KeyLookup func(id []byte) ([]byte, error)
+
+// Проверяющая сторона B.
+type Server struct {
+	config *Config
+	lookup KeyLookup
+	state  state
+	peerID []byte
+	rb     []byte
+}
+
+func NewServer(config *Config, lookup KeyLookup) (*Server, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	if config.Cache == nil {
+		return nil, errors.New(PREFIX + "server requires replay cache")
+	}
+	// Токен принимается, пока его метка времени в пределах ±TimeWindow,
+	// поэтому он должен храниться в кэше не меньше 2*TimeWindow
+	if config.Mechanism == ONE_PASS && config.Cache.TTL() < 2*config.window() {
+		return nil, errors.New(PREFIX + "replay cache ttl is shorter than twice the time window")
+	}
+	return &Server{config: config, lookup: lookup}, nil
+}
+
+// Вызов R_B для 2 и 3 проходов, nil для 1 прохода.
+func (s *Server) Start() (*Message, error) {
+	if s.state != stateStart {
+		return nil, ErrUnexpected
+	}
+	if s.config.Mechanism == ONE_PASS {
+		s.state = stateWaitResponse
+		return nil, nil
+	}
+	var err error
+	s.rb, err = s.config.nonce()
+	if err != nil {
+		return nil, err
+	}
+	s.state = stateWaitResponse
+	return &Message{Type: MSG_CHALLENGE, Fields: [][]byte{s.rb}}, nil
+}
+
+// Обработка сообщения клиента; для 3 проходов возвращает MSG_CONFIRM.
+// При ошибке возвращается MSG_ABORT, если клиент ожидает ответа.
+func (s *Server) Next(m *Message) (*Message, error) {
+	if s.state != stateWaitResponse {
+		return nil, ErrUnexpected
+	}
+	var out *Message
+	var err error
+	switch s.config.Mechanism {
+	case ONE_PASS:
+		err = s.onToken(m)
+	case TWO_PASS:
+		err = s.onResponse2(m)
+	case THREE_PASS:
+		out, err = s.onResponse3(m)
+	}
+	if err != nil {
+		s.state = stateFailed
+		s.peerID = nil
+		if s.config.Mechanism == THREE_PASS && (m == nil || m.Type != MSG_ABORT) {
+			return proto.AbortMessage(), err
+		}
+		return nil, err
+	}
+	s.state = stateDone
+	return out, nil
+}
+
+func (s *Server) key(id []byte) ([]byte, error) {
+	key, err := s.lookup(id)
+	if err != nil || len(key) != KEY_SIZE {
+		return nil, ErrAuthFailed
+	}
+	return key, nil
+}
+
+func (s *Server) onToken(m *Message) error {
+	if err := proto.Expect(m, MSG_TOKEN, 4); err != nil {
+		return err
+	}
+	id, ts, ra, mac := m.Fields[0], m.Fields[1], m.Fields[2], m.Fields[3]
+	if len(ts) != 8 || len(ra) != NONCE_SIZE {
+		return ErrUnexpected
+	}
+	key, err := s.key(id)
+	if err != nil {
+		return err
+	}
+	if err := s.config.checkMAC(key, mac, LABEL_ONE_PASS, ts, ra, s.config.ID, id); err != nil {
+		return err
+	}
+	now := s.config.now()
+	t := time.Unix(0, int64(binary.BigEndian.Uint64(ts)))
+	if d := now.Sub(t); d > s.config.window() || d < -s.config.window() {
+		return ErrStale
+	}
+	if !s.config.Cache.Add(mac, now) {
+		return ErrReplay
+	}
+	s.peerID = append([]byte(nil), id...)
+	return nil
+}
+
+func (s *Server) onResponse2(m *Message) error {
+	if err := proto.Expect(m, MSG_RESPONSE, 2); err != nil {
+		return err
+	}
+	id, mac := m.Fields[0], m.Fields[1]
+	key, err := s.key(id)
+	if err != nil {
+		return err
+	}
+	if err := s.config.checkMAC(key, mac, LABEL_TWO_PASS, s.rb, s.config.ID, id); err != nil {
+		return err
+	}
+	s.peerID = append([]byte(nil), id...)
+	return nil
+}
+
+func (s *Server) onResponse3(m *Message) (*Message, error) {
+	if err := proto.Expect(m, MSG_RESPONSE, 3); err != nil {
+		return nil, err
+	}
+	id, ra, mac := m.Fields[0], m.Fields[1], m.Fields[2]
+	if len(ra) != NONCE_SIZE {
+		return nil, ErrUnexpected
+	}
+	key, err := s.key(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.config.checkMAC(key, mac, LABEL_THREE_PASS_A, ra, s.rb, s.config.ID, id); err != nil {
+		return nil, err
+	}
+	if !s.config.Cache.Add(ra, s.config.now()) {
+		return nil, ErrReplay
+	}
+	confirm, err := s.config.mac(key, LABEL_THREE_PASS_B, s.rb, ra, id, s.config.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.peerID = append([]byte(nil), id...)
+	return &Message{Type: MSG_CONFIRM, Fields: [][]byte{confirm}}, nil
+}
+
+// Идентификатор аутентифицированного клиента.
+func (s *Server) PeerID() []byte {
+	if s.state != stateDone {
+		return nil
+	}
+	return s.peerID
+}
+
+func (s *Server) Done() bool {
+	return s.state == stateDone
+}
+
+// Выполнение обмена по соединению conn; возвращает идентификатор клиента.
+func (s *Server) Run(conn io.ReadWriter) ([]byte, error) {
+	out, err := s.Start()
+	if err != nil {
+		return nil, err
+	}
+	if err := run(conn, out, s.Next, s.Done); err != nil {
+		return nil, err
+	}
+	return s.PeerID(), nil
+}
+
+// Отправка out (если есть) и обработка входящих сообщений до завершения.
+func run(conn io.ReadWriter, out *Message, next func(*Message) (*Message, error), done func() bool) error {
+	for {
+		if out != nil {
+			if err := proto.Write(conn, out); err != nil {
+				return err
+			}
+		}
+		if done() {
+			return nil
+		}
+		in, err := proto.Read(conn)
+		if err != nil {
+			return err
+		}
+		out, err = next(in)
+		if err != nil {
+			if out != nil {
+				proto.Write(conn, out)
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package kuznyechik
+
+import (
+	"gost_magma_cbc/crypto/models"
+	"unsafe"
+)
+
+// little endian format
+type KuznyechikBlock struct {
+	parts [2]uint64
+	data  []byte
+}
+
+func NewKuznyechikBlock() models.Block {
+	b := &KuznyechikBlock{}
+	b.data = unsafe.Slice((*byte)(unsafe.Pointer(&b.parts[0])), blockSize)
+	return b
+}
+
+func (b *KuznyechikBlock) GetPart(i int) any {
+	return Part(b.parts[i])
+}
+
+func (b *KuznyechikBlock) SetPart(i int, v any) {
+	b.parts[i] = uint64(v.(Part))
+}
+
+func (b *KuznyechikBlock) PartLen() int {
+	return 8
+}
+
+func (b *KuznyechikBlock) Get(i int) byte {
+	return b.data[i]
+}
+
+func (b *KuznyechikBlock) Set(i int, v byte) {
+	b.data[i] = v
+}
+
+func (b *KuznyechikBlock) Len() int {
+	return blockSize
+}
+
+func (b *KuznyechikBlock) Data() []byte {
+	return b.data
+}
+
+func (b *KuznyechikBlock) Clear() {
+	b.parts[0] = 0
+	b.parts[1] = 0
+}
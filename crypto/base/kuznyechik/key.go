@@ -0,0 +1,70 @@
+package kuznyechik
+
+import (
+	"crypto/subtle"
+	"gost_magma_cbc/crypto/models"
+	"unsafe"
+)
+
+// little endian format
+type KuznyechikKey struct {
+	parts [4]uint64
+	data  []byte
+
+	// Развёрнутые итерационные ключи и исходный ключ, по которому они
+	// были вычислены.
+	expanded bool
+	raw      [keySize]byte
+	iter     [iterKeysCount][blockSize]byte
+}
+
+func NewKuznyechikKey() models.Key {
+	k := &KuznyechikKey{}
+	k.data = unsafe.Slice((*byte)(unsafe.Pointer(&k.parts[0])), keySize)
+	return k
+}
+
+func (k *KuznyechikKey) GetPart(i int) any {
+	return Part(k.parts[i])
+}
+
+func (k *KuznyechikKey) PartLen() int {
+	return 8
+}
+
+func (k *KuznyechikKey) Set(i int, v byte) {
+	k.data[i] = v
+}
+
+func (k *KuznyechikKey) Len() int {
+	return keySize
+}
+
+func (k *KuznyechikKey) Data() []byte {
+	return k.data
+}
+
+func (k *KuznyechikKey) Clear() {
+	for i := range k.parts {
+		k.parts[i] = 0
+	}
+	k.clearIter()
+}
+
+func (k *KuznyechikKey) clearIter() {
+	for i := range k.iter {
+		k.iter[i] = [blockSize]byte{}
+	}
+	k.raw = [keySize]byte{}
+	k.expanded = false
+}
+
+// Итерационные ключи, пересчитываемые при изменении данных ключа.
+func (k *KuznyechikKey) iterKeys() *[iterKeysCount][blockSize]byte {
+	if !k.expanded || subtle.ConstantTimeCompare(k.raw[:], k.data) != 1 {
+		copy(k.raw[:], k.data)
+		expandKey(&k.iter, &k.raw)
+		k.expanded = true
+	}
+	return &k.iter
+}
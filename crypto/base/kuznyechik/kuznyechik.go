@@ -0,0 +1,153 @@
+package kuznyechik
+
+import (
+	"gost_magma_cbc/crypto/models"
+)
+
+type Part uint64
+
+const (
+	keySize       = 32
+	blockSize     = 16
+	iterKeysCount = 10
+)
+
+// Итерационные константы C1, ..., C32.
+var iterConst [32][blockSize]byte
+
+type Kuznyechik struct {
+}
+
+func NewKuznyechik() models.BaseAlgorithm {
+	return &Kuznyechik{}
+}
+
+func (*Kuznyechik) NewBlock() models.Block {
+	return NewKuznyechikBlock()
+}
+
+func (*Kuznyechik) NewKey() models.Key {
+	return NewKuznyechikKey()
+}
+
+// Байты a0, ..., a15 хранятся в порядке возрастания индекса (a0 - младший).
+
+func x(a *[blockSize]byte, k *[blockSize]byte) {
+	for i := 0; i < blockSize; i++ {
+		a[i] ^= k[i]
+	}
+}
+
+func s(a *[blockSize]byte) {
+	for i := 0; i < blockSize; i++ {
+		a[i] = Pi[a[i]]
+	}
+}
+
+func sInv(a *[blockSize]byte) {
+	for i := 0; i < blockSize; i++ {
+		a[i] = piInv[a[i]]
+	}
+}
+
+// R(a15||...||a0) = l(a15, ..., a0)||a15||...||a1
+func r(a *[blockSize]byte) {
+	var t byte
+	for i := 0; i < blockSize; i++ {
+		t ^= gfMul[a[i]][lCoef[15-i]]
+	}
+	copy(a[:blockSize-1], a[1:])
+	a[blockSize-1] = t
+}
+
+// R^-1(a15||...||a0) = a14||...||a0||l(a14, ..., a0, a15)
+func rInv(a *[blockSize]byte) {
+	top := a[blockSize-1]
+	copy(a[1:], a[:blockSize-1])
+	a[0] = top
+	var t byte
+	for i := 0; i < blockSize; i++ {
+		t ^= gfMul[a[i]][lCoef[15-i]]
+	}
+	a[0] = t
+}
+
+func l(a *[blockSize]byte) {
+	for i := 0; i < blockSize; i++ {
+		r(a)
+	}
+}
+
+func lInv(a *[blockSize]byte) {
+	for i := 0; i < blockSize; i++ {
+		rInv(a)
+	}
+}
+
+// Развёртывание ключа: K1 - старшие 128 бит ключа, K2 - младшие.
+func expandKey(iter *[iterKeysCount][blockSize]byte, key *[keySize]byte) {
+	var k1, k2 [blockSize]byte
+	copy(k1[:], key[blockSize:])
+	copy(k2[:], key[:blockSize])
+	iter[0] = k1
+	iter[1] = k2
+
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			// F[C](a1, a0) = (LSX[C](a1) ^ a0, a1)
+			t := k1
+			x(&t, &iterConst[8*i+j])
+			s(&t)
+			l(&t)
+			x(&t, &k2)
+			k1, k2 = t, k1
+		}
+		iter[2*i+2] = k1
+		iter[2*i+3] = k2
+	}
+}
+
+func iterKeysOf(key models.Key) *[iterKeysCount][blockSize]byte {
+	if k, ok := key.(*KuznyechikKey); ok {
+		return k.iterKeys()
+	}
+	var raw [keySize]byte
+	var iter [iterKeysCount][blockSize]byte
+	copy(raw[:], key.Data())
+	expandKey(&iter, &raw)
+	return &iter
+}
+
+func (*Kuznyechik) Encrypt(key models.Key, src, dst models.Block) {
+	iter := iterKeysOf(key)
+	var a [blockSize]byte
+	copy(a[:], src.Data())
+	for i := 0; i < iterKeysCount-1; i++ {
+		x(&a, &iter[i])
+		s(&a)
+		l(&a)
+	}
+	x(&a, &iter[iterKeysCount-1])
+	copy(dst.Data(), a[:])
+}
+
+func (*Kuznyechik) Decrypt(key models.Key, src, dst models.Block) {
+	iter := iterKeysOf(key)
+	var a [blockSize]byte
+	copy(a[:], src.Data())
+	for i := iterKeysCount - 1; i > 0; i-- {
+		x(&a, &iter[i])
+		lInv(&a)
+		sInv(&a)
+	}
+	x(&a, &iter[0])
+	copy(dst.Data(), a[:])
+}
+
+func (*Kuznyechik) BlockLen() int {
+	return blockSize
+}
+
+func (*Kuznyechik) KeyLen() int {
+	return keySize
+}
@@ -0,0 +1,125 @@
+package kuznyechik
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"testing"
+
+	"gost_magma_cbc/crypto/manage"
+)
+
+func fromBEHex(t *testing.T, s string) [blockSize]byte {
+	var r [blockSize]byte
+	b, err := manage.ConvertHexBigEndian(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(r[:], b)
+	return r
+}
+
+func TestKuznyechikS(t *testing.T) {
+	a := fromBEHex(t, "ffeeddccbbaa99881122334455667700")
+	steps := []string{
+		"b66cd8887d38e8d77765aeea0c9a7efc",
+		"559d8dd7bd06cbfe7e7b262523280d39",
+		"0c3322fed531e4630d80ef5c5a81c50b",
+		"23ae65633f842d29c5df529c13f5acda",
+	}
+	for i, step := range steps {
+		s(&a)
+		if e := fromBEHex(t, step); a != e {
+			t.Errorf("[s_%d] res is %x, not %x", i+1, a, e)
+		}
+	}
+}
+
+func TestKuznyechikR(t *testing.T) {
+	a := fromBEHex(t, "00000000000000000000000000000100")
+	steps := []string{
+		"94000000000000000000000000000001",
+		"a5940000000000000000000000000000",
+		"64a59400000000000000000000000000",
+		"0d64a594000000000000000000000000",
+	}
+	for i, step := range steps {
+		r(&a)
+		if e := fromBEHex(t, step); a != e {
+			t.Errorf("[r_%d] res is %x, not %x", i+1, a, e)
+		}
+	}
+	for i := len(steps) - 2; i >= 0; i-- {
+		rInv(&a)
+		if e := fromBEHex(t, steps[i]); a != e {
+			t.Errorf("[r_inv_%d] res is %x, not %x", i+1, a, e)
+		}
+	}
+}
+
+func TestKuznyechikL(t *testing.T) {
+	a := fromBEHex(t, "64a59400000000000000000000000000")
+	steps := []string{
+		"d456584dd0e3e84cc3166e4b7fa2890d",
+		"79d26221b87b584cd42fbc4ffea5de9a",
+		"0e93691a0cfc60408b7b68f66b513c13",
+		"e6a8094fee0aa204fd97bcb0b44b8580",
+	}
+	for i, step := range steps {
+		l(&a)
+		if e := fromBEHex(t, step); a != e {
+			t.Errorf("[l_%d] res is %x, not %x", i+1, a, e)
+		}
+	}
+}
+
+func TestKuznyechik(t *testing.T) {
+	k := Kuznyechik{}
+
+	hdata, err := manage.ConvertHexBigEndian(
+		"8899aabbccddeeff0011223344556677fedcba98765432100123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := k.NewKey()
+	subtle.ConstantTimeCopy(1, key.Data(), hdata)
+
+	iter := key.(*KuznyechikKey).iterKeys()
+	e := fromBEHex(t, "db31485315694343228d6aef8cc78c44")
+	if iter[2] != e {
+		t.Errorf("[key] K3 is %x, not %x", iter[2], e)
+	}
+	e = fromBEHex(t, "72e9dd7416bcf45b755dbaa88e4a4043")
+	if iter[9] != e {
+		t.Errorf("[key] K10 is %x, not %x", iter[9], e)
+	}
+
+	p := fromBEHex(t, "1122334455667700ffeeddccbbaa9988")
+	c := fromBEHex(t, "7f679d90bebc24305a468d42b9d4edcd")
+
+	b := k.NewBlock()
+	copy(b.Data(), p[:])
+	k.Encrypt(key, b, b)
+	if !bytes.Equal(b.Data(), c[:]) {
+		t.Errorf("[enc] res is %x, not %x", b.Data(), c)
+	}
+
+	k.Decrypt(key, b, b)
+	if !bytes.Equal(b.Data(), p[:]) {
+		t.Errorf("[dec] res is %x, not %x", b.Data(), p)
+	}
+
+	// Смена ключа через Data() должна пересчитать итерационные ключи
+	key.Data()[0] ^= 1
+	copy(b.Data(), p[:])
+	k.Encrypt(key, b, b)
+	if bytes.Equal(b.Data(), c[:]) {
+		t.Error("[rekey] iteration keys are not updated")
+	}
+
+	key.Clear()
+	for i := 0; i < key.Len(); i++ {
+		if key.Data()[i] != 0 {
+			t.Fatal("[clear] key is not zero")
+		}
+	}
+}
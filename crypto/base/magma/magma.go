@@ -45,12 +45,18 @@ var (
 )
 
 type Magma struct {
+	sbox *Sbox
 }
 
 func NewMagma() models.BaseAlgorithm {
 	return &Magma{}
 }
 
+// Создание алгоритма с заменой узлов замены (например, для ГОСТ 28147-89).
+func NewMagmaWithSbox(s *Sbox) models.BaseAlgorithm {
+	return &Magma{sbox: s}
+}
+
 func (m *Magma) NewBlock() models.Block {
 	return NewMagmaBlock()
 }
@@ -64,14 +70,18 @@ func (m *Magma) GetIterKey(key models.Key, i int, mode models.Mode) bh {
 }
 
 func sbox(n bh) bh {
-	return bh(Sbox34_12_2018[0][(n>>0)&0x0F])<<0 +
-		bh(Sbox34_12_2018[1][(n>>4)&0x0F])<<4 +
-		bh(Sbox34_12_2018[2][(n>>8)&0x0F])<<8 +
-		bh(Sbox34_12_2018[3][(n>>12)&0x0F])<<12 +
-		bh(Sbox34_12_2018[4][(n>>16)&0x0F])<<16 +
-		bh(Sbox34_12_2018[5][(n>>20)&0x0F])<<20 +
-		bh(Sbox34_12_2018[6][(n>>24)&0x0F])<<24 +
-		bh(Sbox34_12_2018[7][(n>>28)&0x0F])<<28
+	return sboxWith(&Sbox34_12_2018, n)
+}
+
+func sboxWith(s *Sbox, n bh) bh {
+	return bh(s[0][(n>>0)&0x0F])<<0 +
+		bh(s[1][(n>>4)&0x0F])<<4 +
+		bh(s[2][(n>>8)&0x0F])<<8 +
+		bh(s[3][(n>>12)&0x0F])<<12 +
+		bh(s[4][(n>>16)&0x0F])<<16 +
+		bh(s[5][(n>>20)&0x0F])<<20 +
+		bh(s[6][(n>>24)&0x0F])<<24 +
+		bh(s[7][(n>>28)&0x0F])<<28
 }
 
 func shift11(n bh) bh {
@@ -79,13 +89,21 @@ func shift11(n bh) bh {
 }
 
 func g(n bh, k bh) bh {
-	return shift11(sbox(n + k))
+	return gWith(&Sbox34_12_2018, n, k)
+}
+
+func gWith(s *Sbox, n bh, k bh) bh {
+	return shift11(sboxWith(s, n+k))
 }
 
 func (m *Magma) crypt(key models.Key, seq IterKeysIds, src, trg models.Block) {
+	s := m.sbox
+	if s == nil {
+		s = &Sbox34_12_2018
+	}
 	l, r := bh(src.GetPart(1).(Part)), bh(src.GetPart(0).(Part))
 	for _, i := range seq {
-		l, r = r, g(r, bh(key.GetPart(i).(bh)))^l
+		l, r = r, gWith(s, r, bh(key.GetPart(i).(bh)))^l
 	}
 	trg.SetPart(0, Part(l))
 	trg.SetPart(1, Part(r))
@@ -0,0 +1,315 @@
+package crisp
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/kdf"
+	"gost_magma_cbc/crypto/mode"
+	"gost_magma_cbc/crypto/models"
+	"sync"
+)
+
+// Защита сообщений по схеме протокола CRISP наборами на основе Магмы:
+// гаммирование CTR (или без шифрования) и имитовставка CMAC (OMAC)
+// длиной 32 или 64 бита.
+//
+// Кодирование KeyId и выработка ключей K_enc, K_mac определены этим
+// пакетом и не совпадают с Р 1323565.1.029-2019, поэтому сообщения не
+// совместимы с реализациями стандарта.
+
+const PREFIX = "crypto:crisp: "
+
+// Версия протокола.
+const VERSION = 0
+
+// Длина поля SeqNum и максимальный номер сообщения.
+const (
+	SEQNUM_SIZE = 6
+	MAX_SEQNUM  = 1<<48 - 1
+)
+
+// Максимальная длина KeyId.
+const MAX_KEY_ID_SIZE = 127
+
+// Длина базового ключа.
+const KEY_SIZE = 32
+
+// Размер окна защиты от повтора по умолчанию.
+const DEFAULT_WINDOW_SIZE = 64
+
+// Ключи шифрования и имитозащиты меняются каждые 2^32 сообщений: младшие
+// 32 бита SeqNum образуют IV режима CTR (половина блока Магмы), старшие
+// 16 бит - номер ключей K_enc = KDF256(K, LABEL_ENC, epoch),
+// K_mac = KDF256(K, LABEL_MAC, epoch).
+const EPOCH_SHIFT = 32
+
+const (
+	LABEL_ENC = "CRISP ENC"
+	LABEL_MAC = "CRISP MAC"
+)
+
+var (
+	ErrReplay     = errors.New(PREFIX + "replayed or too old message")
+	ErrBadICV     = errors.New(PREFIX + "integrity check failed")
+	ErrUnknownKey = errors.New(PREFIX + "unknown key id")
+)
+
+// Криптографический набор (поле CS).
+type CryptoSet byte
+
+const (
+	MAGMA_CTR_CMAC   CryptoSet = 1
+	MAGMA_NULL_CMAC  CryptoSet = 2
+	MAGMA_CTR_CMAC8  CryptoSet = 3
+	MAGMA_NULL_CMAC8 CryptoSet = 4
+)
+
+type setParams struct {
+	encrypt bool
+	icvLen  int
+}
+
+func (cs CryptoSet) params() (setParams, error) {
+	switch cs {
+	case MAGMA_CTR_CMAC:
+		return setParams{true, 4}, nil
+	case MAGMA_NULL_CMAC:
+		return setParams{false, 4}, nil
+	case MAGMA_CTR_CMAC8:
+		return setParams{true, 8}, nil
+	case MAGMA_NULL_CMAC8:
+		return setParams{false, 8}, nil
+	default:
+		return setParams{}, errors.New(PREFIX + "unsupported crypto set")
+	}
+}
+
+// Ключи эпохи для базового ключа.
+type epochKeys struct {
+	valid bool
+	epoch uint64
+	enc   models.Key
+	mac   models.Key
+}
+
+func (k *epochKeys) clear() {
+	if k.enc != nil {
+		k.enc.Clear()
+		k.mac.Clear()
+	}
+	k.valid = false
+}
+
+// Ключ Магмы из строки байт (старший байт первым).
+func loadKey(k []byte) models.Key {
+	key := magma.NewMagmaKey()
+	d := key.Data()
+	for i := range k {
+		d[len(k)-1-i] = k[i]
+	}
+	clear(k)
+	return key
+}
+
+func (k *epochKeys) update(base []byte, seq uint64) error {
+	epoch := seq >> EPOCH_SHIFT
+	if k.valid && k.epoch == epoch {
+		return nil
+	}
+	k.clear()
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], epoch)
+	enc, err := kdf.NewKDF256().Create(base, []byte(LABEL_ENC), seed[:])
+	if err != nil {
+		return err
+	}
+	mac, err := kdf.NewKDF256().Create(base, []byte(LABEL_MAC), seed[:])
+	if err != nil {
+		return err
+	}
+	k.enc, k.mac = loadKey(enc), loadKey(mac)
+	k.epoch, k.valid = epoch, true
+	return nil
+}
+
+// IV = младшие 32 бита SeqNum.
+func iv(seq uint64) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(seq))
+}
+
+func (k *epochKeys) crypt(seq uint64, data []byte) error {
+	ctr, err := mode.NewCTR(magma.NewMagma(), k.enc, iv(seq))
+	if err != nil {
+		return err
+	}
+	ctr.XORKeyStream(data, data)
+	return nil
+}
+
+// ICV = CMAC(K_mac, заголовок || Payload).
+func (k *epochKeys) icv(header, payload []byte, size int) ([]byte, error) {
+	m, err := mode.NewOMAC(magma.NewMagma(), k.mac, size)
+	if err != nil {
+		return nil, err
+	}
+	m.Write(header)
+	m.Write(payload)
+	return m.Sum(nil), nil
+}
+
+// Отправитель сообщений с фиксированными набором и ключом.
+type Sender struct {
+	cs    CryptoSet
+	keyId []byte
+	base  []byte
+	seq   uint64
+	done  bool
+	keys  epochKeys
+	mtx   sync.Mutex
+}
+
+// seq - номер первого сообщения (продолжение после перезапуска).
+func NewSender(cs CryptoSet, keyId, key []byte, seq uint64) (*Sender, error) {
+	if _, err := cs.params(); err != nil {
+		return nil, err
+	}
+	if _, err := appendKeyId(nil, keyId); err != nil {
+		return nil, err
+	}
+	if len(key) != KEY_SIZE {
+		return nil, errors.New(PREFIX + "invalid key length")
+	}
+	if seq > MAX_SEQNUM {
+		return nil, errors.New(PREFIX + "invalid sequence number")
+	}
+	return &Sender{
+		cs:    cs,
+		keyId: append([]byte(nil), keyId...),
+		base:  append([]byte(nil), key...),
+		seq:   seq,
+	}, nil
+}
+
+// Защита данных и кодирование сообщения.
+func (s *Sender) Seal(payload []byte) ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.done {
+		return nil, errors.New(PREFIX + "sequence number exhausted")
+	}
+	params, _ := s.cs.params()
+	if err := s.keys.update(s.base, s.seq); err != nil {
+		return nil, err
+	}
+	m := &Message{Version: VERSION, CS: s.cs, KeyId: s.keyId, SeqNum: s.seq}
+	m.Payload = append([]byte(nil), payload...)
+	if params.encrypt {
+		if err := s.keys.crypt(s.seq, m.Payload); err != nil {
+			return nil, err
+		}
+	}
+	hdr, err := m.header()
+	if err != nil {
+		return nil, err
+	}
+	m.ICV, err = s.keys.icv(hdr, m.Payload, params.icvLen)
+	if err != nil {
+		return nil, err
+	}
+	if s.seq == MAX_SEQNUM {
+		s.done = true
+	} else {
+		s.seq++
+	}
+	return m.Marshal()
+}
+
+// Номер следующего сообщения.
+func (s *Sender) SeqNum() uint64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.seq
+}
+
+func (s *Sender) Clear() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	clear(s.base)
+	s.keys.clear()
+}
+
+// Поиск базового ключа по KeyId.
+type KeyLookup func(keyId []byte) ([]byte, error)
+
+type keyState struct {
+	base   []byte
+	window *ReplayWindow
+	keys   epochKeys
+}
+
+// Получатель сообщений: ключи по KeyId и отдельное окно защиты от
+// повтора для каждого ключа.
+type Receiver struct {
+	lookup     KeyLookup
+	windowSize int
+	states     map[string]*keyState
+	mtx        sync.Mutex
+}
+
+// windowSize <= 0 - DEFAULT_WINDOW_SIZE.
+func NewReceiver(lookup KeyLookup, windowSize int) *Receiver {
+	if windowSize <= 0 {
+		windowSize = DEFAULT_WINDOW_SIZE
+	}
+	return &Receiver{lookup: lookup, windowSize: windowSize, states: map[string]*keyState{}}
+}
+
+// Разбор и проверка сообщения. Окно обновляется только после проверки
+// ICV. Возвращает сообщение и открытые данные.
+func (r *Receiver) Open(b []byte) (*Message, []byte, error) {
+	m, err := Unmarshal(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.Version != VERSION {
+		return nil, nil, errors.New(PREFIX + "unsupported version")
+	}
+	params, _ := m.CS.params()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	st := r.states[string(m.KeyId)]
+	if st == nil {
+		key, err := r.lookup(m.KeyId)
+		if err != nil || len(key) != KEY_SIZE {
+			return nil, nil, ErrUnknownKey
+		}
+		st = &keyState{base: append([]byte(nil), key...), window: NewReplayWindow(r.windowSize)}
+		r.states[string(m.KeyId)] = st
+	}
+	if !st.window.Check(m.SeqNum) {
+		return nil, nil, ErrReplay
+	}
+	if err := st.keys.update(st.base, m.SeqNum); err != nil {
+		return nil, nil, err
+	}
+	hdr, _ := m.header()
+	icv, err := st.keys.icv(hdr, m.Payload, params.icvLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare(icv, m.ICV) != 1 {
+		return nil, nil, ErrBadICV
+	}
+	st.window.Update(m.SeqNum)
+
+	payload := append([]byte(nil), m.Payload...)
+	if params.encrypt {
+		if err := st.keys.crypt(m.SeqNum, payload); err != nil {
+			return nil, nil, err
+		}
+	}
+	return m, payload, nil
+}
@@ -0,0 +1,196 @@
+package crisp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var testKey = []byte{
+	0xff, 0xee, 0xdd, 0xcc, 0xbb, 0xaa, 0x99, 0x88, 0x77, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x00,
+	0xf0, 0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8, 0xf9, 0xfa, 0xfb, 0xfc, 0xfd, 0xfe, 0xff,
+}
+
+func lookup(keys map[string][]byte) KeyLookup {
+	return func(id []byte) ([]byte, error) {
+		k, ok := keys[string(id)]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return k, nil
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	payload := []byte("setpoint=42;valve=open")
+	for _, cs := range []CryptoSet{MAGMA_CTR_CMAC, MAGMA_NULL_CMAC, MAGMA_CTR_CMAC8, MAGMA_NULL_CMAC8} {
+		for _, id := range [][]byte{{0x05}, {0x80}, bytes.Repeat([]byte{7}, MAX_KEY_ID_SIZE)} {
+			s, err := NewSender(cs, id, testKey, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := NewReceiver(lookup(map[string][]byte{string(id): testKey}), 0)
+			for i := 0; i < 3; i++ {
+				b, err := s.Seal(payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				m, got, err := r.Open(b)
+				if err != nil {
+					t.Fatalf("[%d] %v", cs, err)
+				}
+				if !bytes.Equal(got, payload) || !bytes.Equal(m.KeyId, id) || m.SeqNum != uint64(i+1) {
+					t.Fatalf("[%d] message mismatch", cs)
+				}
+				params, _ := cs.params()
+				if len(m.ICV) != params.icvLen {
+					t.Errorf("[%d] incorrect icv length", cs)
+				}
+				if bytes.Equal(m.Payload, payload) == params.encrypt {
+					t.Errorf("[%d] incorrect payload protection", cs)
+				}
+			}
+		}
+	}
+}
+
+func TestMessageEncoding(t *testing.T) {
+	m := &Message{
+		ExternalKeyIdFlag: true,
+		Version:           VERSION,
+		CS:                MAGMA_CTR_CMAC,
+		KeyId:             []byte{0x12},
+		SeqNum:            0x0102030405,
+		Payload:           []byte{0xaa, 0xbb},
+		ICV:               []byte{1, 2, 3, 4},
+	}
+	b, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x80, 0x00, 0x01, 0x12, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0xaa, 0xbb, 1, 2, 3, 4}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("incorrect encoding %x", b)
+	}
+	m2, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m2.ExternalKeyIdFlag || m2.SeqNum != m.SeqNum || !bytes.Equal(m2.Payload, m.Payload) || !bytes.Equal(m2.ICV, m.ICV) {
+		t.Error("message roundtrip fail")
+	}
+
+	m.KeyId = []byte{0x90, 0x91}
+	b, _ = m.Marshal()
+	if b[3] != 0x82 || b[4] != 0x90 || b[5] != 0x91 {
+		t.Errorf("incorrect long key id encoding %x", b)
+	}
+	for i := 0; i < 4+2+SEQNUM_SIZE+4; i++ {
+		if _, err := Unmarshal(b[:i]); err == nil {
+			t.Errorf("truncated message %d accepted", i)
+		}
+	}
+
+	bad := []*Message{
+		{CS: 9, KeyId: []byte{1}, ICV: make([]byte, 4)},
+		{CS: MAGMA_CTR_CMAC, KeyId: nil, ICV: make([]byte, 4)},
+		{CS: MAGMA_CTR_CMAC, KeyId: []byte{1}, ICV: make([]byte, 8)},
+		{CS: MAGMA_CTR_CMAC, KeyId: []byte{1}, SeqNum: MAX_SEQNUM + 1, ICV: make([]byte, 4)},
+	}
+	for i, m := range bad {
+		if _, err := m.Marshal(); err == nil {
+			t.Errorf("[%d] invalid message encoded", i)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	s, _ := NewSender(MAGMA_CTR_CMAC, []byte{1}, testKey, 0)
+	msgs := make([][]byte, 100)
+	for i := range msgs {
+		msgs[i], _ = s.Seal([]byte{byte(i)})
+	}
+	r := NewReceiver(lookup(map[string][]byte{"\x01": testKey}), 32)
+
+	open := func(i int) error {
+		_, p, err := r.Open(msgs[i])
+		if err == nil && p[0] != byte(i) {
+			t.Fatalf("payload mismatch %d", i)
+		}
+		return err
+	}
+	for _, i := range []int{0, 2, 1, 10, 5, 40} {
+		if err := open(i); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+	}
+	// Повтор и номера левее окна
+	for _, i := range []int{0, 2, 5, 40, 8} {
+		if err := open(i); !errors.Is(err, ErrReplay) {
+			t.Errorf("message %d: %v", i, err)
+		}
+	}
+	// Непринятые номера внутри окна
+	for _, i := range []int{39, 20, 9 + 32} {
+		if err := open(i); err != nil {
+			t.Errorf("message %d: %v", i, err)
+		}
+	}
+
+	// Изменённое сообщение не сдвигает окно
+	bad := append([]byte(nil), msgs[99]...)
+	bad[len(bad)-5] ^= 1
+	if _, _, err := r.Open(bad); !errors.Is(err, ErrBadICV) {
+		t.Errorf("modified message: %v", err)
+	}
+	if err := open(42); err != nil {
+		t.Errorf("window moved by rejected message: %v", err)
+	}
+	if err := open(99); err != nil {
+		t.Error(err)
+	}
+
+	if _, _, err := NewReceiver(lookup(nil), 0).Open(msgs[0]); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown key: %v", err)
+	}
+}
+
+func TestEpochs(t *testing.T) {
+	// Ключи меняются при переходе через 2^32 сообщений
+	start := uint64(1)<<EPOCH_SHIFT - 2
+	s, err := NewSender(MAGMA_CTR_CMAC8, []byte{3}, testKey, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReceiver(lookup(map[string][]byte{"\x03": testKey}), 0)
+	var payloads [][]byte
+	for i := 0; i < 4; i++ {
+		b, err := s.Seal(make([]byte, 8))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _, err := r.Open(b)
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		payloads = append(payloads, m.Payload)
+	}
+	// Одинаковый IV (младшие 32 бита) в разных эпохах даёт разную гамму
+	s2, _ := NewSender(MAGMA_CTR_CMAC8, []byte{3}, testKey, 0)
+	b, _ := s2.Seal(make([]byte, 8))
+	m, _ := Unmarshal(b)
+	if bytes.Equal(m.Payload, payloads[2]) {
+		t.Error("keystream reused across epochs")
+	}
+
+	s, _ = NewSender(MAGMA_CTR_CMAC, []byte{3}, testKey, MAX_SEQNUM)
+	if _, err := s.Seal(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Seal(nil); err == nil {
+		t.Error("sequence number overflow")
+	}
+	if _, err := NewSender(MAGMA_CTR_CMAC, nil, testKey, 0); err == nil {
+		t.Error("empty key id accepted")
+	}
+}
@@ -0,0 +1,118 @@
+package crisp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Сообщение CRISP:
+//
+//	ExternalKeyIdFlag (1 бит) || Version (15 бит) || CS (1 байт) ||
+//	KeyId || SeqNum (6 байт) || Payload || ICV
+//
+// KeyId кодируется одним байтом 0x00-0x7F либо байтом 0x80 | L, за
+// которым следуют L байт идентификатора (1 <= L <= MAX_KEY_ID_SIZE).
+type Message struct {
+	ExternalKeyIdFlag bool
+	Version           uint16
+	CS                CryptoSet
+	KeyId             []byte
+	SeqNum            uint64
+	// Данные в том виде, в котором они передаются (зашифрованные для
+	// наборов с CTR).
+	Payload []byte
+	ICV     []byte
+}
+
+// Длина идентификатора ключа в однобайтовой форме кодирования.
+func shortKeyId(id []byte) bool {
+	return len(id) == 1 && id[0] < 0x80
+}
+
+func appendKeyId(b, id []byte) ([]byte, error) {
+	if shortKeyId(id) {
+		return append(b, id[0]), nil
+	}
+	if len(id) == 0 || len(id) > MAX_KEY_ID_SIZE {
+		return nil, errors.New(PREFIX + "invalid key id length")
+	}
+	b = append(b, 0x80|byte(len(id)))
+	return append(b, id...), nil
+}
+
+// Заголовок сообщения (всё, кроме Payload и ICV).
+func (m *Message) header() ([]byte, error) {
+	if m.Version > 0x7fff {
+		return nil, errors.New(PREFIX + "invalid version")
+	}
+	if m.SeqNum > MAX_SEQNUM {
+		return nil, errors.New(PREFIX + "invalid sequence number")
+	}
+	v := m.Version
+	if m.ExternalKeyIdFlag {
+		v |= 0x8000
+	}
+	b := binary.BigEndian.AppendUint16(nil, v)
+	b = append(b, byte(m.CS))
+	b, err := appendKeyId(b, m.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], m.SeqNum)
+	return append(b, seq[8-SEQNUM_SIZE:]...), nil
+}
+
+func (m *Message) Marshal() ([]byte, error) {
+	params, err := m.CS.params()
+	if err != nil {
+		return nil, err
+	}
+	if len(m.ICV) != params.icvLen {
+		return nil, errors.New(PREFIX + "invalid icv length")
+	}
+	b, err := m.header()
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, m.Payload...)
+	return append(b, m.ICV...), nil
+}
+
+// Разбор сообщения; длина ICV определяется набором CS.
+func Unmarshal(b []byte) (*Message, error) {
+	if len(b) < 4 {
+		return nil, errors.New(PREFIX + "truncated message")
+	}
+	m := &Message{}
+	v := binary.BigEndian.Uint16(b)
+	m.ExternalKeyIdFlag = v&0x8000 != 0
+	m.Version = v & 0x7fff
+	m.CS = CryptoSet(b[2])
+	params, err := m.CS.params()
+	if err != nil {
+		return nil, err
+	}
+	b = b[3:]
+	if b[0] < 0x80 {
+		m.KeyId = b[:1]
+		b = b[1:]
+	} else {
+		n := int(b[0] & 0x7f)
+		if n == 0 || len(b) < 1+n {
+			return nil, errors.New(PREFIX + "invalid key id")
+		}
+		m.KeyId = b[1 : 1+n]
+		b = b[1+n:]
+	}
+	if len(b) < SEQNUM_SIZE+params.icvLen {
+		return nil, errors.New(PREFIX + "truncated message")
+	}
+	var seq [8]byte
+	copy(seq[8-SEQNUM_SIZE:], b)
+	m.SeqNum = binary.BigEndian.Uint64(seq[:])
+	b = b[SEQNUM_SIZE:]
+	m.Payload = b[:len(b)-params.icvLen]
+	m.ICV = b[len(b)-params.icvLen:]
+	return m, nil
+}
@@ -0,0 +1,59 @@
+package crisp
+
+// Скользящее окно номеров принятых сообщений (аналогично IPsec, RFC 4303,
+// 3.4.3): принимаются новые номера правее окна и ещё не принятые номера
+// внутри окна.
+type ReplayWindow struct {
+	size   uint64
+	top    uint64
+	seen   []uint64
+	inited bool
+}
+
+func NewReplayWindow(size int) *ReplayWindow {
+	if size <= 0 {
+		size = DEFAULT_WINDOW_SIZE
+	}
+	return &ReplayWindow{size: uint64(size), seen: make([]uint64, (size+63)/64)}
+}
+
+func (w *ReplayWindow) bit(seq uint64) (int, uint64) {
+	i := seq % w.size
+	return int(i / 64), 1 << (i % 64)
+}
+
+// Проверка номера без изменения окна.
+func (w *ReplayWindow) Check(seq uint64) bool {
+	if !w.inited || seq > w.top {
+		return true
+	}
+	if w.top-seq >= w.size {
+		return false
+	}
+	i, mask := w.bit(seq)
+	return w.seen[i]&mask == 0
+}
+
+// Отметка номера как принятого; вызывается после проверки ICV.
+func (w *ReplayWindow) Update(seq uint64) {
+	if !w.Check(seq) {
+		return
+	}
+	if !w.inited {
+		w.inited = true
+		w.top = seq
+	} else if seq > w.top {
+		// Сброс отметок номеров, выходящих из окна
+		if seq-w.top >= w.size {
+			clear(w.seen)
+		} else {
+			for s := w.top + 1; s <= seq; s++ {
+				i, mask := w.bit(s)
+				w.seen[i] &^= mask
+			}
+		}
+		w.top = seq
+	}
+	i, mask := w.bit(seq)
+	w.seen[i] |= mask
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/subtle"
 	"errors"
 	"gost_magma_cbc/crypto/adder"
+	"gost_magma_cbc/crypto/base/kuznyechik"
 	"gost_magma_cbc/crypto/base/magma"
 	"gost_magma_cbc/crypto/manage"
 	"gost_magma_cbc/crypto/mode"
@@ -16,7 +17,8 @@ type CryptoMode int
 type AdderType int
 
 const (
-	BaseAlgorithmMagma CryptoBase = 0
+	BaseAlgorithmMagma      CryptoBase = 0
+	BaseAlgorithmKuznyechik CryptoBase = 1
 )
 
 const (
@@ -80,6 +82,8 @@ func (mng *CryptoManager) NewCryptoCtx(settings *CryptoSettings) *CryptoCtx {
 	switch settings.Base {
 	case BaseAlgorithmMagma:
 		ctx.base = magma.NewMagma()
+	case BaseAlgorithmKuznyechik:
+		ctx.base = kuznyechik.NewKuznyechik()
 	default:
 		mng.log.Error("[crypto] unknown base algorithm")
 	}
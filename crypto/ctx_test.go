@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"crypto/subtle"
+	"gost_magma_cbc/crypto/gost3410"
 	"gost_magma_cbc/crypto/manage"
 	"gost_magma_cbc/utils"
 	"os"
@@ -75,3 +76,56 @@ func TestCrypto(t *testing.T) {
 		t.Errorf("decrypt error")
 	}
 }
+
+// Ключ контекста вырабатывается VKO по закрытому ключу стороны и открытому
+// ключу партнёра: данные, зашифрованные одной стороной, расшифровывает другая.
+func TestCryptoVKO(t *testing.T) {
+	log, err := utils.NewLog("")
+	if err != nil {
+		t.Error(err)
+	}
+	prvA, err := gost3410.GenerateKey(gost3410.CurveTC26_256B, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prvB, err := gost3410.GenerateKey(gost3410.CurveTC26_256B, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iv_s := "1234567890abcdef234567890abcdef134567890abcdef12"
+	newSettings := func(prv *gost3410.PrivateKey, peer *gost3410.PublicKey) *CryptoSettings {
+		settings := &CryptoSettings{}
+		settings.KeySetting.Data = manage.BuildData{Vko: manage.VKOParams{Private: prv, Peer: peer, UKM: []byte{1}}}
+		settings.KeySetting.Method = manage.BuildFromVKO
+		settings.IVSetting.Data = manage.BuildData{BEString: &iv_s}
+		settings.IVSetting.Method = manage.BuildFromBEString
+		settings.IVSetting.Len = 24
+		settings.Base = BaseAlgorithmMagma
+		settings.Mode = ModeCBC
+		settings.AddType = AdderType2
+		settings.Log = log
+		return settings
+	}
+	settingsA := newSettings(prvA, &prvB.PublicKey)
+	settingsB := newSettings(prvB, &prvA.PublicKey)
+	mngA := NewCryptoManager(settingsA)
+	mngB := NewCryptoManager(settingsB)
+	ctxA := mngA.NewCryptoCtx(settingsA)
+	ctxB := mngB.NewCryptoCtx(settingsB)
+	if ctxA == nil || ctxB == nil {
+		t.Fatal("ctx is nil")
+	}
+
+	msg := []byte("key agreement by VKO_GOSTR3410_2012_256")
+	data := append([]byte{}, msg...)
+	if _, err := ctxA.EncryptLast(data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ctxB.DecryptLast(data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if subtle.ConstantTimeCompare(data, msg) == 0 {
+		t.Error("decrypt error")
+	}
+}
@@ -0,0 +1,285 @@
+package ctrdrbg
+
+import (
+	"crypto/subtle"
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/models"
+	"io"
+)
+
+const PREFIX = "crypto:drbg:ctrdrbg: "
+
+// Максимальная длина запроса для шифра с 64-битным блоком (аналогично TDEA).
+const MAX_BYTES_PER_GENERATE_64 = 1 << 10
+
+// CTR_DRBG (NIST SP 800-90A, 10.2) над блочным шифром (Магма или Кузнечик),
+// с функцией формирования (Block_Cipher_df) или без неё.
+type CtrDrbg struct {
+	base                    models.BaseAlgorithm
+	key                     models.Key
+	block                   models.Block
+	v                       []byte
+	seedLength              int
+	useDF                   bool
+	reseedCounter           uint64
+	reseedIntervalInCounter uint64
+}
+
+// Без функции формирования entropy должна иметь длину SeedLen(base),
+// а nonce не используется.
+func NewCtrDrbg(base models.BaseAlgorithm, securityLevel hdrbg.SecurityLevel, useDF bool, entropy, nonce, personalization []byte) (*CtrDrbg, error) {
+	hd := &CtrDrbg{base: base, useDF: useDF}
+	hd.reseedIntervalInCounter = hdrbg.ReseedInterval(securityLevel)
+	hd.seedLength = SeedLen(base)
+
+	if useDF {
+		if len(entropy) == 0 || len(entropy) >= hdrbg.MAX_BYTES {
+			return nil, errors.New(PREFIX + "invalid entropy length")
+		}
+		if len(nonce) == 0 || len(nonce) >= hdrbg.MAX_BYTES>>1 {
+			return nil, errors.New(PREFIX + "invalid nonce length")
+		}
+		if len(personalization) >= hdrbg.MAX_BYTES {
+			return nil, errors.New(PREFIX + "personalization is too long")
+		}
+	} else {
+		if len(entropy) != hd.seedLength {
+			return nil, errors.New(PREFIX + "invalid entropy length")
+		}
+		if len(personalization) > hd.seedLength {
+			return nil, errors.New(PREFIX + "personalization is too long")
+		}
+	}
+
+	hd.key = base.NewKey()
+	hd.block = base.NewBlock()
+	hd.v = make([]byte, base.BlockLen())
+
+	var seedMaterial []byte
+	if useDF {
+		// seed_material = Block_Cipher_df(entropy_input || nonce || personalization_string)
+		seedMaterial = hd.df(hd.seedLength, entropy, nonce, personalization)
+	} else {
+		// seed_material = entropy_input ^ personalization_string
+		seedMaterial = make([]byte, hd.seedLength)
+		copy(seedMaterial, personalization)
+		subtle.XORBytes(seedMaterial, seedMaterial, entropy)
+	}
+
+	hd.update(seedMaterial)
+	hd.reseedCounter = 1
+	return hd, nil
+}
+
+// Длина порождающих данных: длина ключа и блока шифра.
+func SeedLen(base models.BaseAlgorithm) int {
+	return base.KeyLen() + base.BlockLen()
+}
+
+// Создание генератора псевдослучайных данных на основе CTR_DRBG.
+func NewCtrDrbgPrng(newBase func() models.BaseAlgorithm, useDF bool, entropySource io.Reader, securityStrength int, securityLevel hdrbg.SecurityLevel, personalization []byte) (*hdrbg.DrbgPrng, error) {
+	// Самопроверка при включении (выполняется однократно, далее - сохранённый результат)
+	if err := SelfTest(); err != nil {
+		return nil, err
+	}
+
+	newDrbg := func(entropy, nonce, personalization []byte) (models.DRBG, error) {
+		return NewCtrDrbg(newBase(), securityLevel, useDF, entropy, nonce, personalization)
+	}
+	entropyLen := 0
+	if !useDF {
+		entropyLen = SeedLen(newBase())
+	}
+	return hdrbg.NewDrbgPrng(newDrbg, entropySource, securityStrength, entropyLen, personalization)
+}
+
+func (hd *CtrDrbg) NeedReseed() bool {
+	return hd.reseedCounter > hd.reseedIntervalInCounter
+}
+
+func (hd *CtrDrbg) MaxBytesPerRequest() int {
+	if hd.base.BlockLen() <= 8 {
+		return MAX_BYTES_PER_GENERATE_64
+	}
+	return hdrbg.MAX_BYTES_PER_GENERATE
+}
+
+// Зашифрование блока in ключом key в out.
+func (hd *CtrDrbg) encrypt(key models.Key, in, out []byte) {
+	copy(hd.block.Data(), in)
+	hd.base.Encrypt(key, hd.block, hd.block)
+	copy(out, hd.block.Data())
+}
+
+func increment(v []byte) {
+	for i := len(v) - 1; i >= 0; i-- {
+		v[i]++
+		if v[i] != 0 {
+			return
+		}
+	}
+}
+
+// CTR_DRBG_Update
+func (hd *CtrDrbg) update(providedData []byte) {
+	blockLen := len(hd.v)
+	temp := make([]byte, (hd.seedLength+blockLen-1)/blockLen*blockLen)
+	for i := 0; i < len(temp); i += blockLen {
+		// V = (V + 1) mod 2^blocklen, temp = temp || Block_Encrypt(Key, V)
+		increment(hd.v)
+		hd.encrypt(hd.key, hd.v, temp[i:i+blockLen])
+	}
+	temp = temp[:hd.seedLength]
+	subtle.XORBytes(temp, temp, providedData)
+
+	keyLen := hd.key.Len()
+	subtle.ConstantTimeCopy(1, hd.key.Data(), temp[:keyLen])
+	copy(hd.v, temp[keyLen:])
+	clear(temp)
+}
+
+// Подготовка дополнительных данных до длины seedlen.
+func (hd *CtrDrbg) prepareAdditional(additional []byte) []byte {
+	if hd.useDF {
+		return hd.df(hd.seedLength, additional)
+	}
+	t := make([]byte, hd.seedLength)
+	copy(t, additional)
+	return t
+}
+
+func (hd *CtrDrbg) Reseed(entropy, additional []byte) error {
+	if hd.useDF {
+		if len(entropy) == 0 || len(entropy) >= hdrbg.MAX_BYTES {
+			return errors.New(PREFIX + "invalid entropy length")
+		}
+		if len(additional) >= hdrbg.MAX_BYTES {
+			return errors.New(PREFIX + "additional input too long")
+		}
+	} else {
+		if len(entropy) != hd.seedLength {
+			return errors.New(PREFIX + "invalid entropy length")
+		}
+		if len(additional) > hd.seedLength {
+			return errors.New(PREFIX + "additional input too long")
+		}
+	}
+
+	var seedMaterial []byte
+	if hd.useDF {
+		// seed_material = Block_Cipher_df(entropy_input || additional_input)
+		seedMaterial = hd.df(hd.seedLength, entropy, additional)
+	} else {
+		// seed_material = entropy_input ^ additional_input
+		seedMaterial = hd.prepareAdditional(additional)
+		subtle.XORBytes(seedMaterial, seedMaterial, entropy)
+	}
+
+	hd.update(seedMaterial)
+	hd.reseedCounter = 1
+	return nil
+}
+
+func (hd *CtrDrbg) Generate(b, additional []byte) error {
+	if hd.NeedReseed() {
+		return hdrbg.ErrReseedRequired
+	}
+	if len(b) > hd.MaxBytesPerRequest() {
+		return errors.New(PREFIX + "too many bytes requested")
+	}
+	if !hd.useDF && len(additional) > hd.seedLength {
+		return errors.New(PREFIX + "additional input too long")
+	}
+
+	addInput := make([]byte, hd.seedLength)
+	if len(additional) > 0 {
+		addInput = hd.prepareAdditional(additional)
+		hd.update(addInput)
+	}
+
+	blockLen := len(hd.v)
+	out := make([]byte, blockLen)
+	for len(b) > 0 {
+		// V = (V + 1) mod 2^blocklen, temp = temp || Block_Encrypt(Key, V)
+		increment(hd.v)
+		hd.encrypt(hd.key, hd.v, out)
+		n := copy(b, out)
+		b = b[n:]
+	}
+	clear(out)
+
+	hd.update(addInput)
+	hd.reseedCounter++
+	return nil
+}
+
+// Block_Cipher_df: сжатие input = data[0] || data[1] || ... до n байт.
+func (hd *CtrDrbg) df(n int, data ...[]byte) []byte {
+	blockLen := hd.base.BlockLen()
+	keyLen := hd.base.KeyLen()
+
+	inputLen := 0
+	for _, d := range data {
+		inputLen += len(d)
+	}
+
+	// S = L || N || input_string || 0x80, дополненная нулями до кратности блоку
+	sLen := 8 + inputLen + 1
+	sLen = (sLen + blockLen - 1) / blockLen * blockLen
+	s := make([]byte, blockLen+sLen)
+	setUint32(s[blockLen:], uint32(inputLen))
+	setUint32(s[blockLen+4:], uint32(n))
+	off := blockLen + 8
+	for _, d := range data {
+		off += copy(s[off:], d)
+	}
+	s[off] = 0x80
+
+	// K = leftmost(0x00010203...1F, keylen)
+	k := hd.base.NewKey()
+	for i := 0; i < keyLen; i++ {
+		k.Data()[i] = byte(i)
+	}
+
+	temp := make([]byte, (keyLen+blockLen+blockLen-1)/blockLen*blockLen)
+	for i := 0; i*blockLen < keyLen+blockLen; i++ {
+		// IV = i || 0^(outlen - 32), temp = temp || BCC(K, IV || S)
+		clear(s[:blockLen])
+		setUint32(s, uint32(i))
+		hd.bcc(k, s, temp[i*blockLen:(i+1)*blockLen])
+	}
+
+	subtle.ConstantTimeCopy(1, k.Data(), temp[:keyLen])
+	x := temp[keyLen : keyLen+blockLen]
+
+	res := make([]byte, (n+blockLen-1)/blockLen*blockLen)
+	for i := 0; i < len(res); i += blockLen {
+		// X = Block_Encrypt(K, X), temp = temp || X
+		hd.encrypt(k, x, x)
+		copy(res[i:], x)
+	}
+
+	k.Clear()
+	clear(temp)
+	clear(s)
+	return res[:n]
+}
+
+// BCC: CBC-MAC с нулевым начальным значением.
+func (hd *CtrDrbg) bcc(key models.Key, data []byte, out []byte) {
+	blockLen := hd.base.BlockLen()
+	chaining := make([]byte, blockLen)
+	for i := 0; i < len(data); i += blockLen {
+		subtle.XORBytes(chaining, chaining, data[i:i+blockLen])
+		hd.encrypt(key, chaining, chaining)
+	}
+	copy(out, chaining)
+}
+
+func setUint32(b []byte, v uint32) {
+	b[0] = byte(v >> 24)
+	b[1] = byte(v >> 16)
+	b[2] = byte(v >> 8)
+	b[3] = byte(v)
+}
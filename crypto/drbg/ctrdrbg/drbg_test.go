@@ -0,0 +1,162 @@
+package ctrdrbg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/models"
+	"testing"
+)
+
+func seq(from byte, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = from + byte(i)
+	}
+	return b
+}
+
+// Известный ответ CTR_DRBG AES-256 без функции формирования (из самопроверки
+// криптографического модуля Go).
+func TestCtrDRBGNoDFKnownAnswer(t *testing.T) {
+	want := []byte{
+		0x6e, 0x6e, 0x47, 0x9d, 0x24, 0xf8, 0x6a, 0x3b,
+		0x77, 0x87, 0xa8, 0xf8, 0x18, 0x6d, 0x98, 0x5a,
+		0x53, 0xbe, 0xbe, 0xed, 0xde, 0xab, 0x92, 0x28,
+		0xf0, 0xf4, 0xac, 0x6e, 0x10, 0xbf, 0x01, 0x93,
+	}
+
+	hd, err := NewCtrDrbg(aesBase{}, hdrbg.SECURITY_LEVEL_ONE, false, seq(0x01, 48), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hd.Reseed(seq(0x31, 48), seq(0x61, 48)); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(want))
+	if err := hd.Generate(got, seq(0x61, 48)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("not expected return bits %x", got)
+	}
+}
+
+// Известный ответ CTR_DRBG AES-256 с функцией формирования Block_Cipher_df
+// (NIST CAVP, CTR_DRBG.rsp: AES-256 use df, без устойчивости к
+// компрометации, COUNT = 0). Выход - результат второго вызова Generate.
+func TestCtrDRBGDFKnownAnswer(t *testing.T) {
+	entropy, _ := hex.DecodeString("36401940fa8b1fba91a1661f211d78a0b9389a74e5bccfece8d766af1a6d3b14")
+	nonce, _ := hex.DecodeString("496f25b0f1301b4f501be30380a137eb")
+	want, _ := hex.DecodeString("5862eb38bd558dd978a696e6df164782ddd887e7e9a6c9f3f1fbafb78941b535" +
+		"a64912dfd224c6dc7454e5250b3d97165e16260c2faf1cc7735cb75fb4f07e1d")
+
+	hd, err := NewCtrDrbg(aesBase{}, hdrbg.SECURITY_LEVEL_ONE, true, entropy, nonce, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(want))
+	for i := 0; i < 2; i++ {
+		if err := hd.Generate(got, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("not expected return bits %x", got)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		if err := SelfTest(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCtrDRBG(t *testing.T) {
+	bases := []func() models.BaseAlgorithm{magma.NewMagma, kuznyechik.NewKuznyechik}
+	for _, newBase := range bases {
+		for _, useDF := range []bool{false, true} {
+			base := newBase()
+			entropy := seq(0x10, SeedLen(base))
+			nonce := seq(0x80, 16)
+
+			hd1, err := NewCtrDrbg(base, hdrbg.SECURITY_LEVEL_TEST, useDF, entropy, nonce, []byte("pers"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			hd2, err := NewCtrDrbg(newBase(), hdrbg.SECURITY_LEVEL_TEST, useDF, entropy, nonce, []byte("pers"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out1 := make([]byte, 100)
+			out2 := make([]byte, 100)
+			hd1.Generate(out1, []byte("add"))
+			hd2.Generate(out2, []byte("add"))
+			if !bytes.Equal(out1, out2) {
+				t.Errorf("[%d df=%v] same seed gives different output", base.BlockLen(), useDF)
+			}
+			if bytes.Equal(out1[:base.BlockLen()], make([]byte, base.BlockLen())) {
+				t.Errorf("[%d df=%v] zero output", base.BlockLen(), useDF)
+			}
+
+			hd2.Generate(out2, nil)
+			if bytes.Equal(out1, out2) {
+				t.Errorf("[%d df=%v] output repeats", base.BlockLen(), useDF)
+			}
+
+			for !hd1.NeedReseed() {
+				hd1.Generate(out1, nil)
+			}
+			if err := hd1.Generate(out1, nil); err != hdrbg.ErrReseedRequired {
+				t.Errorf("[%d df=%v] expected reseed required, got %v", base.BlockLen(), useDF, err)
+			}
+			if err := hd1.Reseed(entropy, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := hd1.Generate(out1, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestCtrDRBGValidation(t *testing.T) {
+	base := magma.NewMagma()
+	if _, err := NewCtrDrbg(base, hdrbg.SECURITY_LEVEL_ONE, false, seq(0, 32), nil, nil); err == nil {
+		t.Error("short entropy accepted without df")
+	}
+	if _, err := NewCtrDrbg(base, hdrbg.SECURITY_LEVEL_ONE, true, seq(0, 32), nil, nil); err == nil {
+		t.Error("empty nonce accepted with df")
+	}
+
+	hd, err := NewCtrDrbg(base, hdrbg.SECURITY_LEVEL_ONE, true, seq(0, 32), seq(0, 16), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, hd.MaxBytesPerRequest()+1)
+	if err := hd.Generate(out, nil); err == nil {
+		t.Error("too long request accepted")
+	}
+}
+
+func Test_CtrDrbgPrng(t *testing.T) {
+	for _, useDF := range []bool{false, true} {
+		prng, err := NewCtrDrbgPrng(kuznyechik.NewKuznyechik, useDF, nil, 32, hdrbg.SECURITY_LEVEL_TEST, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, hdrbg.MAX_BYTES_PER_GENERATE+1)
+		n, err := prng.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(data) {
+			t.Errorf("not got enough random bytes")
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Источники энтропии для генераторов случайных бит: сбор джиттера
+// процессора, чтение файла или устройства и смешивание нескольких
+// источников через Стрибог-256. Каждый источник сообщает оценку
+// минимальной энтропии своего выхода, по которой hdrbg.NewDrbgPrng
+// выбирает длину входной энтропии.
+package entropy
+
+import (
+	"crypto/rand"
+	"io"
+	"math"
+)
+
+const PREFIX = "crypto:drbg:entropy: "
+
+// Источник энтропии с оценкой минимальной энтропии в битах на байт выхода
+// (от 0 до 8). Источники с хэшированием отсчётов (джиттер, смеситель)
+// сообщают оценку отсчётов до хэширования. Реализует hdrbg.EntropySource.
+type Source interface {
+	io.Reader
+	MinEntropy() float64
+}
+
+type systemSource struct{}
+
+func (systemSource) Read(b []byte) (int, error) {
+	return io.ReadFull(rand.Reader, b)
+}
+
+func (systemSource) MinEntropy() float64 {
+	return 8
+}
+
+// Системный генератор (crypto/rand), считающийся источником полной энтропии.
+var System Source = systemSource{}
+
+// Число байт источника, содержащих не менее bits бит минимальной энтропии.
+func InputLength(src Source, bits int) int {
+	return int(math.Ceil(float64(bits) / src.MinEntropy()))
+}
+
+// Оценка минимальной энтропии на отсчёт по наиболее частому значению
+// (SP 800-90B, 6.3.1) с верхней границей доверительного интервала 99%.
+func EstimateMinEntropy(samples []byte) float64 {
+	n := len(samples)
+	if n < 2 {
+		return 0
+	}
+	var counts [256]int
+	mode := 0
+	for _, s := range samples {
+		counts[s]++
+		mode = max(mode, counts[s])
+	}
+	p := float64(mode) / float64(n)
+	pu := math.Min(1, p+2.576*math.Sqrt(p*(1-p)/float64(n-1)))
+	return -math.Log2(pu)
+}
@@ -0,0 +1,216 @@
+package entropy
+
+import (
+	"bytes"
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Источник с заданной оценкой, считающий прочитанные байты.
+type countingSource struct {
+	minEntropy float64
+	read       int
+	err        error
+}
+
+func (s *countingSource) Read(b []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	for i := range b {
+		b[i] = byte(s.read + i)
+	}
+	s.read += len(b)
+	return len(b), nil
+}
+
+func (s *countingSource) MinEntropy() float64 {
+	return s.minEntropy
+}
+
+func TestEstimateMinEntropy(t *testing.T) {
+	if h := EstimateMinEntropy(bytes.Repeat([]byte{7}, 1000)); h != 0 {
+		t.Errorf("constant samples estimate %f", h)
+	}
+	uniform := make([]byte, 256*64)
+	for i := range uniform {
+		uniform[i] = byte(i)
+	}
+	if h := EstimateMinEntropy(uniform); h < 7 || h > 8 {
+		t.Errorf("uniform samples estimate %f", h)
+	}
+}
+
+func TestJitterSource(t *testing.T) {
+	s, err := NewJitterSource()
+	if err == ErrNoJitter {
+		t.Skip("timer resolution is too coarse")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.SampleEntropy() <= 0 || s.SampleEntropy() > JITTER_MAX_SAMPLE_ENTROPY {
+		t.Errorf("incorrect sample entropy %f", s.SampleEntropy())
+	}
+	if s.MinEntropy() != s.SampleEntropy() {
+		t.Errorf("min-entropy %f is not the raw sample estimate", s.MinEntropy())
+	}
+	a := make([]byte, 40)
+	b := make([]byte, 40)
+	if _, err := s.Read(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) {
+		t.Error("repeated output")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entropy.bin")
+	if err := os.WriteFile(path, []byte("0123456789"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileSource(path, 9); err == nil {
+		t.Error("invalid estimate accepted")
+	}
+	s, err := NewFileSource(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	b := make([]byte, 6)
+	if _, err := s.Read(b); err != nil || string(b) != "012345" {
+		t.Fatalf("incorrect read %q: %v", b, err)
+	}
+	if _, err := s.Read(b); err == nil {
+		t.Error("exhausted file read without error")
+	}
+	if InputLength(s, 256) != 128 {
+		t.Errorf("incorrect input length %d", InputLength(s, 256))
+	}
+}
+
+func TestMixer(t *testing.T) {
+	low := &countingSource{minEntropy: 0.5}
+	full := &countingSource{minEntropy: 8}
+	m, err := NewMixer(low, full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, 64)
+	if _, err := m.Read(out); err != nil {
+		t.Fatal(err)
+	}
+	// Каждый проход даёт 8 + 128 бит, для 320 бит нужно 3 прохода на блок
+	if low.read != 2*3*MIXER_CHUNK_SIZE || full.read != low.read {
+		t.Errorf("incorrect read counts %d %d", low.read, full.read)
+	}
+
+	// Первый блок: Стрибог-256(счётчик 0 || порции источников)
+	low2 := &countingSource{minEntropy: 0.5}
+	full2 := &countingSource{minEntropy: 8}
+	h := streebog.New256()
+	h.Write(make([]byte, 8))
+	chunk := make([]byte, MIXER_CHUNK_SIZE)
+	for i := 0; i < 3; i++ {
+		low2.Read(chunk)
+		h.Write(chunk)
+		full2.Read(chunk)
+		h.Write(chunk)
+	}
+	if !bytes.Equal(out[:32], h.Sum(nil)) {
+		t.Error("incorrect mixer output")
+	}
+	if bytes.Equal(out[:32], out[32:]) {
+		t.Error("repeated mixer blocks")
+	}
+
+	fail := errors.New("fail")
+	m, _ = NewMixer(full, &countingSource{minEntropy: 1, err: fail})
+	if _, err := m.Read(out); err != fail {
+		t.Errorf("incorrect error %v", err)
+	}
+	if _, err := NewMixer(&countingSource{}); err == nil {
+		t.Error("mixer without entropy accepted")
+	}
+	if m, _ := NewMixer(low, full); m.MinEntropy() != 4.25 {
+		t.Errorf("incorrect mixer min-entropy %f", m.MinEntropy())
+	}
+}
+
+// Источник, повторяющий один байт.
+type stuckSource struct {
+	minEntropy float64
+}
+
+func (s *stuckSource) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0x42
+	}
+	return len(b), nil
+}
+
+func (s *stuckSource) MinEntropy() float64 {
+	return s.minEntropy
+}
+
+// Отказ источника обнаруживается по его данным до смешивания.
+func TestMixerHealth(t *testing.T) {
+	m, err := NewMixer(&countingSource{minEntropy: 8}, &stuckSource{minEntropy: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var herr *hdrbg.HealthTestError
+	if _, err := m.Read(make([]byte, 32)); !errors.As(err, &herr) || herr.Test != "repetition count" {
+		t.Errorf("stuck source not detected: %v", err)
+	}
+	// Источник без оценки энтропии не проверяется
+	m, err = NewMixer(&countingSource{minEntropy: 8}, &stuckSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Read(make([]byte, 32)); err != nil {
+		t.Error(err)
+	}
+}
+
+// Длина входной энтропии генератора определяется оценкой источника.
+func TestDrbgEntropyBudget(t *testing.T) {
+	for _, h := range []float64{8, 4, 0.5} {
+		src := &countingSource{minEntropy: h}
+		_, err := hdrbg.NewHashDrbgPrng(streebog.New256, src, 32, hdrbg.SECURITY_LEVEL_ONE, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := hdrbg.STARTUP_SAMPLES_COUNT + int(math.Ceil(256/h)) + int(math.Ceil(128/h))
+		if src.read != want {
+			t.Errorf("min-entropy %f: read %d bytes, want %d", h, src.read, want)
+		}
+	}
+
+	src := &countingSource{minEntropy: 4}
+	if _, err := hdrbg.NewDrbgPrng(nil, src, 32, 48, nil); err == nil {
+		t.Error("explicit entropy length accepted for low-entropy source")
+	}
+
+	m, err := NewMixer(System, &countingSource{minEntropy: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prng, err := hdrbg.NewHashDrbgPrng(streebog.New256, m, 32, hdrbg.SECURITY_LEVEL_ONE, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prng.Read(make([]byte, 64)); err != nil {
+		t.Fatal(err)
+	}
+}
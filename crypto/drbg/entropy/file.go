@@ -0,0 +1,59 @@
+package entropy
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// Источник энтропии из файла или устройства (например, /dev/hwrng) с
+// заявленной оценкой минимальной энтропии.
+type FileSource struct {
+	mtx        sync.Mutex
+	path       string
+	file       *os.File
+	minEntropy float64
+}
+
+// Создание источника; файл открывается при первом чтении.
+func NewFileSource(path string, minEntropy float64) (*FileSource, error) {
+	if minEntropy <= 0 || minEntropy > 8 {
+		return nil, errors.New(PREFIX + "invalid min-entropy estimate")
+	}
+	return &FileSource{path: path, minEntropy: minEntropy}, nil
+}
+
+func (s *FileSource) Read(b []byte) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.file == nil {
+		f, err := os.Open(s.path)
+		if err != nil {
+			return 0, err
+		}
+		s.file = f
+	}
+	n, err := io.ReadFull(s.file, b)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return n, errors.New(PREFIX + "entropy file exhausted")
+	}
+	return n, err
+}
+
+func (s *FileSource) MinEntropy() float64 {
+	return s.minEntropy
+}
+
+func (s *FileSource) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,118 @@
+package entropy
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"math"
+	"sync"
+	"time"
+)
+
+// Число отсчётов для оценки энтропии при создании источника.
+const JITTER_ESTIMATE_SAMPLES = 1024
+
+// Наибольшая энтропия, приписываемая одному отсчёту джиттера (бит).
+const JITTER_MAX_SAMPLE_ENTROPY = 1.0
+
+// Наименьшая допустимая оценка энтропии отсчёта (бит).
+const JITTER_MIN_SAMPLE_ENTROPY = 0.05
+
+// Размер буфера, по которому проходит рабочая нагрузка.
+const JITTER_MEMORY_SIZE = 4096
+
+var ErrNoJitter = errors.New(PREFIX + "insufficient timing jitter")
+
+// Источник энтропии на основе джиттера времени выполнения: отсчётом
+// служит младший байт второй разности времён выполнения рабочей
+// нагрузки с обращениями к памяти. Каждые 32 байта выхода получаются
+// хэшированием Стрибог-256 отсчётов с суммарной оценкой не менее
+// 256 + 64 бит (SP 800-90B, 3.1.5.1.2). Непрерывные тесты RCT и APT
+// выполняются над отсчётами до хэширования с порогами по их оценке.
+// MinEntropy сообщает оценку отсчёта, а не выхода после хэширования.
+type JitterSource struct {
+	mtx           sync.Mutex
+	memory        []byte
+	index         int
+	lastDelta     int64
+	sampleEntropy float64
+	samplesCount  int
+	health        *hdrbg.HealthTester
+}
+
+// Создание источника с оценкой энтропии отсчёта по JITTER_ESTIMATE_SAMPLES
+// отсчётам (не более JITTER_MAX_SAMPLE_ENTROPY бит). Стартовые тесты
+// выполняются над hdrbg.STARTUP_SAMPLES_COUNT новыми отсчётами.
+func NewJitterSource() (*JitterSource, error) {
+	s := &JitterSource{memory: make([]byte, JITTER_MEMORY_SIZE)}
+	s.sample()
+
+	samples := make([]byte, JITTER_ESTIMATE_SAMPLES)
+	for i := range samples {
+		samples[i] = s.sample()
+	}
+	s.sampleEntropy = math.Min(EstimateMinEntropy(samples), JITTER_MAX_SAMPLE_ENTROPY)
+	if s.sampleEntropy < JITTER_MIN_SAMPLE_ENTROPY {
+		return nil, ErrNoJitter
+	}
+	s.samplesCount = int(math.Ceil((streebog.Size256*8 + 64) / s.sampleEntropy))
+
+	var err error
+	if s.health, err = hdrbg.NewHealthTester(hdrbg.HealthTestConfigFor(s.sampleEntropy)); err != nil {
+		return nil, err
+	}
+	startup := make([]byte, hdrbg.STARTUP_SAMPLES_COUNT)
+	for i := range startup {
+		startup[i] = s.sample()
+	}
+	if err := s.health.Check(startup); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Оценка энтропии одного отсчёта джиттера (бит).
+func (s *JitterSource) SampleEntropy() float64 {
+	return s.sampleEntropy
+}
+
+func (s *JitterSource) MinEntropy() float64 {
+	return s.sampleEntropy
+}
+
+func (s *JitterSource) Read(b []byte) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	h := streebog.New256()
+	samples := make([]byte, s.samplesCount)
+	total := 0
+	for total < len(b) {
+		for i := range samples {
+			samples[i] = s.sample()
+		}
+		if err := s.health.Check(samples); err != nil {
+			clear(samples)
+			clear(b)
+			return 0, err
+		}
+		h.Reset()
+		h.Write(samples)
+		total += copy(b[total:], h.Sum(nil))
+	}
+	clear(samples)
+	return total, nil
+}
+
+// Один отсчёт: время прохода по памяти с зависящим от данных шагом.
+func (s *JitterSource) sample() byte {
+	start := time.Now()
+	for i := 0; i < 64; i++ {
+		s.index = (s.index + int(s.memory[s.index]) + 67) % len(s.memory)
+		s.memory[s.index]++
+	}
+	delta := int64(time.Since(start))
+	d := delta - s.lastDelta
+	s.lastDelta = delta
+	return byte(d) ^ byte(d>>8)
+}
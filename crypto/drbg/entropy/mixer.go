@@ -0,0 +1,96 @@
+package entropy
+
+import (
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"io"
+	"sync"
+)
+
+// Размер порции, читаемой из каждого источника за один проход.
+const MIXER_CHUNK_SIZE = 16
+
+// Смеситель нескольких источников: каждые 32 байта выхода получаются как
+// Стрибог-256(счётчик || данные источников), где данные читаются порциями
+// по кругу до суммарной оценки не менее 256 + 64 бит минимальной энтропии.
+// Порции каждого источника до хэширования проходят непрерывные тесты RCT
+// и APT с порогами по оценке этого источника. Отказ любого источника или
+// теста приводит к ошибке чтения.
+type Mixer struct {
+	mtx        sync.Mutex
+	sources    []Source
+	health     []*hdrbg.HealthTester
+	minEntropy float64
+	counter    uint64
+}
+
+func NewMixer(sources ...Source) (*Mixer, error) {
+	total := 0.0
+	health := make([]*hdrbg.HealthTester, len(sources))
+	for i, s := range sources {
+		if s == nil {
+			return nil, errors.New(PREFIX + "nil entropy source")
+		}
+		h := s.MinEntropy()
+		if h < 0 || h > 8 {
+			return nil, errors.New(PREFIX + "invalid min-entropy estimate")
+		}
+		total += h
+		// Источник без оценки энтропии не учитывается и не проверяется
+		if h == 0 {
+			continue
+		}
+		var err error
+		if health[i], err = hdrbg.NewHealthTester(hdrbg.HealthTestConfigFor(h)); err != nil {
+			return nil, err
+		}
+	}
+	if total == 0 {
+		return nil, errors.New(PREFIX + "no entropy sources")
+	}
+	return &Mixer{sources: sources, health: health, minEntropy: total / float64(len(sources))}, nil
+}
+
+// Оценка минимальной энтропии на байт смешиваемых данных: среднее оценок
+// источников, так как из каждого читается одинаковое число байт.
+func (m *Mixer) MinEntropy() float64 {
+	return m.minEntropy
+}
+
+func (m *Mixer) Read(b []byte) (int, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	h := streebog.New256()
+	chunk := make([]byte, MIXER_CHUNK_SIZE)
+	defer clear(chunk)
+
+	total := 0
+	for total < len(b) {
+		h.Reset()
+		var ctr [8]byte
+		binary.BigEndian.PutUint64(ctr[:], m.counter)
+		m.counter++
+		h.Write(ctr[:])
+
+		collected := 0.0
+		for collected < streebog.Size256*8+64 {
+			for i, s := range m.sources {
+				if _, err := io.ReadFull(s, chunk); err != nil {
+					return total, err
+				}
+				if m.health[i] != nil {
+					if err := m.health[i].Check(chunk); err != nil {
+						return total, err
+					}
+				}
+				h.Write(chunk)
+				collected += s.MinEntropy() * MIXER_CHUNK_SIZE
+			}
+		}
+		total += copy(b[total:], h.Sum(nil))
+	}
+	return total, nil
+}
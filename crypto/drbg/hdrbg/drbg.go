@@ -16,15 +16,19 @@ type HashDrbg struct {
 	reseedTime              time.Time
 	reseedCounter           uint64
 	reseedIntervalInCounter uint64
-	securityLevel           SecurityLevel
-	newHash                 func() hash.Hash
-	hashSize                int
+	reseedIntervalInTime    time.Duration
+	// Источник текущего времени (подменяется в тестах).
+	now           func() time.Time
+	securityLevel SecurityLevel
+	newHash       func() hash.Hash
+	hashSize      int
 }
 
 func NewHashDrbg(newHash func() hash.Hash, securityLevel SecurityLevel, entropy, nonce, personalization []byte) (*HashDrbg, error) {
 	hd := &HashDrbg{}
 
 	hd.newHash = newHash
+	hd.now = time.Now
 	hd.setSecurityLevel(securityLevel)
 
 	md := newHash()
@@ -70,13 +74,18 @@ func NewHashDrbg(newHash func() hash.Hash, securityLevel SecurityLevel, entropy,
 	copy(hd.c, seed)
 
 	hd.reseedCounter = 1
-	hd.reseedTime = time.Now()
+	hd.reseedTime = hd.now()
 
 	return hd, nil
 }
 
+// Перезапуск требуется по исчерпании числа запросов или по истечении
+// интервала времени с последней инициализации.
 func (hd *HashDrbg) NeedReseed() bool {
-	return (hd.reseedCounter > hd.reseedIntervalInCounter)
+	if hd.reseedCounter > hd.reseedIntervalInCounter {
+		return true
+	}
+	return hd.reseedIntervalInTime > 0 && hd.now().Sub(hd.reseedTime) >= hd.reseedIntervalInTime
 }
 
 func (hd *HashDrbg) MaxBytesPerRequest() int {
@@ -85,17 +94,16 @@ func (hd *HashDrbg) MaxBytesPerRequest() int {
 
 func (hd *HashDrbg) setSecurityLevel(securityLevel SecurityLevel) {
 	hd.securityLevel = securityLevel
-	switch securityLevel {
-	case SECURITY_LEVEL_TWO:
-		hd.reseedIntervalInCounter = DRBG_RESEED_COUNTER_INTERVAL_LEVEL2
-	case SECURITY_LEVEL_TEST:
-		hd.reseedIntervalInCounter = DRBG_RESEED_COUNTER_INTERVAL_LEVEL_TEST
-	default:
-		hd.reseedIntervalInCounter = DRBG_RESEED_COUNTER_INTERVAL_LEVEL1
-	}
+	hd.reseedIntervalInCounter = ReseedInterval(securityLevel)
+	hd.reseedIntervalInTime = ReseedTimeInterval(securityLevel)
 }
 
-// Инициализация с новой энтропией
+// Установка интервала перезапуска по времени (0 - без ограничения).
+func (hd *HashDrbg) SetReseedTimeInterval(interval time.Duration) {
+	hd.reseedIntervalInTime = interval
+}
+
+// Инициализация с новой энтропией (SP 800-90A, 10.1.1.3)
 func (hd *HashDrbg) Reseed(entropy, additional []byte) error {
 	// here for the min length, we just check <=0 now
 	if len(entropy) == 0 || len(entropy) >= MAX_BYTES {
@@ -119,14 +127,14 @@ func (hd *HashDrbg) Reseed(entropy, additional []byte) error {
 	copy(hd.v, seed)
 	temp := make([]byte, hd.seedLength+1)
 
-	// C = Hash_df(0x01 || V, seed_length)
+	// C = Hash_df(0x00 || V, seed_length)
 	temp[0] = 0
 	copy(temp[1:], seed)
 	seed = hd.hashDf(temp, hd.seedLength)
 	copy(hd.c, seed)
 
 	hd.reseedCounter = 1
-	hd.reseedTime = time.Now()
+	hd.reseedTime = hd.now()
 	return nil
 }
 
@@ -0,0 +1,185 @@
+package hdrbg
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// Размер окна адаптивного теста пропорции для 8-битных отсчётов (SP 800-90B, 4.4.2).
+const APT_WINDOW_SIZE = 512
+
+// Число отсчётов, проверяемых при запуске (SP 800-90B, 4.3).
+const STARTUP_SAMPLES_COUNT = 1024
+
+// Пороги непрерывных тестов источника энтропии.
+type HealthTestConfig struct {
+	// Порог теста числа повторений (Repetition Count Test).
+	RCTCutoff int
+	// Порог адаптивного теста пропорции (Adaptive Proportion Test).
+	APTCutoff int
+}
+
+// Пороги для оценки минимальной энтропии 4 бита на байт (HealthTestConfigFor(4)).
+var DefaultHealthTestConfig = HealthTestConfig{
+	RCTCutoff: 6,
+	APTCutoff: 62,
+}
+
+// Вероятность ложной тревоги непрерывных тестов: 2^-HEALTH_TEST_ALPHA_LOG.
+const HEALTH_TEST_ALPHA_LOG = 20
+
+// Пороги для источника с минимальной энтропией h бит на байт и
+// вероятностью ложной тревоги 2^-20 (SP 800-90B, 4.4.1 и 4.4.2):
+// C_RCT = 1 + ceil(20 / h), C_APT = 1 + CRITBINOM(W, 2^-h, 1 - 2^-20).
+func HealthTestConfigFor(h float64) HealthTestConfig {
+	if h <= 0 || h > 8 {
+		h = 8
+	}
+	return HealthTestConfig{
+		RCTCutoff: 1 + int(math.Ceil(HEALTH_TEST_ALPHA_LOG/h)),
+		APTCutoff: 1 + critBinom(APT_WINDOW_SIZE, math.Exp2(-h), HEALTH_TEST_ALPHA_LOG),
+	}
+}
+
+// Наименьшее k, для которого P(X <= k) >= 1 - 2^-alphaLog при X ~ B(n, p).
+// Хвост P(X > k) суммируется в логарифмах, чтобы не терять точность.
+func critBinom(n int, p float64, alphaLog int) int {
+	alpha := math.Exp2(-float64(alphaLog))
+	lp, lq := math.Log(p), math.Log1p(-p)
+	lgn, _ := math.Lgamma(float64(n + 1))
+	pmf := func(k int) float64 {
+		a, _ := math.Lgamma(float64(k + 1))
+		b, _ := math.Lgamma(float64(n - k + 1))
+		return math.Exp(lgn - a - b + float64(k)*lp + float64(n-k)*lq)
+	}
+	tail := 0.0
+	for k := n; k > 0; k-- {
+		tail += pmf(k)
+		if tail > alpha {
+			return k
+		}
+	}
+	return 0
+}
+
+// Ошибка непрерывного или стартового теста источника энтропии.
+type HealthTestError struct {
+	Test   string
+	Sample byte
+	Count  int
+}
+
+func (e *HealthTestError) Error() string {
+	return PREFIX + "entropy source health test failed: " + e.Test
+}
+
+// Непрерывные тесты RCT и APT над потоком 8-битных отсчётов. После первой
+// ошибки все последующие проверки возвращают ту же ошибку.
+type HealthTester struct {
+	config HealthTestConfig
+	err    error
+
+	rctSample byte
+	rctCount  int
+
+	aptSample byte
+	aptCount  int
+	aptIndex  int
+}
+
+func NewHealthTester(config HealthTestConfig) (*HealthTester, error) {
+	if config.RCTCutoff < 2 || config.APTCutoff < 2 || config.APTCutoff > APT_WINDOW_SIZE {
+		return nil, errors.New(PREFIX + "invalid health test cutoffs")
+	}
+	return &HealthTester{config: config}, nil
+}
+
+// Проверка очередных отсчётов.
+func (t *HealthTester) Check(samples []byte) error {
+	if t.err != nil {
+		return t.err
+	}
+	for _, x := range samples {
+		if err := t.check(x); err != nil {
+			t.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Ошибка, переведшая тесты в состояние отказа.
+func (t *HealthTester) Err() error {
+	return t.err
+}
+
+func (t *HealthTester) check(x byte) error {
+	// Repetition Count Test
+	if t.rctCount > 0 && x == t.rctSample {
+		t.rctCount++
+		if t.rctCount >= t.config.RCTCutoff {
+			return &HealthTestError{Test: "repetition count", Sample: x, Count: t.rctCount}
+		}
+	} else {
+		t.rctSample = x
+		t.rctCount = 1
+	}
+
+	// Adaptive Proportion Test
+	if t.aptIndex == 0 {
+		t.aptSample = x
+		t.aptCount = 1
+	} else if x == t.aptSample {
+		t.aptCount++
+		if t.aptCount >= t.config.APTCutoff {
+			return &HealthTestError{Test: "adaptive proportion", Sample: x, Count: t.aptCount}
+		}
+	}
+	t.aptIndex = (t.aptIndex + 1) % APT_WINDOW_SIZE
+	return nil
+}
+
+// Источник энтропии с непрерывными тестами. После первой ошибки все
+// последующие чтения возвращают ту же ошибку.
+type HealthTestedSource struct {
+	src    io.Reader
+	tester *HealthTester
+}
+
+// Оборачивает src непрерывными тестами и выполняет стартовые тесты
+// на STARTUP_SAMPLES_COUNT отсчётах.
+func NewHealthTestedSource(src io.Reader, config HealthTestConfig) (*HealthTestedSource, error) {
+	if src == nil {
+		return nil, errors.New(PREFIX + "nil entropy source")
+	}
+	tester, err := NewHealthTester(config)
+	if err != nil {
+		return nil, err
+	}
+	s := &HealthTestedSource{src: src, tester: tester}
+
+	startup := make([]byte, STARTUP_SAMPLES_COUNT)
+	if _, err := io.ReadFull(s, startup); err != nil {
+		return nil, err
+	}
+	clear(startup)
+	return s, nil
+}
+
+func (s *HealthTestedSource) Read(b []byte) (int, error) {
+	if err := s.tester.Err(); err != nil {
+		return 0, err
+	}
+	n, err := s.src.Read(b)
+	if herr := s.tester.Check(b[:n]); herr != nil {
+		clear(b[:n])
+		return 0, herr
+	}
+	return n, err
+}
+
+// Ошибка, переведшая источник в состояние отказа.
+func (s *HealthTestedSource) Err() error {
+	return s.tester.Err()
+}
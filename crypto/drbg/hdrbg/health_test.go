@@ -0,0 +1,179 @@
+package hdrbg
+
+import (
+	"crypto/rand"
+	"errors"
+	"gost_magma_cbc/crypto/hash/streebog"
+	mrand "math/rand"
+	"testing"
+)
+
+// Источник, выдающий одно и то же значение.
+type stuckSource struct {
+	value byte
+}
+
+func (s *stuckSource) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = s.value
+	}
+	return len(b), nil
+}
+
+// Источник, выдающий value в каждом втором отсчёте.
+type biasedSource struct {
+	value byte
+	n     int
+}
+
+func (s *biasedSource) Read(b []byte) (int, error) {
+	rand.Read(b)
+	for i := range b {
+		if s.n%2 == 0 {
+			b[i] = s.value
+		} else if b[i] == s.value {
+			b[i] ^= 0xff
+		}
+		s.n++
+	}
+	return len(b), nil
+}
+
+// Исправный источник, который залипает после limit байт.
+type failingSource struct {
+	limit int
+	read  int
+}
+
+func (s *failingSource) Read(b []byte) (int, error) {
+	rand.Read(b)
+	for i := range b {
+		if s.read >= s.limit {
+			b[i] = 0
+		}
+		s.read++
+	}
+	return len(b), nil
+}
+
+// Источник с энтропией 1 бит на байт: равновероятные байты 0 и 1.
+type bitSource struct {
+	rnd *mrand.Rand
+}
+
+func (s *bitSource) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = byte(s.rnd.Intn(2))
+	}
+	return len(b), nil
+}
+
+func (s *bitSource) MinEntropy() float64 {
+	return 1
+}
+
+func TestHealthStartupStuck(t *testing.T) {
+	_, err := NewHealthTestedSource(&stuckSource{value: 0x42}, DefaultHealthTestConfig)
+	var herr *HealthTestError
+	if !errors.As(err, &herr) {
+		t.Fatalf("expected health test error, got %v", err)
+	}
+	if herr.Test != "repetition count" {
+		t.Errorf("failed test is %s", herr.Test)
+	}
+
+	_, err = NewHashDrbgPrng(streebog.New256, &stuckSource{}, 32, SECURITY_LEVEL_ONE, nil)
+	if !errors.As(err, &herr) {
+		t.Errorf("drbg instantiated over stuck source: %v", err)
+	}
+}
+
+func TestHealthStartupBiased(t *testing.T) {
+	_, err := NewHealthTestedSource(&biasedSource{value: 0x17}, DefaultHealthTestConfig)
+	var herr *HealthTestError
+	if !errors.As(err, &herr) {
+		t.Fatalf("expected health test error, got %v", err)
+	}
+	if herr.Test != "adaptive proportion" {
+		t.Errorf("failed test is %s", herr.Test)
+	}
+}
+
+func TestHealthCutoffs(t *testing.T) {
+	// Ослабленные пороги пропускают смещённый источник
+	config := HealthTestConfig{RCTCutoff: 6, APTCutoff: APT_WINDOW_SIZE}
+	if _, err := NewHealthTestedSource(&biasedSource{value: 0x17}, config); err != nil {
+		t.Errorf("biased source rejected with relaxed cutoffs: %v", err)
+	}
+
+	if _, err := NewHealthTestedSource(rand.Reader, HealthTestConfig{RCTCutoff: 1, APTCutoff: 10}); err == nil {
+		t.Error("invalid cutoffs accepted")
+	}
+}
+
+func TestHealthContinuousFailure(t *testing.T) {
+	src := &failingSource{limit: STARTUP_SAMPLES_COUNT + 3*32 + 16}
+	prng, err := NewHashDrbgPrng(streebog.New256, src, 32, SECURITY_LEVEL_TEST, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 32)
+	var herr *HealthTestError
+	for i := 0; i < 100 && err == nil; i++ {
+		_, err = prng.GenerateWithOptions(data, nil, true)
+	}
+	if !errors.As(err, &herr) {
+		t.Fatalf("expected health test error, got %v", err)
+	}
+	if prng.Err() != herr {
+		t.Error("drbg is not in error state")
+	}
+
+	// Генератор остаётся в состоянии отказа даже без перезапуска
+	if _, err := prng.Read(data); err != herr {
+		t.Errorf("drbg works after health test failure: %v", err)
+	}
+}
+
+func TestHealthTestConfigFor(t *testing.T) {
+	if HealthTestConfigFor(4) != DefaultHealthTestConfig {
+		t.Errorf("cutoffs for 4 bits: %v", HealthTestConfigFor(4))
+	}
+	// Таблица 2 SP 800-90B (W = 512)
+	for _, c := range []struct {
+		h        float64
+		rct, apt int
+	}{{0.5, 41, 410}, {1, 21, 311}, {2, 11, 177}, {4, 6, 62}, {8, 4, 13}} {
+		got := HealthTestConfigFor(c.h)
+		if got.RCTCutoff != c.rct || got.APTCutoff != c.apt {
+			t.Errorf("min-entropy %v: cutoffs %v, want {%d %d}", c.h, got, c.rct, c.apt)
+		}
+	}
+}
+
+// Пороги выбираются по заявленной оценке источника: исправный источник
+// с низкой энтропией проходит тесты, а пороги для 4 бит на байт его
+// отвергают.
+func TestHealthLowEntropySource(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		src := &bitSource{rnd: mrand.New(mrand.NewSource(seed))}
+		prng, err := NewHashDrbgPrng(streebog.New256, src, 32, SECURITY_LEVEL_ONE, nil)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if _, err := prng.Read(make([]byte, 64)); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+	}
+
+	src := &bitSource{rnd: mrand.New(mrand.NewSource(0))}
+	if _, err := NewHealthTestedSource(src, DefaultHealthTestConfig); err == nil {
+		t.Error("1-bit source passed 4-bit cutoffs")
+	}
+	// Залипание источника по-прежнему обнаруживается
+	stuck := &stuckSource{}
+	if _, err := NewHealthTestedSource(stuck, HealthTestConfigFor(1)); err == nil {
+		t.Error("stuck source passed 1-bit cutoffs")
+	}
+}
@@ -0,0 +1,198 @@
+package hdrbg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Тестовый вектор Hash_DRBG в формате файлов ответов CAVP (.rsp).
+type TestVector struct {
+	Hash                  string
+	PredictionResistance  bool
+	Count                 int
+	EntropyInput          []byte
+	Nonce                 []byte
+	PersonalizationString []byte
+	EntropyInputReseed    []byte
+	AdditionalInputReseed []byte
+	AdditionalInput       [][]byte
+	EntropyInputPR        [][]byte
+	ReturnedBits          []byte
+}
+
+// Хэш-функции, известные по именам секций файлов ответов.
+var KnownHashes = map[string]func() hash.Hash{
+	"Streebog-256": streebog.New256,
+	"Streebog-512": streebog.New512,
+}
+
+// Разбор файла ответов. Секции задаются заголовками вида [Streebog-256]
+// и [PredictionResistance = False], векторы начинаются с COUNT.
+func ParseTestVectors(r io.Reader) ([]TestVector, error) {
+	var res []TestVector
+	var cur *TestVector
+	hashName := ""
+	pr := false
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	line := 0
+	for sc.Scan() {
+		line++
+		s := strings.TrimSpace(sc.Text())
+		if len(s) == 0 || s[0] == '#' {
+			continue
+		}
+
+		if s[0] == '[' && s[len(s)-1] == ']' {
+			s = s[1 : len(s)-1]
+			name, value, ok := strings.Cut(s, "=")
+			if !ok {
+				hashName = strings.TrimSpace(s)
+				continue
+			}
+			if strings.TrimSpace(name) == "PredictionResistance" {
+				pr = strings.TrimSpace(value) == "True"
+			}
+			continue
+		}
+
+		name, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf(PREFIX+"incorrect line %d", line)
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+
+		if name == "COUNT" {
+			res = append(res, TestVector{Hash: hashName, PredictionResistance: pr})
+			cur = &res[len(res)-1]
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf(PREFIX+"incorrect count in line %d", line)
+			}
+			cur.Count = n
+			continue
+		}
+		if cur == nil {
+			return nil, fmt.Errorf(PREFIX+"value before COUNT in line %d", line)
+		}
+
+		data, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf(PREFIX+"incorrect hex in line %d", line)
+		}
+		switch name {
+		case "EntropyInput":
+			cur.EntropyInput = data
+		case "Nonce":
+			cur.Nonce = data
+		case "PersonalizationString":
+			cur.PersonalizationString = data
+		case "EntropyInputReseed":
+			cur.EntropyInputReseed = data
+		case "AdditionalInputReseed":
+			cur.AdditionalInputReseed = data
+		case "AdditionalInput":
+			cur.AdditionalInput = append(cur.AdditionalInput, data)
+		case "EntropyInputPR":
+			cur.EntropyInputPR = append(cur.EntropyInputPR, data)
+		case "ReturnedBits":
+			cur.ReturnedBits = data
+		default:
+			return nil, fmt.Errorf(PREFIX+"unknown field %s in line %d", name, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Выполнение вектора: инициализация, перезапуск (или перезапуск перед
+// каждым запросом при устойчивости к предсказанию), два запроса и сравнение
+// результата второго запроса с ReturnedBits.
+func (tv *TestVector) Run(newHash func() hash.Hash) error {
+	if len(tv.AdditionalInput) != 2 {
+		return fmt.Errorf(PREFIX+"vector %d: expected 2 additional inputs", tv.Count)
+	}
+	if tv.PredictionResistance && len(tv.EntropyInputPR) != 2 {
+		return fmt.Errorf(PREFIX+"vector %d: expected 2 prediction resistance entropy inputs", tv.Count)
+	}
+
+	hd, err := NewHashDrbg(newHash, SECURITY_LEVEL_ONE, tv.EntropyInput, tv.Nonce, tv.PersonalizationString)
+	if err != nil {
+		return err
+	}
+	if !tv.PredictionResistance && len(tv.EntropyInputReseed) > 0 {
+		if err := hd.Reseed(tv.EntropyInputReseed, tv.AdditionalInputReseed); err != nil {
+			return err
+		}
+	}
+
+	out := make([]byte, len(tv.ReturnedBits))
+	for i := 0; i < 2; i++ {
+		additional := tv.AdditionalInput[i]
+		if tv.PredictionResistance {
+			if err := hd.Reseed(tv.EntropyInputPR[i], additional); err != nil {
+				return err
+			}
+			additional = nil
+		}
+		if err := hd.Generate(out, additional); err != nil {
+			return err
+		}
+	}
+
+	if !bytes.Equal(out, tv.ReturnedBits) {
+		return fmt.Errorf(PREFIX+"vector %s/%d: returned bits mismatch", tv.Hash, tv.Count)
+	}
+	return nil
+}
+
+// Выполнение всех векторов файла ответов. Возвращает число выполненных векторов.
+func RunTestVectors(r io.Reader, hashes map[string]func() hash.Hash) (int, error) {
+	vectors, err := ParseTestVectors(r)
+	if err != nil {
+		return 0, err
+	}
+	for i := range vectors {
+		newHash, ok := hashes[vectors[i].Hash]
+		if !ok {
+			return i, errors.New(PREFIX + "unknown hash " + vectors[i].Hash)
+		}
+		if err := vectors[i].Run(newHash); err != nil {
+			return i, err
+		}
+	}
+	return len(vectors), nil
+}
+
+var (
+	selfTestOnce sync.Once
+	selfTestErr  error
+)
+
+// Самопроверка при включении: однократное выполнение встроенных векторов
+// Hash_DRBG со Стрибог-256 и Стрибог-512.
+func SelfTest() error {
+	selfTestOnce.Do(func() {
+		n, err := RunTestVectors(strings.NewReader(selfTestVectors), KnownHashes)
+		if err == nil && n == 0 {
+			err = errors.New(PREFIX + "no self test vectors")
+		}
+		if err != nil {
+			selfTestErr = errors.New(PREFIX + "self test failed: " + err.Error())
+		}
+	})
+	return selfTestErr
+}
@@ -0,0 +1,60 @@
+package hdrbg
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"os"
+	"strings"
+	"testing"
+)
+
+func runVectorsFile(t *testing.T, path string, hashes map[string]func() hash.Hash, count int) {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n, err := RunTestVectors(f, hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != count {
+		t.Errorf("%s: %d vectors run, not %d", path, n, count)
+	}
+}
+
+func TestKnownAnswerSha(t *testing.T) {
+	hashes := map[string]func() hash.Hash{"SHA-256": sha256.New, "SHA-512": sha512.New}
+	runVectorsFile(t, "testdata/hash_drbg_sha.rsp", hashes, 4)
+}
+
+func TestKnownAnswerStreebog(t *testing.T) {
+	runVectorsFile(t, "testdata/hash_drbg_streebog.rsp", KnownHashes, 8)
+}
+
+func TestKnownAnswerMismatch(t *testing.T) {
+	vectors, err := ParseTestVectors(strings.NewReader(selfTestVectors))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 {
+		t.Fatalf("self test vectors count is %d, not 2", len(vectors))
+	}
+	tv := vectors[0]
+	tv.ReturnedBits[0] ^= 0x01
+	if err := tv.Run(KnownHashes[tv.Hash]); err == nil {
+		t.Error("corrupted vector passed")
+	}
+
+	if _, err := RunTestVectors(strings.NewReader("[Unknown]\nCOUNT = 0\n"), KnownHashes); err == nil {
+		t.Error("unknown hash accepted")
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatal(err)
+	}
+}
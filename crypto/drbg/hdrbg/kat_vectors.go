@@ -0,0 +1,30 @@
+package hdrbg
+
+// Встроенные векторы самопроверки Hash_DRBG (см. testdata/hash_drbg_streebog.rsp).
+const selfTestVectors = `
+[Streebog-256]
+[PredictionResistance = False]
+
+COUNT = 1
+EntropyInput = 11181f262d343b424950575e656c737a81888f969da4abb2b9c0c7ced5dce3ea
+Nonce = 232a31383f464d545b626970777e858c
+PersonalizationString = 555c636a71787f868d949ba2a9b0b7bec5ccd3dae1e8eff6fd040b121920272e
+EntropyInputReseed = 31383f464d545b626970777e858c939aa1a8afb6bdc4cbd2d9e0e7eef5fc030a
+AdditionalInputReseed = 777e858c939aa1a8afb6bdc4cbd2d9e0e7eef5fc030a11181f262d343b424950
+AdditionalInput = 99a0a7aeb5bcc3cad1d8dfe6edf4fb020910171e252c333a41484f565d646b72
+AdditionalInput = abb2b9c0c7ced5dce3eaf1f8ff060d141b222930373e454c535a61686f767d84
+ReturnedBits = b55d00d86dca941d678d11d135c9042ade44cd6989780a8f35b2870175e615b4fd3bbee133b09e66af03d42e7d0f0ed23ddc7bdf0a2362b1da2b824c833a523abd1c95607cb619cbaad78cc428d65610d5df2bcd10c5fbcdd15c56df30ed7eb2beec1167475d3362740afb572c0b5bfe179532da2a6a378a65f73e5cd618f8f1
+
+[Streebog-512]
+[PredictionResistance = False]
+
+COUNT = 1
+EntropyInput = 11181f262d343b424950575e656c737a81888f969da4abb2b9c0c7ced5dce3ea
+Nonce = 232a31383f464d545b626970777e858c
+PersonalizationString = 555c636a71787f868d949ba2a9b0b7bec5ccd3dae1e8eff6fd040b121920272e
+EntropyInputReseed = 31383f464d545b626970777e858c939aa1a8afb6bdc4cbd2d9e0e7eef5fc030a
+AdditionalInputReseed = 777e858c939aa1a8afb6bdc4cbd2d9e0e7eef5fc030a11181f262d343b424950
+AdditionalInput = 99a0a7aeb5bcc3cad1d8dfe6edf4fb020910171e252c333a41484f565d646b72
+AdditionalInput = abb2b9c0c7ced5dce3eaf1f8ff060d141b222930373e454c535a61686f767d84
+ReturnedBits = 507c4047462eb2f4ee0b584c16e55d1ebdb5a4bb453184b8d5a382be6a71a3019117ffe7e9c6ce92f25fb1873024b245427cfd9358703286c2a7bc53586828c703bb3a91f2ecb93137caac520acc939c211eaa714e9a86b3f3fb6faef4befec5188f898767614f18ec3bd5e4cfc3bd41f9ab2c35b8a39c206b7db1d7a04c1749dd5e7f7bd1451d7fe811a9e84676aaef6cc609cfb9c5fd2683d3afe07bcafb35fc5e04721c42ff127f89cc59873247ad7d05437ecf06dac34cdd358c083e8dbfba65bff2d45254b0418e86fa9336b6178e3e3c3093e569d624710402b1e960b85ff8361015138c165f2bad9c76bf5339f366104b2fe045f2fcbd3f01e6b82a25
+`
@@ -0,0 +1,124 @@
+package hdrbg
+
+import (
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"hash"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Функция создания генератора для сегмента пула по строке персонализации.
+type NewPoolPrng func(personalization []byte) (*DrbgPrng, error)
+
+type poolShard struct {
+	mtx  sync.Mutex
+	id   int
+	prng *DrbgPrng
+	pid  int
+}
+
+// Потокобезопасный пул генераторов, реализующий io.Reader.
+// Запросы распределяются по сегментам, каждый со своим экземпляром
+// DrbgPrng под отдельной блокировкой. Экземпляры создаются при первом
+// обращении к сегменту и пересоздаются при смене идентификатора процесса
+// (защита от повторения выхода после fork). Перезапуск по счётчику
+// выполняет сам DrbgPrng.
+// Источник энтропии, используемый newPrng, должен допускать конкурентное
+// чтение.
+type DrbgPool struct {
+	newPrng NewPoolPrng
+	shards  []poolShard
+	next    atomic.Uint32
+	getpid  func() int
+}
+
+// Создание пула из shards сегментов (0 - по числу GOMAXPROCS).
+func NewDrbgPool(shards int, newPrng NewPoolPrng) (*DrbgPool, error) {
+	if newPrng == nil {
+		return nil, errors.New(PREFIX + "nil prng constructor")
+	}
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	pool := &DrbgPool{
+		newPrng: newPrng,
+		shards:  make([]poolShard, shards),
+		getpid:  os.Getpid,
+	}
+	for i := range pool.shards {
+		pool.shards[i].id = i
+	}
+	return pool, nil
+}
+
+// Пул генераторов Hash_DRBG над crypto/rand.
+func NewHashDrbgPool(shards int, newHash func() hash.Hash, securityLevel SecurityLevel) (*DrbgPool, error) {
+	return NewDrbgPool(shards, func(personalization []byte) (*DrbgPrng, error) {
+		return NewHashDrbgPrng(newHash, nil, 32, securityLevel, personalization)
+	})
+}
+
+// Захват свободного сегмента; если все заняты, ожидание очередного.
+func (p *DrbgPool) acquire() *poolShard {
+	start := int(p.next.Add(1)) % len(p.shards)
+	for i := 0; i < len(p.shards); i++ {
+		s := &p.shards[(start+i)%len(p.shards)]
+		if s.mtx.TryLock() {
+			return s
+		}
+	}
+	s := &p.shards[start]
+	s.mtx.Lock()
+	return s
+}
+
+func (p *DrbgPool) Read(b []byte) (int, error) {
+	s := p.acquire()
+	defer s.mtx.Unlock()
+
+	pid := p.getpid()
+	if s.prng == nil || s.pid != pid {
+		prng, err := p.newPrng(poolPersonalization(s, pid))
+		if err != nil {
+			return 0, err
+		}
+		s.prng = prng
+		s.pid = pid
+	}
+	return s.prng.Read(b)
+}
+
+// Персонализация, различающая сегменты и процессы:
+// "drbg-pool" || номер сегмента || pid || время.
+func poolPersonalization(s *poolShard, pid int) []byte {
+	p := []byte("drbg-pool")
+	p = binary.BigEndian.AppendUint64(p, uint64(s.id))
+	p = binary.BigEndian.AppendUint64(p, uint64(pid))
+	p = binary.BigEndian.AppendUint64(p, uint64(time.Now().UnixNano()))
+	return p
+}
+
+var (
+	defaultPoolOnce sync.Once
+	defaultPool     *DrbgPool
+)
+
+// Общий для процесса пул Hash_DRBG (Стрибог-256), который можно
+// использовать вместо crypto/rand.Reader, например в manage.BuildData.Prng.
+var Reader = readerFunc(func(b []byte) (int, error) {
+	defaultPoolOnce.Do(func() {
+		defaultPool, _ = NewHashDrbgPool(0, streebog.New256, SECURITY_LEVEL_ONE)
+	})
+	return defaultPool.Read(b)
+})
+
+type readerFunc func(b []byte) (int, error)
+
+func (f readerFunc) Read(b []byte) (int, error) {
+	return f(b)
+}
@@ -0,0 +1,141 @@
+package hdrbg
+
+import (
+	"bytes"
+	"errors"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func countingPool(t *testing.T, shards int, created *atomic.Int32) *DrbgPool {
+	pool, err := NewDrbgPool(shards, func(personalization []byte) (*DrbgPrng, error) {
+		created.Add(1)
+		return NewHashDrbgPrng(streebog.New256, nil, 32, SECURITY_LEVEL_TEST, personalization)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestDrbgPoolConcurrentRead(t *testing.T) {
+	var created atomic.Int32
+	pool := countingPool(t, 4, &created)
+
+	const workers = 16
+	const reads = 50
+	outs := make([][]byte, workers*reads)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < reads; i++ {
+				b := make([]byte, 32)
+				if _, err := pool.Read(b); err != nil {
+					t.Error(err)
+					return
+				}
+				outs[w*reads+i] = b
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, b := range outs {
+		if seen[string(b)] {
+			t.Fatalf("repeated output %x", b)
+		}
+		seen[string(b)] = true
+	}
+	if n := created.Load(); n < 1 || n > 4 {
+		t.Errorf("incorrect instances count %d", n)
+	}
+}
+
+func TestDrbgPoolLazyInstantiation(t *testing.T) {
+	var created atomic.Int32
+	pool := countingPool(t, 8, &created)
+	if created.Load() != 0 {
+		t.Fatal("instances created before first read")
+	}
+	b := make([]byte, 16)
+	if _, err := pool.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if created.Load() != 1 {
+		t.Errorf("incorrect instances count %d", created.Load())
+	}
+}
+
+func TestDrbgPoolPidChange(t *testing.T) {
+	var created atomic.Int32
+	pool := countingPool(t, 1, &created)
+	pid := 100
+	pool.getpid = func() int { return pid }
+
+	b := make([]byte, 32)
+	if _, err := pool.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	before := pool.shards[0].prng
+
+	// Повторное чтение в том же процессе использует тот же экземпляр
+	if _, err := pool.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if pool.shards[0].prng != before || created.Load() != 1 {
+		t.Fatal("instance recreated without pid change")
+	}
+
+	// Имитация fork: дочерний процесс не должен продолжать состояние родителя
+	pid = 101
+	parent := make([]byte, 32)
+	child := make([]byte, 32)
+	if _, err := before.Read(parent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Read(child); err != nil {
+		t.Fatal(err)
+	}
+	if pool.shards[0].prng == before || created.Load() != 2 {
+		t.Fatal("instance not recreated after pid change")
+	}
+	if bytes.Equal(parent, child) {
+		t.Error("child output equals parent output")
+	}
+}
+
+func TestDrbgPoolConstructorError(t *testing.T) {
+	fail := errors.New("fail")
+	pool, err := NewDrbgPool(2, func([]byte) (*DrbgPrng, error) {
+		return nil, fail
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Read(make([]byte, 8)); err != fail {
+		t.Errorf("incorrect error %v", err)
+	}
+	if _, err := NewDrbgPool(1, nil); err == nil {
+		t.Error("nil constructor accepted")
+	}
+}
+
+func TestDefaultReader(t *testing.T) {
+	a := make([]byte, 32)
+	b := make([]byte, 32)
+	if _, err := Reader.Read(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Reader.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) {
+		t.Error("repeated output")
+	}
+}
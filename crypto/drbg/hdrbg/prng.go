@@ -6,12 +6,18 @@ import (
 	"gost_magma_cbc/crypto/models"
 	"hash"
 	"io"
+	"math"
+	"time"
 )
 
 const DRBG_RESEED_COUNTER_INTERVAL_LEVEL_TEST uint64 = 8
 const DRBG_RESEED_COUNTER_INTERVAL_LEVEL2 uint64 = 1 << 10
 const DRBG_RESEED_COUNTER_INTERVAL_LEVEL1 uint64 = 1 << 20
 
+const DRBG_RESEED_TIME_INTERVAL_LEVEL_TEST = time.Minute
+const DRBG_RESEED_TIME_INTERVAL_LEVEL2 = 10 * time.Minute
+const DRBG_RESEED_TIME_INTERVAL_LEVEL1 = time.Hour
+
 const MAX_BYTES = 1 << 45
 const MAX_BYTES_PER_GENERATE = 1 << 16
 
@@ -27,41 +33,100 @@ const (
 	SECURITY_LEVEL_TEST SecurityLevel = 0x99
 )
 
+// Функция создания генератора случайных бит по энтропии, метке и
+// строке персонализации.
+type NewDRBG func(entropy, nonce, personalization []byte) (models.DRBG, error)
+
+// Источник энтропии с оценкой минимальной энтропии (бит на байт выхода).
+type EntropySource interface {
+	io.Reader
+	MinEntropy() float64
+}
+
 type DrbgPrng struct {
 	entropySource    io.Reader
 	securityStrength int
+	entropyLen       int
+	nonceLen         int
 	drbg             models.DRBG
+	// Ошибка, переводящая генератор в состояние отказа.
+	err error
 }
 
 func NewHashDrbgPrng(newHash func() hash.Hash, entropySource io.Reader, securityStrength int, securityLevel SecurityLevel, personalization []byte) (*DrbgPrng, error) {
+	// Самопроверка при включении (выполняется однократно, далее - сохранённый результат)
+	if err := SelfTest(); err != nil {
+		return nil, err
+	}
+
+	newDrbg := func(entropy, nonce, personalization []byte) (models.DRBG, error) {
+		return NewHashDrbg(newHash, securityLevel, entropy, nonce, personalization)
+	}
+	return NewDrbgPrng(newDrbg, entropySource, securityStrength, 0, personalization)
+}
+
+// Создание генератора псевдослучайных данных над любым models.DRBG.
+// entropyLen задаёт длину энтропии для инициализации и перезапуска
+// (0 - по уровню безопасности). Источник энтропии оборачивается
+// непрерывными тестами с порогами HealthTestConfigFor по заявленной
+// минимальной энтропии, если он ещё не является HealthTestedSource.
+//
+// Если источник реализует EntropySource, длины энтропии и метки выбираются
+// так, чтобы они содержали не менее securityStrength*8 и securityStrength*4
+// бит минимальной энтропии соответственно. Явно заданная entropyLen
+// допускается только для источника полной энтропии (8 бит на байт).
+func NewDrbgPrng(newDrbg NewDRBG, entropySource io.Reader, securityStrength int, entropyLen int, personalization []byte) (*DrbgPrng, error) {
 	prng := &DrbgPrng{}
 
-	if entropySource != nil {
-		prng.entropySource = entropySource
+	if entropySource == nil {
+		entropySource = rand.Reader
+	}
+	minEntropy := 8.0
+	if es, ok := entropySource.(EntropySource); ok {
+		minEntropy = es.MinEntropy()
+		if minEntropy <= 0 || minEntropy > 8 {
+			return nil, errors.New(PREFIX + "invalid entropy source estimate")
+		}
+		if entropyLen > 0 && minEntropy < 8 {
+			return nil, errors.New(PREFIX + "entropy source does not provide full entropy")
+		}
+	}
+	if hs, ok := entropySource.(*HealthTestedSource); ok {
+		prng.entropySource = hs
 	} else {
-		prng.entropySource = rand.Reader
+		hs, err := NewHealthTestedSource(entropySource, HealthTestConfigFor(minEntropy))
+		if err != nil {
+			return nil, err
+		}
+		prng.entropySource = hs
 	}
 	prng.securityStrength = selectSecurityStrength(securityStrength) // в байтах
 	if securityStrength < 32 {
 		return nil, errors.New(PREFIX + "invalid security strength")
 	}
 
+	prng.entropyLen = entropyLen
+	if entropyLen <= 0 {
+		prng.entropyLen = entropyBytes(prng.securityStrength*8, minEntropy)
+	}
+	prng.nonceLen = entropyBytes(prng.securityStrength*4, minEntropy)
+
 	// Получение энтропии для инициализации данных
-	entropyInput := make([]byte, prng.securityStrength)
+	entropyInput := make([]byte, prng.entropyLen)
 	err := prng.getEntropy(entropyInput)
 	if err != nil {
 		return nil, err
 	}
 
 	// Получение метки (8.6.7)
-	nonce := make([]byte, prng.securityStrength/2)
+	nonce := make([]byte, prng.nonceLen)
 	err = prng.getEntropy(nonce)
 	if err != nil {
 		return nil, err
 	}
 
 	// Инициализация генератора случайных бит
-	prng.drbg, err = NewHashDrbg(newHash, securityLevel, entropyInput, nonce, personalization)
+	prng.drbg, err = newDrbg(entropyInput, nonce, personalization)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +136,10 @@ func NewHashDrbgPrng(newHash func() hash.Hash, entropySource io.Reader, security
 
 func (prng *DrbgPrng) getEntropy(entropyInput []byte) error {
 	n, err := prng.entropySource.Read(entropyInput)
+	if herr, ok := err.(*HealthTestError); ok {
+		prng.err = herr
+		return herr
+	}
 	if err != nil {
 		return err
 	}
@@ -81,6 +150,31 @@ func (prng *DrbgPrng) getEntropy(entropyInput []byte) error {
 }
 
 func (prng *DrbgPrng) Read(data []byte) (int, error) {
+	return prng.GenerateWithOptions(data, nil, false)
+}
+
+// Перезапуск генератора со свежей энтропией.
+func (prng *DrbgPrng) reseed(additional []byte) error {
+	entropyInput := make([]byte, prng.entropyLen)
+	err := prng.getEntropy(entropyInput)
+	if err != nil {
+		return err
+	}
+	return prng.drbg.Reseed(entropyInput, additional)
+}
+
+// Ошибка, переведшая генератор в состояние отказа (nil при нормальной работе).
+func (prng *DrbgPrng) Err() error {
+	return prng.err
+}
+
+// Заполняет data псевдослучайными данными с учётом дополнительных данных
+// additional. При predictionResistance перед каждым запросом к генератору
+// он перезапускается со свежей энтропией (SP 800-90A, 9.3.1).
+func (prng *DrbgPrng) GenerateWithOptions(data, additional []byte, predictionResistance bool) (int, error) {
+	if prng.err != nil {
+		return 0, prng.err
+	}
 	maxBytesPerRequest := prng.drbg.MaxBytesPerRequest()
 	total := 0
 
@@ -91,27 +185,63 @@ func (prng *DrbgPrng) Read(data []byte) (int, error) {
 			b = data[:maxBytesPerRequest]
 		}
 
-		err := prng.drbg.Generate(b, nil)
-		if err == ErrReseedRequired {
-			entropyInput := make([]byte, prng.securityStrength)
-			err := prng.getEntropy(entropyInput)
-			if err != nil {
-				return 0, err
+		add := additional
+		if predictionResistance {
+			// Дополнительные данные используются при перезапуске
+			if err := prng.reseed(additional); err != nil {
+				return total, err
 			}
-			err = prng.drbg.Reseed(entropyInput, nil)
+			add = nil
+		}
+
+		err := prng.drbg.Generate(b, add)
+		if err == ErrReseedRequired {
+			err = prng.reseed(additional)
 			if err != nil {
-				return 0, err
+				return total, err
 			}
-		} else if err != nil {
-			return 0, err
-		} else {
-			total += len(b)
-			data = data[len(b):]
+			// Дополнительные данные уже учтены при перезапуске
+			err = prng.drbg.Generate(b, nil)
 		}
+		if err != nil {
+			return total, err
+		}
+		total += len(b)
+		data = data[len(b):]
 	}
 	return total, nil
 }
 
+// Интервал перезапуска генератора (в числе запросов) для уровня безопасности.
+func ReseedInterval(securityLevel SecurityLevel) uint64 {
+	switch securityLevel {
+	case SECURITY_LEVEL_TWO:
+		return DRBG_RESEED_COUNTER_INTERVAL_LEVEL2
+	case SECURITY_LEVEL_TEST:
+		return DRBG_RESEED_COUNTER_INTERVAL_LEVEL_TEST
+	default:
+		return DRBG_RESEED_COUNTER_INTERVAL_LEVEL1
+	}
+}
+
+// Интервал перезапуска генератора по времени для уровня безопасности.
+func ReseedTimeInterval(securityLevel SecurityLevel) time.Duration {
+	switch securityLevel {
+	case SECURITY_LEVEL_TWO:
+		return DRBG_RESEED_TIME_INTERVAL_LEVEL2
+	case SECURITY_LEVEL_TEST:
+		return DRBG_RESEED_TIME_INTERVAL_LEVEL_TEST
+	default:
+		return DRBG_RESEED_TIME_INTERVAL_LEVEL1
+	}
+}
+
+// Число байт источника, содержащих не менее bits бит минимальной энтропии
+// при оценке minEntropy бит на байт.
+func entropyBytes(bits int, minEntropy float64) int {
+	return int(math.Ceil(float64(bits) / minEntropy))
+}
+
 // Возвращает наибольшую длину порождающих данных, которая необходима для
 // переданного уровня безопасности.
 func selectSecurityStrength(requested int) int {
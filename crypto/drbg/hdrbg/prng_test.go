@@ -1,6 +1,7 @@
 package hdrbg
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"gost_magma_cbc/crypto/hash/streebog"
 	"testing"
@@ -35,3 +36,85 @@ func Test_HashDrbgPrngWithStreebog(t *testing.T) {
 		t.Errorf("not got enough random bytes")
 	}
 }
+
+// Детерминированный источник энтропии, считающий обращения.
+type countingSource struct {
+	reads int
+	next  byte
+}
+
+func (s *countingSource) Read(b []byte) (int, error) {
+	s.reads++
+	for i := range b {
+		b[i] = s.next
+		s.next++
+	}
+	return len(b), nil
+}
+
+func Test_HashDrbgPrngEntropyRequests(t *testing.T) {
+	src := &countingSource{}
+	prng, err := NewHashDrbgPrng(streebog.New256, src, 32, SECURITY_LEVEL_TEST, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Стартовые тесты, энтропия и метка при инициализации
+	if src.reads != 3 {
+		t.Fatalf("entropy reads on instantiate is %d, not 3", src.reads)
+	}
+
+	data := make([]byte, 64)
+	for i := uint64(0); i < DRBG_RESEED_COUNTER_INTERVAL_LEVEL_TEST; i++ {
+		if _, err := prng.GenerateWithOptions(data, nil, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if src.reads != 3 {
+		t.Errorf("entropy pulled before reseed interval: %d reads", src.reads)
+	}
+
+	// Превышение интервала требует перезапуска
+	if _, err := prng.GenerateWithOptions(data, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if src.reads != 4 {
+		t.Errorf("entropy reads after reseed interval is %d, not 4", src.reads)
+	}
+
+	// Устойчивость к предсказанию: свежая энтропия на каждый запрос
+	big := make([]byte, 2*MAX_BYTES_PER_GENERATE+1)
+	if _, err := prng.GenerateWithOptions(big, []byte("additional"), true); err != nil {
+		t.Fatal(err)
+	}
+	if src.reads != 7 {
+		t.Errorf("entropy reads with prediction resistance is %d, not 7", src.reads)
+	}
+}
+
+func Test_HashDrbgPrngAdditional(t *testing.T) {
+	newPrng := func() *DrbgPrng {
+		prng, err := NewHashDrbgPrng(streebog.New256, &countingSource{}, 32, SECURITY_LEVEL_ONE, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return prng
+	}
+
+	out1 := make([]byte, 64)
+	out2 := make([]byte, 64)
+	newPrng().GenerateWithOptions(out1, []byte("one"), false)
+	newPrng().GenerateWithOptions(out2, []byte("one"), false)
+	if !bytes.Equal(out1, out2) {
+		t.Error("same additional input gives different output")
+	}
+
+	newPrng().GenerateWithOptions(out2, []byte("two"), false)
+	if bytes.Equal(out1, out2) {
+		t.Error("additional input is ignored")
+	}
+
+	newPrng().GenerateWithOptions(out2, []byte("one"), true)
+	if bytes.Equal(out1, out2) {
+		t.Error("prediction resistance does not reseed")
+	}
+}
@@ -0,0 +1,124 @@
+package hdrbg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+	"time"
+)
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Состояние после перезапуска по SP 800-90A, 10.1.1.3:
+// V = Hash_df(0x01 || V || entropy || additional), C = Hash_df(0x00 || V).
+func TestHashDrbgReseedState(t *testing.T) {
+	hd, err := NewHashDrbg(sha256.New, SECURITY_LEVEL_ONE, bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entropy := bytes.Repeat([]byte{3}, 32)
+	additional := []byte("additional")
+
+	material := append([]byte{0x01}, hd.v...)
+	material = append(material, entropy...)
+	material = append(material, additional...)
+	v := hd.hashDf(material, hd.seedLength)
+	c := hd.hashDf(append([]byte{0x00}, v...), hd.seedLength)
+
+	if err := hd.Reseed(entropy, additional); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hd.v, v) {
+		t.Errorf("incorrect V after reseed %x", hd.v)
+	}
+	if !bytes.Equal(hd.c, c) {
+		t.Errorf("incorrect C after reseed %x", hd.c)
+	}
+	if hd.reseedCounter != 1 {
+		t.Errorf("incorrect reseed counter %d", hd.reseedCounter)
+	}
+}
+
+func TestHashDrbgTimeReseed(t *testing.T) {
+	hd, err := NewHashDrbg(sha256.New, SECURITY_LEVEL_TWO, bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := hd.reseedTime
+	hd.now = func() time.Time { return now }
+
+	out := make([]byte, 32)
+	if err := hd.Generate(out, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(DRBG_RESEED_TIME_INTERVAL_LEVEL2)
+	if !hd.NeedReseed() {
+		t.Fatal("reseed is not required after time interval")
+	}
+	if err := hd.Generate(out, nil); err != ErrReseedRequired {
+		t.Fatalf("incorrect error %v", err)
+	}
+
+	if err := hd.Reseed(bytes.Repeat([]byte{3}, 32), nil); err != nil {
+		t.Fatal(err)
+	}
+	if hd.NeedReseed() {
+		t.Fatal("reseed is required right after reseed")
+	}
+	if err := hd.Generate(out, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Без ограничения по времени остаётся только счётчик
+	hd.SetReseedTimeInterval(0)
+	now = now.Add(24 * time.Hour)
+	if hd.NeedReseed() {
+		t.Error("reseed is required with disabled time interval")
+	}
+}
+
+// Источник, отдающий сначала данные для стартовых тестов, затем заданную
+// энтропию.
+func vectorSource(entropy ...[]byte) io.Reader {
+	startup := make([]byte, STARTUP_SAMPLES_COUNT)
+	for i := range startup {
+		startup[i] = byte(i)
+	}
+	return bytes.NewReader(bytes.Join(append([][]byte{startup}, entropy...), nil))
+}
+
+// Перезапуск по времени через DrbgPrng воспроизводит вектор CAVP
+// (SHA-256, COUNT 0): инициализация, перезапуск, два запроса.
+func TestHashDrbgPrngTimeReseedVector(t *testing.T) {
+	entropy := fromHex("63363377e41e86468deb0ab4a8ed683f6a134e47e014c700454e81e95358a569")
+	nonce := fromHex("808aa38f2a72a62359915a9f8a04ca68")
+	entropyReseed := fromHex("e62b8a8ee8f141b6980566e3bfe3c04903dad4ac2cdf9f2280010a6739bc83d3")
+	expected := fromHex("04eec63bb231df2c630a1afbe724949d005a587851e1aa795e477347c8b056621c18bddcdd8d99fc5fc2b92053d8cfacfb0bb8831205fad1ddd6c071318a6018f03b73f5ede4d4d071f9de03fd7aea105d9299b8af99aa075bdb4db9aa28c18d174b56ee2a014d098896ff2282c955a81969e069fa8ce007a180183a07dfae17")
+
+	prng, err := NewHashDrbgPrng(sha256.New, vectorSource(entropy, nonce, entropyReseed), 32, SECURITY_LEVEL_ONE, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hd := prng.drbg.(*HashDrbg)
+	now := hd.reseedTime.Add(DRBG_RESEED_TIME_INTERVAL_LEVEL1)
+	hd.now = func() time.Time { return now }
+
+	out := make([]byte, len(expected))
+	for i := 0; i < 2; i++ {
+		if _, err := prng.Read(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("incorrect output after time reseed %x", out)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build drbgdebug
+
+package hdrbg
+
+import "fmt"
+
+// Состояние Hash_DRBG для проверки по промежуточным значениям.
+// Доступно только при сборке с тегом drbgdebug.
+type HashDrbgState struct {
+	V             []byte
+	C             []byte
+	ReseedCounter uint64
+}
+
+func (hd *HashDrbg) DumpState() HashDrbgState {
+	return HashDrbgState{
+		V:             append([]byte(nil), hd.v...),
+		C:             append([]byte(nil), hd.c...),
+		ReseedCounter: hd.reseedCounter,
+	}
+}
+
+func (s HashDrbgState) String() string {
+	return fmt.Sprintf("V = %x\nC = %x\nreseed_counter = %d", s.V, s.C, s.ReseedCounter)
+}
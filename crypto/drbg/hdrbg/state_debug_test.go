@@ -0,0 +1,32 @@
+//go:build drbgdebug
+
+package hdrbg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHashDrbgDumpState(t *testing.T) {
+	test := tests[0]
+	entropyInput, _ := hex.DecodeString(test.entropyInput)
+	nonce, _ := hex.DecodeString(test.nonce)
+	v0, _ := hex.DecodeString(test.v0)
+	c0, _ := hex.DecodeString(test.c0)
+
+	hd, err := NewHashDrbg(test.newHash, SECURITY_LEVEL_ONE, entropyInput, nonce, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := hd.DumpState()
+	if !bytes.Equal(state.V, v0) || !bytes.Equal(state.C, c0) || state.ReseedCounter != 1 {
+		t.Errorf("unexpected state:\n%s", state)
+	}
+
+	// Изменение копии не влияет на генератор
+	state.V[0] ^= 0xff
+	if !bytes.Equal(hd.DumpState().V, v0) {
+		t.Error("state dump shares memory with drbg")
+	}
+}
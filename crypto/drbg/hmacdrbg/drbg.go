@@ -0,0 +1,145 @@
+package hmacdrbg
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/hash/hmac"
+	"gost_magma_cbc/crypto/models"
+	"hash"
+	"io"
+)
+
+const PREFIX = "crypto:drbg:hmacdrbg: "
+
+// HMAC_DRBG (NIST SP 800-90A, 10.1.2) над потоковым HMAC.
+type HmacDrbg struct {
+	k                       []byte
+	v                       []byte
+	reseedCounter           uint64
+	reseedIntervalInCounter uint64
+	newHash                 func() hash.Hash
+}
+
+func NewHmacDrbg(newHash func() hash.Hash, securityLevel hdrbg.SecurityLevel, entropy, nonce, personalization []byte) (*HmacDrbg, error) {
+	if len(entropy) == 0 || len(entropy) >= hdrbg.MAX_BYTES {
+		return nil, errors.New(PREFIX + "invalid entropy length")
+	}
+
+	if len(nonce) == 0 || len(nonce) >= hdrbg.MAX_BYTES>>1 {
+		return nil, errors.New(PREFIX + "invalid nonce length")
+	}
+
+	if len(personalization) >= hdrbg.MAX_BYTES {
+		return nil, errors.New(PREFIX + "personalization is too long")
+	}
+
+	hd := &HmacDrbg{newHash: newHash}
+	hd.reseedIntervalInCounter = hdrbg.ReseedInterval(securityLevel)
+
+	size := newHash().Size()
+	// Key = 0x00 00...00, V = 0x01 01...01
+	hd.k = make([]byte, size)
+	hd.v = make([]byte, size)
+	for i := range hd.v {
+		hd.v[i] = 0x01
+	}
+
+	// seed_material = entropy_input || nonce || personalization_string
+	hd.update(entropy, nonce, personalization)
+	hd.reseedCounter = 1
+
+	return hd, nil
+}
+
+// Создание генератора псевдослучайных данных на основе HMAC_DRBG.
+func NewHmacDrbgPrng(newHash func() hash.Hash, entropySource io.Reader, securityStrength int, securityLevel hdrbg.SecurityLevel, personalization []byte) (*hdrbg.DrbgPrng, error) {
+	// Самопроверка при включении (выполняется однократно, далее - сохранённый результат)
+	if err := SelfTest(); err != nil {
+		return nil, err
+	}
+
+	newDrbg := func(entropy, nonce, personalization []byte) (models.DRBG, error) {
+		return NewHmacDrbg(newHash, securityLevel, entropy, nonce, personalization)
+	}
+	return hdrbg.NewDrbgPrng(newDrbg, entropySource, securityStrength, 0, personalization)
+}
+
+// HMAC_DRBG_Update для provided_data = data[0] || data[1] || ...
+func (hd *HmacDrbg) update(data ...[]byte) {
+	empty := true
+	for _, d := range data {
+		if len(d) > 0 {
+			empty = false
+		}
+	}
+
+	for _, sep := range []byte{0x00, 0x01} {
+		// K = HMAC(K, V || sep || provided_data)
+		mac := hmac.New(hd.newHash, hd.k)
+		mac.Write(hd.v)
+		mac.Write([]byte{sep})
+		for _, d := range data {
+			mac.Write(d)
+		}
+		hd.k = mac.Sum(hd.k[:0])
+
+		// V = HMAC(K, V)
+		mac = hmac.New(hd.newHash, hd.k)
+		mac.Write(hd.v)
+		hd.v = mac.Sum(hd.v[:0])
+
+		if empty {
+			return
+		}
+	}
+}
+
+func (hd *HmacDrbg) NeedReseed() bool {
+	return hd.reseedCounter > hd.reseedIntervalInCounter
+}
+
+func (hd *HmacDrbg) MaxBytesPerRequest() int {
+	return hdrbg.MAX_BYTES_PER_GENERATE
+}
+
+func (hd *HmacDrbg) Reseed(entropy, additional []byte) error {
+	if len(entropy) == 0 || len(entropy) >= hdrbg.MAX_BYTES {
+		return errors.New(PREFIX + "invalid entropy length")
+	}
+
+	if len(additional) >= hdrbg.MAX_BYTES {
+		return errors.New(PREFIX + "additional input too long")
+	}
+
+	// seed_material = entropy_input || additional_input
+	hd.update(entropy, additional)
+	hd.reseedCounter = 1
+	return nil
+}
+
+func (hd *HmacDrbg) Generate(b, additional []byte) error {
+	if hd.NeedReseed() {
+		return hdrbg.ErrReseedRequired
+	}
+	if len(b) > hdrbg.MAX_BYTES_PER_GENERATE {
+		return errors.New(PREFIX + "too many bytes requested")
+	}
+
+	if len(additional) > 0 {
+		hd.update(additional)
+	}
+
+	// V = HMAC(K, V), temp = temp || V
+	mac := hmac.New(hd.newHash, hd.k)
+	for out := b; len(out) > 0; {
+		mac.Reset()
+		mac.Write(hd.v)
+		hd.v = mac.Sum(hd.v[:0])
+		n := copy(out, hd.v)
+		out = out[n:]
+	}
+
+	hd.update(additional)
+	hd.reseedCounter++
+	return nil
+}
@@ -0,0 +1,80 @@
+package hmacdrbg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"testing"
+)
+
+// NIST CAVP HMAC_DRBG, SHA-256, без устойчивости к предсказанию.
+func TestHmacDRBG(t *testing.T) {
+	entropy, _ := hex.DecodeString("ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488")
+	nonce, _ := hex.DecodeString("659ba96c601dc69fc902940805ec0ca8")
+	want, _ := hex.DecodeString("e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89d54fbb978a15b5c443c9ec21036d2460b6f73ebad0dc2aba6e624abf07745bc107694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668961df86803482cb37ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8")
+
+	hd, err := NewHmacDrbg(sha256.New, hdrbg.SECURITY_LEVEL_ONE, entropy, nonce, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, len(want))
+	if err := hd.Generate(out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := hd.Generate(out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, want) {
+		t.Errorf("not expected return bits %x", out)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		if err := SelfTest(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHmacDRBGReseed(t *testing.T) {
+	entropy := bytes.Repeat([]byte{0x11}, 32)
+	nonce := bytes.Repeat([]byte{0x22}, 16)
+
+	hd, err := NewHmacDrbg(streebog.New256, hdrbg.SECURITY_LEVEL_TEST, entropy, nonce, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]byte, 16)
+	for i := uint64(0); i < hdrbg.DRBG_RESEED_COUNTER_INTERVAL_LEVEL_TEST; i++ {
+		if err := hd.Generate(out, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := hd.Generate(out, nil); err != hdrbg.ErrReseedRequired {
+		t.Fatalf("expected reseed required, got %v", err)
+	}
+	if err := hd.Reseed(entropy, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := hd.Generate(out, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_HmacDrbgPrngWithStreebog(t *testing.T) {
+	prng, err := NewHmacDrbgPrng(streebog.New512, nil, 32, hdrbg.SECURITY_LEVEL_TEST, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, hdrbg.MAX_BYTES_PER_GENERATE+1)
+	n, err := prng.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != hdrbg.MAX_BYTES_PER_GENERATE+1 {
+		t.Errorf("not got enough random bytes")
+	}
+}
@@ -0,0 +1,58 @@
+package hmacdrbg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"sync"
+)
+
+// NIST CAVP HMAC_DRBG, SHA-256, без устойчивости к предсказанию.
+// Выход - результат второго вызова Generate.
+const (
+	selfTestEntropy = "ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488"
+	selfTestNonce   = "659ba96c601dc69fc902940805ec0ca8"
+	selfTestOutput  = "e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89" +
+		"d54fbb978a15b5c443c9ec21036d2460b6f73ebad0dc2aba6e624abf07745bc1" +
+		"07694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668" +
+		"961df86803482cb37ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8"
+)
+
+var (
+	selfTestOnce sync.Once
+	selfTestErr  error
+)
+
+func selfTest() error {
+	entropy, _ := hex.DecodeString(selfTestEntropy)
+	nonce, _ := hex.DecodeString(selfTestNonce)
+	want, _ := hex.DecodeString(selfTestOutput)
+
+	hd, err := NewHmacDrbg(sha256.New, hdrbg.SECURITY_LEVEL_ONE, entropy, nonce, nil)
+	if err != nil {
+		return err
+	}
+	got := make([]byte, len(want))
+	for i := 0; i < 2; i++ {
+		if err := hd.Generate(got, nil); err != nil {
+			return err
+		}
+	}
+	if !bytes.Equal(got, want) {
+		return errors.New("known answer mismatch")
+	}
+	return nil
+}
+
+// Самопроверка при включении: однократная проверка HMAC_DRBG по известному
+// ответу, результат запоминается.
+func SelfTest() error {
+	selfTestOnce.Do(func() {
+		if err := selfTest(); err != nil {
+			selfTestErr = errors.New(PREFIX + "self test failed: " + err.Error())
+		}
+	})
+	return selfTestErr
+}
@@ -0,0 +1,234 @@
+// Файл начального заполнения генератора: при завершении работы генератор
+// сохраняет SEED_SIZE байт выхода, зашифрованных Магмой в режиме CBC и
+// защищённых HMAC-Стрибог-256, а при запуске содержимое файла однократно
+// подмешивается к свежей энтропии через строку персонализации.
+package seedfile
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"errors"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"gost_magma_cbc/crypto/kdf"
+	"gost_magma_cbc/crypto/mode"
+	"gost_magma_cbc/crypto/models"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	ghmac "gost_magma_cbc/crypto/hash/hmac"
+)
+
+const PREFIX = "crypto:drbg:seedfile: "
+
+// Длина сохраняемого заполнения.
+const SEED_SIZE = 64
+
+// Длина главного ключа файла.
+const KEY_SIZE = 32
+
+const ivSize = 8
+
+// Формат файла: MAGIC || IV || E(seed) || HMAC(MAGIC || IV || E(seed)).
+const MAGIC = "GSTSEED1"
+
+const FILE_SIZE = len(MAGIC) + ivSize + SEED_SIZE + streebog.Size256
+
+var (
+	ErrNoSeed   = errors.New(PREFIX + "seed file does not exist")
+	ErrTampered = errors.New(PREFIX + "seed file is corrupted or tampered")
+)
+
+type SeedFile struct {
+	mtx  sync.Mutex
+	path string
+	key  []byte
+}
+
+// Создание файла заполнения по пути path с главным ключом key
+// (KEY_SIZE байт), из которого выводятся ключи шифрования и имитовставки.
+func NewSeedFile(path string, key []byte) (*SeedFile, error) {
+	if len(key) != KEY_SIZE {
+		return nil, errors.New(PREFIX + "invalid key length")
+	}
+	k := make([]byte, KEY_SIZE)
+	copy(k, key)
+	return &SeedFile{path: path, key: k}, nil
+}
+
+// Ключи шифрования и имитовставки для вектора инициализации iv.
+func (sf *SeedFile) keys(iv []byte) ([]byte, []byte, error) {
+	kdf256 := kdf.NewKDF256()
+	encKey, err := kdf256.Create(sf.key, []byte("drbg-seed-enc"), iv)
+	if err != nil {
+		return nil, nil, err
+	}
+	macKey, err := kdf256.Create(sf.key, []byte("drbg-seed-mac"), iv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encKey, macKey, nil
+}
+
+func mac(key, data []byte) []byte {
+	h := ghmac.New(streebog.New256, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// Шифрование или расшифрование data на месте Магмой в режиме CBC.
+func cbc(keyData, iv, data []byte, encrypt bool) error {
+	base := magma.NewMagma()
+	key := base.NewKey()
+	defer key.Clear()
+	for i, b := range keyData {
+		key.Set(i, b)
+	}
+	m, err := mode.NewCBCMode(iv, base.BlockLen())
+	if err != nil {
+		return err
+	}
+	block := base.NewBlock()
+	defer block.Clear()
+	for i := 0; i < len(data); i += base.BlockLen() {
+		copy(block.Data(), data[i:i+base.BlockLen()])
+		if encrypt {
+			m.Encrypt(base, key, block, block)
+		} else {
+			m.Decrypt(base, key, block, block)
+		}
+		copy(data[i:], block.Data())
+	}
+	return nil
+}
+
+// Сохранение SEED_SIZE байт из prng с атомарной заменой файла.
+func (sf *SeedFile) Save(prng models.DRBGPrng) error {
+	sf.mtx.Lock()
+	defer sf.mtx.Unlock()
+
+	data := make([]byte, FILE_SIZE)
+	defer clear(data)
+	copy(data, MAGIC)
+	iv := data[len(MAGIC) : len(MAGIC)+ivSize]
+	seed := data[len(MAGIC)+ivSize : len(MAGIC)+ivSize+SEED_SIZE]
+	if _, err := io.ReadFull(prng, data[len(MAGIC):len(MAGIC)+ivSize+SEED_SIZE]); err != nil {
+		return err
+	}
+
+	encKey, macKey, err := sf.keys(iv)
+	if err != nil {
+		return err
+	}
+	defer clear(encKey)
+	defer clear(macKey)
+	if err := cbc(encKey, iv, seed, true); err != nil {
+		return err
+	}
+	copy(data[len(MAGIC)+ivSize+SEED_SIZE:], mac(macKey, data[:len(MAGIC)+ivSize+SEED_SIZE]))
+
+	return writeAtomic(sf.path, data)
+}
+
+// Однократное получение заполнения: файл проверяется, расшифровывается и
+// удаляется до возврата результата. При отсутствии файла возвращается
+// ErrNoSeed, при нарушении целостности - ErrTampered (файл также удаляется).
+func (sf *SeedFile) Consume() ([]byte, error) {
+	sf.mtx.Lock()
+	defer sf.mtx.Unlock()
+
+	data, err := os.ReadFile(sf.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSeed
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer clear(data)
+	if err := os.Remove(sf.path); err != nil {
+		return nil, err
+	}
+
+	if len(data) != FILE_SIZE || !bytes.Equal(data[:len(MAGIC)], []byte(MAGIC)) {
+		return nil, ErrTampered
+	}
+	iv := data[len(MAGIC) : len(MAGIC)+ivSize]
+	body := data[:len(MAGIC)+ivSize+SEED_SIZE]
+
+	encKey, macKey, err := sf.keys(iv)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(encKey)
+	defer clear(macKey)
+	if !hmac.Equal(mac(macKey, body), data[len(body):]) {
+		return nil, ErrTampered
+	}
+
+	seed := make([]byte, SEED_SIZE)
+	copy(seed, body[len(MAGIC)+ivSize:])
+	if err := cbc(encKey, iv, seed, false); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// Запись через временный файл в том же каталоге с последующим
+// переименованием, чтобы при сбое остался либо старый, либо новый файл.
+func writeAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// Создание генератора с подмешиванием заполнения из файла: заполнение
+// дописывается к строке персонализации, после чего файл сразу
+// перезаписывается выходом нового генератора, так что одно заполнение не
+// используется дважды. Отсутствие файла не является ошибкой.
+func Instantiate(sf *SeedFile, newPrng func(personalization []byte) (*hdrbg.DrbgPrng, error), personalization []byte) (*hdrbg.DrbgPrng, error) {
+	seed, err := sf.Consume()
+	if err != nil && err != ErrNoSeed {
+		return nil, err
+	}
+	pers := append(append([]byte{}, personalization...), seed...)
+	clear(seed)
+
+	prng, err := newPrng(pers)
+	clear(pers)
+	if err != nil {
+		return nil, err
+	}
+	if err := sf.Save(prng); err != nil {
+		return nil, err
+	}
+	return prng, nil
+}
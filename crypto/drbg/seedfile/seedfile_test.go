@@ -0,0 +1,187 @@
+package seedfile
+
+import (
+	"bytes"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, KEY_SIZE)
+
+// Генератор с фиксированным выходом.
+type constPrng struct {
+	b byte
+}
+
+func (p *constPrng) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = p.b + byte(i)
+	}
+	return len(b), nil
+}
+
+func newTestSeedFile(t *testing.T) *SeedFile {
+	sf, err := NewSeedFile(filepath.Join(t.TempDir(), "drbg.seed"), testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sf
+}
+
+func TestSaveConsume(t *testing.T) {
+	sf := newTestSeedFile(t)
+	if _, err := sf.Consume(); err != ErrNoSeed {
+		t.Fatalf("incorrect error %v", err)
+	}
+	if err := sf.Save(&constPrng{}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(sf.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != FILE_SIZE {
+		t.Fatalf("incorrect file size %d", len(data))
+	}
+	var want [SEED_SIZE]byte
+	for i := range want {
+		want[i] = byte(ivSize + i)
+	}
+	if bytes.Contains(data, want[:16]) {
+		t.Error("seed is stored in plaintext")
+	}
+
+	seed, err := sf.Consume()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(seed, want[:]) {
+		t.Errorf("incorrect seed %x", seed)
+	}
+	// Повторное использование невозможно
+	if _, err := sf.Consume(); err != ErrNoSeed {
+		t.Errorf("seed consumed twice: %v", err)
+	}
+}
+
+func TestTamper(t *testing.T) {
+	sf := newTestSeedFile(t)
+	if err := sf.Save(&constPrng{b: 7}); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := os.ReadFile(sf.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < FILE_SIZE; i++ {
+		data := bytes.Clone(orig)
+		data[i] ^= 0x01
+		if err := os.WriteFile(sf.path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sf.Consume(); err != ErrTampered {
+			t.Fatalf("[%d] tampering not detected: %v", i, err)
+		}
+		if _, err := os.Stat(sf.path); !os.IsNotExist(err) {
+			t.Fatalf("[%d] tampered file is not removed", i)
+		}
+	}
+
+	for _, data := range [][]byte{orig[:FILE_SIZE-1], append(bytes.Clone(orig), 0)} {
+		os.WriteFile(sf.path, data, 0600)
+		if _, err := sf.Consume(); err != ErrTampered {
+			t.Errorf("incorrect length accepted: %v", err)
+		}
+	}
+
+	// Другой ключ
+	os.WriteFile(sf.path, orig, 0600)
+	other, _ := NewSeedFile(sf.path, bytes.Repeat([]byte{0x43}, KEY_SIZE))
+	if _, err := other.Consume(); err != ErrTampered {
+		t.Errorf("file accepted with wrong key: %v", err)
+	}
+
+	if _, err := NewSeedFile(sf.path, testKey[:16]); err == nil {
+		t.Error("short key accepted")
+	}
+}
+
+func TestAtomicReplace(t *testing.T) {
+	sf := newTestSeedFile(t)
+	for i := 0; i < 3; i++ {
+		if err := sf.Save(&constPrng{b: byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(sf.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "drbg.seed" {
+		t.Errorf("unexpected files in directory: %v", entries)
+	}
+	seed, err := sf.Consume()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seed[0] != 2+ivSize {
+		t.Error("file is not replaced by last save")
+	}
+}
+
+// Источник с одинаковой энтропией при каждом запуске.
+type fixedSource struct {
+	n int
+}
+
+func (s *fixedSource) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = byte(s.n)
+		s.n++
+	}
+	return len(b), nil
+}
+
+func TestInstantiate(t *testing.T) {
+	sf := newTestSeedFile(t)
+	newPrng := func(personalization []byte) (*hdrbg.DrbgPrng, error) {
+		return hdrbg.NewHashDrbgPrng(streebog.New256, &fixedSource{}, 32, hdrbg.SECURITY_LEVEL_ONE, personalization)
+	}
+
+	var outs [][]byte
+	var files [][]byte
+	for i := 0; i < 3; i++ {
+		prng, err := Instantiate(sf, newPrng, []byte("lab3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]byte, 32)
+		if _, err := prng.Read(out); err != nil {
+			t.Fatal(err)
+		}
+		outs = append(outs, out)
+		data, err := os.ReadFile(sf.path)
+		if err != nil {
+			t.Fatal("seed file is not written after instantiation")
+		}
+		files = append(files, data)
+	}
+
+	// Энтропия одинакова, различие даёт только заполнение из файла
+	if bytes.Equal(outs[0], outs[1]) || bytes.Equal(outs[1], outs[2]) {
+		t.Error("seed file is not mixed into instantiation")
+	}
+	if bytes.Equal(files[0], files[1]) || bytes.Equal(files[1], files[2]) {
+		t.Error("seed file is not replaced")
+	}
+
+	os.WriteFile(sf.path, files[2][:10], 0600)
+	if _, err := Instantiate(sf, newPrng, nil); err != ErrTampered {
+		t.Errorf("tampered seed file accepted: %v", err)
+	}
+}
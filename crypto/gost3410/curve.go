@@ -0,0 +1,145 @@
+// Электронная подпись ГОСТ Р 34.10-2012 на эллиптических кривых.
+package gost3410
+
+import (
+	"errors"
+	"math/big"
+)
+
+const PREFIX = "crypto:gost3410: "
+
+var (
+	zero = big.NewInt(0)
+	one  = big.NewInt(1)
+	two  = big.NewInt(2)
+)
+
+// Эллиптическая кривая в форме Вейерштрасса y^2 = x^3 + ax + b (mod p)
+// с базовой точкой (X, Y) простого порядка Q и кофактором Co.
+type Curve struct {
+	Name string
+	P    *big.Int
+	Q    *big.Int
+	A    *big.Int
+	B    *big.Int
+	X    *big.Int
+	Y    *big.Int
+	Co   *big.Int
+	// Длина координаты и закрытого ключа в байтах (32 или 64)
+	PointSize int
+	// Форма Эдвардса (nil, если не задана)
+	Edwards *EdwardsParams
+}
+
+func hexInt(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic(PREFIX + "invalid constant " + s)
+	}
+	return v
+}
+
+// Создание кривой с проверкой параметров; pointSize вычисляется по p.
+func NewCurve(name string, p, q, a, b, x, y, co *big.Int) (*Curve, error) {
+	c := &Curve{Name: name, P: p, Q: q, A: a, B: b, X: x, Y: y, Co: co}
+	if co == nil {
+		c.Co = big.NewInt(1)
+	}
+	switch {
+	case p.BitLen() <= 256:
+		c.PointSize = 32
+	case p.BitLen() <= 512:
+		c.PointSize = 64
+	default:
+		return nil, errors.New(PREFIX + "unsupported field size")
+	}
+	if !c.IsOnCurve(x, y) {
+		return nil, errors.New(PREFIX + "base point is not on curve")
+	}
+	return c, nil
+}
+
+func mustCurve(name string, p, q, a, b, x, y, co string) *Curve {
+	c, err := NewCurve(name, hexInt(p), hexInt(q), hexInt(a), hexInt(b), hexInt(x), hexInt(y), hexInt(co))
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c *Curve) mod(v *big.Int) *big.Int {
+	return v.Mod(v, c.P)
+}
+
+// Проверка принадлежности точки кривой.
+func (c *Curve) IsOnCurve(x, y *big.Int) bool {
+	if x == nil || y == nil || x.Sign() < 0 || x.Cmp(c.P) >= 0 || y.Sign() < 0 || y.Cmp(c.P) >= 0 {
+		return false
+	}
+	left := c.mod(new(big.Int).Mul(y, y))
+	right := new(big.Int).Mul(x, x)
+	right.Add(right, c.A)
+	right.Mul(right, x)
+	right.Add(right, c.B)
+	return left.Cmp(c.mod(right)) == 0
+}
+
+// Сложение точек в аффинных координатах; nil обозначает точку на
+// бесконечности.
+func (c *Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	var lambda *big.Int
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) != 0 || y1.Sign() == 0 {
+			return nil, nil
+		}
+		// lambda = (3x^2 + a) / 2y
+		num := new(big.Int).Mul(x1, x1)
+		num.Mul(num, big.NewInt(3))
+		num.Add(num, c.A)
+		den := new(big.Int).Lsh(y1, 1)
+		lambda = num.Mul(num, den.ModInverse(c.mod(den), c.P))
+	} else {
+		// lambda = (y2 - y1) / (x2 - x1)
+		num := new(big.Int).Sub(y2, y1)
+		den := c.mod(new(big.Int).Sub(x2, x1))
+		lambda = num.Mul(num, den.ModInverse(den, c.P))
+	}
+	c.mod(lambda)
+
+	x3 := new(big.Int).Mul(lambda, lambda)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	c.mod(x3)
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, lambda)
+	y3.Sub(y3, y1)
+	c.mod(y3)
+	return x3, y3
+}
+
+// Умножение точки на скаляр k (лестница Монтгомери).
+func (c *Curve) ScalarMult(k, x, y *big.Int) (*big.Int, *big.Int) {
+	var r0x, r0y *big.Int
+	r1x, r1y := x, y
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		if k.Bit(i) == 0 {
+			r1x, r1y = c.Add(r0x, r0y, r1x, r1y)
+			r0x, r0y = c.Add(r0x, r0y, r0x, r0y)
+		} else {
+			r0x, r0y = c.Add(r0x, r0y, r1x, r1y)
+			r1x, r1y = c.Add(r1x, r1y, r1x, r1y)
+		}
+	}
+	return r0x, r0y
+}
+
+// Умножение базовой точки на скаляр k.
+func (c *Curve) ScalarBaseMult(k *big.Int) (*big.Int, *big.Int) {
+	return c.ScalarMult(k, c.X, c.Y)
+}
@@ -0,0 +1,302 @@
+package gost3410
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Параметры скрученной кривой Эдвардса e*u^2 + v^2 = 1 + d*u^2*v^2,
+// бирационально эквивалентной кривой в форме Вейерштрасса
+// (Р 1323565.1.024-2019): s = (e - d) / 4, t = (e + d) / 6,
+// x = s(1 + v)/(1 - v) + t, y = s(1 + v)/((1 - v)u).
+type EdwardsParams struct {
+	E *big.Int
+	D *big.Int
+	// Базовая точка в форме Эдвардса
+	U *big.Int
+	V *big.Int
+
+	s *big.Int
+	t *big.Int
+}
+
+// Задание формы Эдвардса кривой с проверкой соответствия параметрам
+// Вейерштрасса и базовой точке.
+func (c *Curve) SetEdwards(e, d, u, v *big.Int) error {
+	ed := &EdwardsParams{E: e, D: d, U: u, V: v}
+	inv4 := new(big.Int).ModInverse(big.NewInt(4), c.P)
+	inv6 := new(big.Int).ModInverse(big.NewInt(6), c.P)
+	ed.s = new(big.Int).Sub(e, d)
+	ed.s.Mul(ed.s, inv4)
+	c.mod(ed.s)
+	ed.t = new(big.Int).Add(e, d)
+	ed.t.Mul(ed.t, inv6)
+	c.mod(ed.t)
+
+	// a = s^2 - 3t^2, b = 2t^3 - ts^2
+	s2 := new(big.Int).Mul(ed.s, ed.s)
+	t2 := new(big.Int).Mul(ed.t, ed.t)
+	a := new(big.Int).Mul(t2, big.NewInt(3))
+	a.Sub(s2, a)
+	b := new(big.Int).Mul(t2, ed.t)
+	b.Lsh(b, 1)
+	b.Sub(b, new(big.Int).Mul(ed.t, s2))
+	if c.mod(a).Cmp(c.A) != 0 || c.mod(b).Cmp(c.B) != 0 {
+		return errors.New(PREFIX + "edwards parameters do not match curve")
+	}
+
+	saved := c.Edwards
+	c.Edwards = ed
+	if !c.isOnEdwards(u, v) {
+		c.Edwards = saved
+		return errors.New(PREFIX + "edwards base point is not on curve")
+	}
+	x, y, err := c.FromEdwards(u, v)
+	if err != nil || x.Cmp(c.X) != 0 || y.Cmp(c.Y) != 0 {
+		c.Edwards = saved
+		return errors.New(PREFIX + "edwards base point does not match curve")
+	}
+	return nil
+}
+
+func (c *Curve) mustEdwards(e, d, u, v string) *Curve {
+	if err := c.SetEdwards(hexInt(e), hexInt(d), hexInt(u), hexInt(v)); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Кривая имеет форму Эдвардса.
+func (c *Curve) IsEdwards() bool {
+	return c.Edwards != nil
+}
+
+func (c *Curve) isOnEdwards(u, v *big.Int) bool {
+	ed := c.Edwards
+	u2 := new(big.Int).Mul(u, u)
+	v2 := new(big.Int).Mul(v, v)
+	left := new(big.Int).Mul(ed.E, u2)
+	left.Add(left, v2)
+	right := new(big.Int).Mul(ed.D, u2)
+	right.Mul(right, v2)
+	right.Add(right, one)
+	return c.mod(left).Cmp(c.mod(right)) == 0
+}
+
+// Переход от формы Вейерштрасса к форме Эдвардса:
+// u = (x - t) / y, v = (x - t - s) / (x - t + s).
+func (c *Curve) ToEdwards(x, y *big.Int) (*big.Int, *big.Int, error) {
+	ed := c.Edwards
+	if ed == nil {
+		return nil, nil, errors.New(PREFIX + "curve has no edwards form")
+	}
+	xt := c.mod(new(big.Int).Sub(x, ed.t))
+	den := c.mod(new(big.Int).Add(xt, ed.s))
+	if y.Sign() == 0 || den.Sign() == 0 {
+		return nil, nil, errors.New(PREFIX + "exceptional point")
+	}
+	u := new(big.Int).ModInverse(y, c.P)
+	u.Mul(u, xt)
+	c.mod(u)
+	v := new(big.Int).Sub(xt, ed.s)
+	v.Mul(v, den.ModInverse(den, c.P))
+	c.mod(v)
+	return u, v, nil
+}
+
+// Переход от формы Эдвардса к форме Вейерштрасса. Нейтральной точке
+// (0, 1) соответствует точка на бесконечности (nil).
+func (c *Curve) FromEdwards(u, v *big.Int) (*big.Int, *big.Int, error) {
+	ed := c.Edwards
+	if ed == nil {
+		return nil, nil, errors.New(PREFIX + "curve has no edwards form")
+	}
+	if u.Sign() == 0 && v.Cmp(one) == 0 {
+		return nil, nil, nil
+	}
+	oneMinusV := c.mod(new(big.Int).Sub(one, v))
+	if u.Sign() == 0 || oneMinusV.Sign() == 0 {
+		return nil, nil, errors.New(PREFIX + "exceptional point")
+	}
+	// w = s(1 + v) / (1 - v)
+	w := new(big.Int).Add(one, v)
+	w.Mul(w, ed.s)
+	w.Mul(w, oneMinusV.ModInverse(oneMinusV, c.P))
+	c.mod(w)
+	x := new(big.Int).Add(w, ed.t)
+	c.mod(x)
+	y := new(big.Int).ModInverse(u, c.P)
+	y.Mul(y, w)
+	c.mod(y)
+	return x, y, nil
+}
+
+// Полная формула сложения на кривой Эдвардса (пригодна и для удвоения):
+// u3 = (u1v2 + v1u2) / (1 + d*u1u2v1v2), v3 = (v1v2 - e*u1u2) / (1 - d*u1u2v1v2).
+func (c *Curve) EdwardsAdd(u1, v1, u2, v2 *big.Int) (*big.Int, *big.Int) {
+	ed := c.Edwards
+	u1u2 := c.mod(new(big.Int).Mul(u1, u2))
+	v1v2 := c.mod(new(big.Int).Mul(v1, v2))
+	duv := new(big.Int).Mul(ed.D, u1u2)
+	duv.Mul(duv, v1v2)
+	c.mod(duv)
+
+	num := new(big.Int).Mul(u1, v2)
+	num.Add(num, new(big.Int).Mul(v1, u2))
+	den := new(big.Int).Add(one, duv)
+	u3 := num.Mul(num, den.ModInverse(c.mod(den), c.P))
+	c.mod(u3)
+
+	num = new(big.Int).Mul(ed.E, u1u2)
+	num.Sub(v1v2, num)
+	den = new(big.Int).Sub(one, duv)
+	v3 := num.Mul(num, den.ModInverse(c.mod(den), c.P))
+	c.mod(v3)
+	return u3, v3
+}
+
+// Точка в проективных координатах (X : Y : Z) над field, x = X/Z, y = Y/Z.
+type projPoint struct {
+	x, y, z []uint64
+}
+
+// Лестница Монтгомери на фиксированном числе слов с полными формулами
+// сложения Renes-Costello-Batina (2015, алгоритм 1) для кривой
+// y^2 = x^3 + ax + b: одна формула без исключений служит и для удвоения,
+// и для сложения с бесконечно удалённой точкой (0 : 1 : 0).
+type ladder struct {
+	f     *field
+	a, b3 []uint64
+	t     [9][]uint64
+}
+
+func newLadder(c *Curve) *ladder {
+	l := &ladder{f: newField(c.P)}
+	l.a = l.f.fromBig(new(big.Int).Mod(c.A, c.P))
+	b3 := new(big.Int).Mul(c.B, big.NewInt(3))
+	l.b3 = l.f.fromBig(b3.Mod(b3, c.P))
+	for i := range l.t {
+		l.t[i] = l.f.newElement()
+	}
+	return l
+}
+
+func (l *ladder) newPoint() *projPoint {
+	return &projPoint{x: l.f.newElement(), y: l.f.newElement(), z: l.f.newElement()}
+}
+
+// r = p + q; r может совпадать с p или q.
+func (l *ladder) add(r, p, q *projPoint) {
+	f := l.f
+	t0, t1, t2, t3, t4, t5 := l.t[0], l.t[1], l.t[2], l.t[3], l.t[4], l.t[5]
+	x3, y3, z3 := l.t[6], l.t[7], l.t[8]
+	f.mul(t0, p.x, q.x)
+	f.mul(t1, p.y, q.y)
+	f.mul(t2, p.z, q.z)
+	f.add(t3, p.x, p.y)
+	f.add(t4, q.x, q.y)
+	f.mul(t3, t3, t4)
+	f.add(t4, t0, t1)
+	f.sub(t3, t3, t4)
+	f.add(t4, p.x, p.z)
+	f.add(t5, q.x, q.z)
+	f.mul(t4, t4, t5)
+	f.add(t5, t0, t2)
+	f.sub(t4, t4, t5)
+	f.add(t5, p.y, p.z)
+	f.add(x3, q.y, q.z)
+	f.mul(t5, t5, x3)
+	f.add(x3, t1, t2)
+	f.sub(t5, t5, x3)
+	f.mul(z3, l.a, t4)
+	f.mul(x3, l.b3, t2)
+	f.add(z3, x3, z3)
+	f.sub(x3, t1, z3)
+	f.add(z3, t1, z3)
+	f.mul(y3, x3, z3)
+	f.add(t1, t0, t0)
+	f.add(t1, t1, t0)
+	f.mul(t2, l.a, t2)
+	f.mul(t4, l.b3, t4)
+	f.add(t1, t1, t2)
+	f.sub(t2, t0, t2)
+	f.mul(t2, l.a, t2)
+	f.add(t4, t4, t2)
+	f.mul(t0, t1, t4)
+	f.add(y3, y3, t0)
+	f.mul(t0, t5, t4)
+	f.mul(x3, t3, x3)
+	f.sub(x3, x3, t0)
+	f.mul(t0, t3, t1)
+	f.mul(z3, t5, z3)
+	f.add(z3, z3, t0)
+	copy(r.x, x3)
+	copy(r.y, y3)
+	copy(r.z, z3)
+}
+
+func (l *ladder) cswap(p, q *projPoint, bit uint64) {
+	cswapWords(p.x, q.x, bit)
+	cswapWords(p.y, q.y, bit)
+	cswapWords(p.z, q.z, bit)
+}
+
+func (l *ladder) clear(points ...*projPoint) {
+	for _, p := range points {
+		clear(p.x)
+		clear(p.y)
+		clear(p.z)
+	}
+	for _, t := range l.t {
+		clear(t)
+	}
+}
+
+// Умножение точки на секретный скаляр за постоянное время: лестница
+// Монтгомери из Q.BitLen() шагов по полным проективным формулам,
+// арифметика на фиксированном числе 64-битных слов, выбор точек обменом
+// по маске, одно обращение Z по малой теореме Ферма в конце. Время
+// зависит только от кривой. Скаляр приводится по модулю q средствами
+// math/big до начала лестницы, поэтому вызывающие передают k < q.
+// Для точки порядка 2 (y = 0), где полные формулы неприменимы на кривых
+// чётного порядка, используется ScalarMult: такая точка не бывает
+// допустимым открытым ключом.
+func (c *Curve) ScalarMultCT(k, x, y *big.Int) (*big.Int, *big.Int) {
+	if x == nil {
+		return nil, nil
+	}
+	if y.Sign() == 0 {
+		return c.ScalarMult(k, x, y)
+	}
+	kk := new(big.Int).Mod(k, c.Q)
+	n := c.Q.BitLen()
+	kw := wordsFromBig(kk, (n+63)/64)
+	kk.SetInt64(0)
+	defer clear(kw)
+
+	l := newLadder(c)
+	r0, r1 := l.newPoint(), l.newPoint()
+	copy(r0.y, l.f.one)
+	copy(r1.x, l.f.fromBig(new(big.Int).Mod(x, c.P)))
+	copy(r1.y, l.f.fromBig(new(big.Int).Mod(y, c.P)))
+	copy(r1.z, l.f.one)
+	defer l.clear(r0, r1)
+
+	for i := n - 1; i >= 0; i-- {
+		bit := (kw[i/64] >> (i % 64)) & 1
+		l.cswap(r0, r1, bit)
+		l.add(r1, r0, r1)
+		l.add(r0, r0, r0)
+		l.cswap(r0, r1, bit)
+	}
+
+	zi := l.f.newElement()
+	l.f.inv(zi, r0.z)
+	l.f.mul(r0.x, r0.x, zi)
+	l.f.mul(r0.y, r0.y, zi)
+	// k = 0 mod q: Z = 0 и результат - бесконечно удалённая точка
+	if l.f.toBig(r0.z).Sign() == 0 {
+		return nil, nil
+	}
+	return l.f.toBig(r0.x), l.f.toBig(r0.y)
+}
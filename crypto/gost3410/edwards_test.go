@@ -0,0 +1,184 @@
+package gost3410
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestEdwardsConversion(t *testing.T) {
+	for _, c := range []*Curve{CurveTC26_256A, CurveTC26_512C} {
+		if !c.IsEdwards() {
+			t.Fatalf("%s: no edwards form", c.Name)
+		}
+		u, v, err := c.ToEdwards(c.X, c.Y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Cmp(c.Edwards.U) != 0 || v.Cmp(c.Edwards.V) != 0 {
+			t.Errorf("%s: incorrect edwards base point", c.Name)
+		}
+
+		// Сложение в форме Эдвардса согласовано со сложением Вейерштрасса
+		px, py := c.ScalarBaseMult(big.NewInt(12345))
+		qx, qy := c.ScalarBaseMult(big.NewInt(67890))
+		pu, pv, _ := c.ToEdwards(px, py)
+		qu, qv, _ := c.ToEdwards(qx, qy)
+		su, sv := c.EdwardsAdd(pu, pv, qu, qv)
+		sx, sy, err := c.FromEdwards(su, sv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wx, wy := c.Add(px, py, qx, qy)
+		if sx.Cmp(wx) != 0 || sy.Cmp(wy) != 0 {
+			t.Errorf("%s: edwards addition mismatch", c.Name)
+		}
+		du, dv := c.EdwardsAdd(pu, pv, pu, pv)
+		dx, dy, _ := c.FromEdwards(du, dv)
+		wx, wy = c.Add(px, py, px, py)
+		if dx.Cmp(wx) != 0 || dy.Cmp(wy) != 0 {
+			t.Errorf("%s: edwards doubling mismatch", c.Name)
+		}
+
+		// Нейтральный элемент
+		nu, nv := c.EdwardsAdd(pu, pv, zero, one)
+		if nu.Cmp(pu) != 0 || nv.Cmp(pv) != 0 {
+			t.Errorf("%s: incorrect neutral element", c.Name)
+		}
+		if x, _, err := c.FromEdwards(zero, one); x != nil || err != nil {
+			t.Errorf("%s: neutral element is not infinity", c.Name)
+		}
+	}
+	if CurveTC26_256B.IsEdwards() {
+		t.Error("weierstrass curve has edwards form")
+	}
+	if _, _, err := CurveTC26_256B.ToEdwards(CurveTC26_256B.X, CurveTC26_256B.Y); err == nil {
+		t.Error("conversion without edwards form")
+	}
+}
+
+func TestSetEdwardsInvalid(t *testing.T) {
+	c, err := NewCurve("copy", CurveTC26_256A.P, CurveTC26_256A.Q, CurveTC26_256A.A, CurveTC26_256A.B,
+		CurveTC26_256A.X, CurveTC26_256A.Y, CurveTC26_256A.Co)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed := CurveTC26_256A.Edwards
+	if err := c.SetEdwards(ed.E, new(big.Int).Add(ed.D, one), ed.U, ed.V); err == nil {
+		t.Error("incorrect d accepted")
+	}
+	if err := c.SetEdwards(ed.E, ed.D, ed.U, new(big.Int).Add(ed.V, one)); err == nil {
+		t.Error("incorrect base point accepted")
+	}
+	if c.IsEdwards() {
+		t.Error("edwards form is set after error")
+	}
+}
+
+func TestScalarMultCT(t *testing.T) {
+	for _, c := range allCurves {
+		ks := []*big.Int{one, two, new(big.Int).Sub(c.Q, one), big.NewInt(0xdeadbeef)}
+		for i := 0; i < 4; i++ {
+			k, err := randScalar(c.Q, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ks = append(ks, k)
+		}
+		for _, k := range ks {
+			x1, y1 := c.ScalarMultCT(k, c.X, c.Y)
+			x2, y2 := c.ScalarBaseMult(k)
+			if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 {
+				t.Errorf("%s: mismatch for k = %x", c.Name, k)
+			}
+		}
+		if x, _ := c.ScalarMultCT(c.Q, c.X, c.Y); x != nil {
+			t.Errorf("%s: q * P is not infinity", c.Name)
+		}
+		if x, _ := c.ScalarMultCT(zero, c.X, c.Y); x != nil {
+			t.Errorf("%s: 0 * P is not infinity", c.Name)
+		}
+		// Точка, отличная от базовой
+		px, py := c.ScalarBaseMult(big.NewInt(0x1234567))
+		for _, k := range ks {
+			x1, y1 := c.ScalarMultCT(k, px, py)
+			x2, y2 := c.ScalarMult(k, px, py)
+			if x1.Cmp(x2) != 0 || y1.Cmp(y2) != 0 {
+				t.Errorf("%s: mismatch for point and k = %x", c.Name, k)
+			}
+		}
+	}
+}
+
+func TestField(t *testing.T) {
+	for _, c := range allCurves {
+		f := newField(c.P)
+		for i := 0; i < 16; i++ {
+			a, _ := randScalar(c.P, nil)
+			b, _ := randScalar(c.P, nil)
+			if i == 0 {
+				a.Sub(c.P, one)
+			}
+			x, y, z := f.fromBig(a), f.fromBig(b), f.newElement()
+			check := func(op string, want *big.Int) {
+				if f.toBig(z).Cmp(want.Mod(want, c.P)) != 0 {
+					t.Errorf("%s: %s mismatch for %x, %x", c.Name, op, a, b)
+				}
+			}
+			f.mul(z, x, y)
+			check("mul", new(big.Int).Mul(a, b))
+			f.add(z, x, y)
+			check("add", new(big.Int).Add(a, b))
+			f.sub(z, x, y)
+			check("sub", new(big.Int).Sub(a, b))
+			f.inv(z, x)
+			check("inv", new(big.Int).ModInverse(a, c.P))
+		}
+	}
+}
+
+func TestRawEncoding(t *testing.T) {
+	// Открытый ключ примера 1 ГОСТ Р 34.10-2012
+	prv, err := NewPrivateKey(CurveTest256, hexInt(examples[0].d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := prv.PublicKey.Raw()
+	if len(raw) != 64 || raw[0] != 0x0B || raw[31] != 0x7F || raw[32] != 0xDA || raw[63] != 0x26 {
+		t.Errorf("incorrect encoding %x", raw)
+	}
+
+	for _, c := range allCurves {
+		prv, err := GenerateKey(c, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := NewPublicKeyFromRaw(c, prv.PublicKey.Raw())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub.X.Cmp(prv.X) != 0 || pub.Y.Cmp(prv.Y) != 0 {
+			t.Errorf("%s: public key roundtrip fail", c.Name)
+		}
+		prv2, err := NewPrivateKeyFromRaw(c, prv.Raw())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prv2.D.Cmp(prv.D) != 0 {
+			t.Errorf("%s: private key roundtrip fail", c.Name)
+		}
+
+		bad := prv.PublicKey.Raw()
+		bad[0] ^= 1
+		if _, err := NewPublicKeyFromRaw(c, bad); err == nil {
+			t.Errorf("%s: modified public key accepted", c.Name)
+		}
+		if _, err := NewPublicKeyFromRaw(c, bad[1:]); err == nil {
+			t.Errorf("%s: short public key accepted", c.Name)
+		}
+	}
+
+	if !bytes.Equal(leBytes(big.NewInt(0x0102), 4), []byte{2, 1, 0, 0}) {
+		t.Error("incorrect little-endian encoding")
+	}
+}
@@ -0,0 +1,75 @@
+package gost3410
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Каноническое представление открытого ключа в структурах ГОСТ:
+// координаты X и Y в little-endian по PointSize байт, X || Y.
+// Закрытый ключ представляется PointSize байтами в little-endian.
+
+func reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+func leBytes(v *big.Int, size int) []byte {
+	b := v.FillBytes(make([]byte, size))
+	reverse(b)
+	return b
+}
+
+func leInt(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	copy(be, b)
+	reverse(be)
+	return new(big.Int).SetBytes(be)
+}
+
+// Запись координат точки в little-endian: X || Y.
+func (c *Curve) pointBytes(x, y *big.Int) []byte {
+	return append(leBytes(x, c.PointSize), leBytes(y, c.PointSize)...)
+}
+
+func (pub *PublicKey) Raw() []byte {
+	return pub.Curve.pointBytes(pub.X, pub.Y)
+}
+
+// Открытый ключ из представления X || Y с проверкой точки.
+func NewPublicKeyFromRaw(curve *Curve, raw []byte) (*PublicKey, error) {
+	if len(raw) != 2*curve.PointSize {
+		return nil, errors.New(PREFIX + "invalid public key length")
+	}
+	return NewPublicKey(curve, leInt(raw[:curve.PointSize]), leInt(raw[curve.PointSize:]))
+}
+
+func (prv *PrivateKey) Raw() []byte {
+	return leBytes(prv.D, prv.Curve.PointSize)
+}
+
+func NewPrivateKeyFromRaw(curve *Curve, raw []byte) (*PrivateKey, error) {
+	if len(raw) != curve.PointSize {
+		return nil, errors.New(PREFIX + "invalid private key length")
+	}
+	return NewPrivateKey(curve, leInt(raw))
+}
+
+// Точка в представлении X || Y для передачи в протоколах.
+func (c *Curve) Marshal(x, y *big.Int) []byte {
+	return c.pointBytes(x, y)
+}
+
+// Разбор точки X || Y с проверкой принадлежности кривой (без проверки
+// принадлежности подгруппе порядка q).
+func (c *Curve) Unmarshal(raw []byte) (*big.Int, *big.Int, error) {
+	if len(raw) != 2*c.PointSize {
+		return nil, nil, errors.New(PREFIX + "invalid point length")
+	}
+	x, y := leInt(raw[:c.PointSize]), leInt(raw[c.PointSize:])
+	if !c.IsOnCurve(x, y) {
+		return nil, nil, errors.New(PREFIX + "point is not on curve")
+	}
+	return x, y, nil
+}
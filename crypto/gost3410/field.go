@@ -0,0 +1,170 @@
+package gost3410
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// Наибольшее число 64-битных слов элемента поля (p < 2^512).
+const MAX_FIELD_WORDS = 8
+
+// Арифметика по модулю p на фиксированном числе 64-битных слов (младшее
+// слово первое) в форме Монтгомери, R = 2^(64n). Время выполнения
+// операций зависит только от n, но не от значений элементов.
+type field struct {
+	p   []uint64
+	n0  uint64   // -p^-1 mod 2^64
+	rr  []uint64 // R^2 mod p
+	one []uint64 // R mod p
+	pm2 *big.Int // p - 2
+}
+
+func newField(p *big.Int) *field {
+	n := (p.BitLen() + 63) / 64
+	f := &field{p: wordsFromBig(p, n), pm2: new(big.Int).Sub(p, two)}
+	// Обратный к p[0] по модулю 2^64 методом Ньютона
+	inv := uint64(1)
+	for i := 0; i < 6; i++ {
+		inv *= 2 - f.p[0]*inv
+	}
+	f.n0 = -inv
+	r := new(big.Int).Lsh(one, uint(64*n))
+	f.one = wordsFromBig(new(big.Int).Mod(r, p), n)
+	f.rr = wordsFromBig(r.Mod(r.Mul(r, r), p), n)
+	return f
+}
+
+func wordsFromBig(x *big.Int, n int) []uint64 {
+	b := x.FillBytes(make([]byte, 8*n))
+	w := make([]uint64, n)
+	for i := range w {
+		for _, v := range b[8*(n-1-i) : 8*(n-i)] {
+			w[i] = w[i]<<8 | uint64(v)
+		}
+	}
+	clear(b)
+	return w
+}
+
+func (f *field) newElement() []uint64 {
+	return make([]uint64, len(f.p))
+}
+
+// Перевод x < p в форму Монтгомери.
+func (f *field) fromBig(x *big.Int) []uint64 {
+	z := wordsFromBig(x, len(f.p))
+	f.mul(z, z, f.rr)
+	return z
+}
+
+// Перевод из формы Монтгомери.
+func (f *field) toBig(x []uint64) *big.Int {
+	z := f.newElement()
+	u := f.newElement()
+	u[0] = 1
+	f.mul(z, x, u)
+	b := make([]byte, 8*len(z))
+	for i, w := range z {
+		for j := 0; j < 8; j++ {
+			b[len(b)-1-8*i-j] = byte(w >> (8 * j))
+		}
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+// z = x * y * R^-1 mod p (CIOS). z может совпадать с x или y.
+func (f *field) mul(z, x, y []uint64) {
+	n := len(f.p)
+	var t [MAX_FIELD_WORDS + 2]uint64
+	for i := 0; i < n; i++ {
+		var c, cc uint64
+		for j := 0; j < n; j++ {
+			hi, lo := bits.Mul64(x[j], y[i])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j], c = lo, hi
+		}
+		t[n], cc = bits.Add64(t[n], c, 0)
+		t[n+1] = cc
+
+		m := t[0] * f.n0
+		hi, lo := bits.Mul64(m, f.p[0])
+		_, cc = bits.Add64(lo, t[0], 0)
+		c = hi + cc
+		for j := 1; j < n; j++ {
+			hi, lo = bits.Mul64(m, f.p[j])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j-1], c = lo, hi
+		}
+		t[n-1], cc = bits.Add64(t[n], c, 0)
+		t[n] = t[n+1] + cc
+	}
+	f.reduce(z, t[:n], t[n])
+}
+
+// z = (hi:t) mod p для (hi:t) < 2p.
+func (f *field) reduce(z, t []uint64, hi uint64) {
+	var s [MAX_FIELD_WORDS]uint64
+	var b uint64
+	for j := range t {
+		s[j], b = bits.Sub64(t[j], f.p[j], b)
+	}
+	_, b = bits.Sub64(hi, 0, b)
+	// b = 1, если (hi:t) < p
+	mask := -b
+	for j := range t {
+		z[j] = t[j]&mask | s[j]&^mask
+	}
+}
+
+// z = x + y mod p.
+func (f *field) add(z, x, y []uint64) {
+	var t [MAX_FIELD_WORDS]uint64
+	var c uint64
+	for j := range f.p {
+		t[j], c = bits.Add64(x[j], y[j], c)
+	}
+	f.reduce(z, t[:len(f.p)], c)
+}
+
+// z = x - y mod p.
+func (f *field) sub(z, x, y []uint64) {
+	var b, c uint64
+	for j := range f.p {
+		z[j], b = bits.Sub64(x[j], y[j], b)
+	}
+	mask := -b
+	for j := range f.p {
+		z[j], c = bits.Add64(z[j], f.p[j]&mask, c)
+	}
+}
+
+// z = x^(p-2) = x^-1 mod p (0 для x = 0). Показатель открыт, поэтому
+// ветвление по его битам допустимо.
+func (f *field) inv(z, x []uint64) {
+	e := f.pm2
+	r := f.newElement()
+	copy(r, f.one)
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		f.mul(r, r, r)
+		if e.Bit(i) == 1 {
+			f.mul(r, r, x)
+		}
+	}
+	copy(z, r)
+}
+
+// Условный обмен a и b при bit = 1 без ветвления по bit.
+func cswapWords(a, b []uint64, bit uint64) {
+	mask := -bit
+	for i := range a {
+		t := mask & (a[i] ^ b[i])
+		a[i] ^= t
+		b[i] ^= t
+	}
+}
@@ -0,0 +1,152 @@
+package gost3410
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+type example struct {
+	curve *Curve
+	d     string
+	qx    string
+	qy    string
+	e     string
+	k     string
+	r     string
+	s     string
+}
+
+// Контрольные примеры 1 и 2 из приложения А ГОСТ Р 34.10-2012.
+var examples = []example{
+	{
+		CurveTest256,
+		"7A929ADE789BB9BE10ED359DD39A72C11B60961F49397EEE1D19CE9891EC3B28",
+		"7F2B49E270DB6D90D8595BEC458B50C58585BA1D4E9B788F6689DBD8E56FD80B",
+		"26F1B489D6701DD185C8413A977B3CBBAF64D1C593D26627DFFB101A87FF77DA",
+		"2DFBC1B372D89A1188C09C52E0EEC61FCE52032AB1022E8E67ECE6672B043EE5",
+		"77105C9B20BCD3122823C8CF6FCC7B956DE33814E95B7FE64FED924594DCEAB3",
+		"41AA28D2F1AB148280CD9ED56FEDA41974053554A42767B83AD043FD39DC0493",
+		"01456C64BA4642A1653C235A98A60249BCD6D3F746B631DF928014F6C5BF9C40",
+	},
+	{
+		CurveTest512,
+		"0BA6048AADAE241BA40936D47756D7C93091A0E8514669700EE7508E508B102072E8123B2200A0563322DAD2827E2714A2636B7BFD18AADFC62967821FA18DD4",
+		"115DC5BC96760C7B48598D8AB9E740D4C4A85A65BE33C1815B5C320C854621DD5A515856D13314AF69BC5B924C8B4DDFF75C45415C1D9DD9DD33612CD530EFE1",
+		"37C7C90CD40B0F5621DC3AC1B751CFA0E2634FA0503B3D52639F5D7FB72AFD61EA199441D943FFE7F0C70A2759A3CDB84C114E1F9339FDF27F35ECA93677BEEC",
+		"3754F3CFACC9E0615C4F4A7C4D8DAB531B09B6F9C170C533A71D147035B0C5917184EE536593F4414339976C647C5D5A407ADEDB1D560C4FC6777D2972075B8C",
+		"0359E7F4B1410FEACC570456C6801496946312120B39D019D455986E364F365886748ED7A44B3E794434006011842286212273A6D14CF70EA3AF71BB1AE679F1",
+		"2F86FA60A081091A23DD795E1E3C689EE512A3C82EE0DCC2643C78EEA8FCACD35492558486B20F1C9EC197C90699850260C93BCBCD9C5C3317E19344E173AE36",
+		"1081B394696FFE8E6585E7A9362D26B6325F56778AADBC081C0BFBE933D52FF5823CE288E8C4F362526080DF7F70CE406A6EEB1F56919CB92A9853BDE73E5B4A",
+	},
+}
+
+func TestStandardExamples(t *testing.T) {
+	for i, ex := range examples {
+		prv, err := NewPrivateKey(ex.curve, hexInt(ex.d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prv.X.Cmp(hexInt(ex.qx)) != 0 || prv.Y.Cmp(hexInt(ex.qy)) != 0 {
+			t.Errorf("[%d] incorrect public key", i)
+		}
+		r, s := prv.signWithK(hexInt(ex.e), hexInt(ex.k))
+		if r.Cmp(hexInt(ex.r)) != 0 || s.Cmp(hexInt(ex.s)) != 0 {
+			t.Errorf("[%d] incorrect signature r = %x, s = %x", i, r, s)
+		}
+		if !prv.PublicKey.verify(hexInt(ex.e), r, s) {
+			t.Errorf("[%d] signature is not verified", i)
+		}
+		if prv.PublicKey.verify(new(big.Int).Add(hexInt(ex.e), one), r, s) {
+			t.Errorf("[%d] signature of other digest is verified", i)
+		}
+	}
+}
+
+var allCurves = []*Curve{CurveTest256, CurveTest512, CurveTC26_256A, CurveTC26_256B, CurveTC26_256C,
+	CurveTC26_256D, CurveTC26_512A, CurveTC26_512B, CurveTC26_512C}
+
+func TestCurveParams(t *testing.T) {
+	for _, c := range allCurves {
+		if !c.P.ProbablyPrime(20) || !c.Q.ProbablyPrime(20) {
+			t.Errorf("%s: p or q is not prime", c.Name)
+		}
+		if x, _ := c.ScalarBaseMult(c.Q); x != nil {
+			t.Errorf("%s: base point order is not q", c.Name)
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	msg := []byte("release artefact")
+	for _, c := range allCurves {
+		prv, err := GenerateKey(c, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := prv.SignMessage(nil, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sig) != c.SignatureSize() {
+			t.Fatalf("%s: incorrect signature length %d", c.Name, len(sig))
+		}
+		pub, err := NewPublicKey(c, prv.X, prv.Y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := pub.VerifyMessage(msg, sig); !ok || err != nil {
+			t.Errorf("%s: signature is not verified: %v", c.Name, err)
+		}
+		if ok, _ := pub.VerifyMessage([]byte("other artefact"), sig); ok {
+			t.Errorf("%s: signature of other message is verified", c.Name)
+		}
+		sig[len(sig)-1] ^= 1
+		if ok, _ := pub.VerifyMessage(msg, sig); ok {
+			t.Errorf("%s: modified signature is verified", c.Name)
+		}
+	}
+}
+
+func TestSignDeterministic(t *testing.T) {
+	prv, err := NewPrivateKey(CurveTC26_256A, big.NewInt(12345))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := CurveTC26_256A.hashMessage([]byte("message"))
+	sig1, err := prv.SignDeterministic(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, _ := prv.SignDeterministic(digest)
+	if !bytes.Equal(sig1, sig2) {
+		t.Error("deterministic signatures differ")
+	}
+	sig3, _ := prv.SignDeterministic(CurveTC26_256A.hashMessage([]byte("other")))
+	if bytes.Equal(sig1[:32], sig3[:32]) {
+		t.Error("nonce is reused for other digest")
+	}
+	if ok, _ := prv.Verify(digest, sig1); !ok {
+		t.Error("deterministic signature is not verified")
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	c := CurveTC26_256B
+	if _, err := NewPrivateKey(c, big.NewInt(0)); err == nil {
+		t.Error("zero private key accepted")
+	}
+	if _, err := NewPrivateKey(c, c.Q); err == nil {
+		t.Error("private key q accepted")
+	}
+	if _, err := NewPublicKey(c, big.NewInt(1), big.NewInt(1)); err == nil {
+		t.Error("point not on curve accepted")
+	}
+	prv, _ := GenerateKey(c, nil)
+	if _, err := prv.Verify(make([]byte, 32), make([]byte, 10)); err == nil {
+		t.Error("short signature accepted")
+	}
+	if ok, _ := prv.Verify(make([]byte, 32), make([]byte, 64)); ok {
+		t.Error("zero signature verified")
+	}
+}
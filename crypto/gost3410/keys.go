@@ -0,0 +1,69 @@
+package gost3410
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"io"
+	"math/big"
+)
+
+type PublicKey struct {
+	Curve *Curve
+	X     *big.Int
+	Y     *big.Int
+}
+
+type PrivateKey struct {
+	PublicKey
+	D *big.Int
+}
+
+// Создание закрытого ключа по значению d (0 < d < q).
+func NewPrivateKey(curve *Curve, d *big.Int) (*PrivateKey, error) {
+	if d.Sign() <= 0 || d.Cmp(curve.Q) >= 0 {
+		return nil, errors.New(PREFIX + "invalid private key")
+	}
+	x, y := curve.ScalarMultCT(d, curve.X, curve.Y)
+	return &PrivateKey{PublicKey: PublicKey{Curve: curve, X: x, Y: y}, D: new(big.Int).Set(d)}, nil
+}
+
+// Создание открытого ключа с проверкой принадлежности точки кривой и
+// подгруппе порядка q.
+func NewPublicKey(curve *Curve, x, y *big.Int) (*PublicKey, error) {
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New(PREFIX + "public key is not on curve")
+	}
+	if qx, _ := curve.ScalarMult(curve.Q, x, y); qx != nil {
+		return nil, errors.New(PREFIX + "public key is not in prime order subgroup")
+	}
+	return &PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// Случайное число из интервала (0, q) по выходу генератора.
+func randScalar(q *big.Int, rand io.Reader) (*big.Int, error) {
+	if rand == nil {
+		rand = hdrbg.Reader
+	}
+	buf := make([]byte, (q.BitLen()+7)/8)
+	defer clear(buf)
+	excess := uint(len(buf)*8 - q.BitLen())
+	for {
+		if _, err := io.ReadFull(rand, buf); err != nil {
+			return nil, err
+		}
+		buf[0] &= 0xff >> excess
+		k := new(big.Int).SetBytes(buf)
+		if k.Sign() > 0 && k.Cmp(q) < 0 {
+			return k, nil
+		}
+	}
+}
+
+// Генерация ключевой пары; при rand == nil используется hdrbg.Reader.
+func GenerateKey(curve *Curve, rand io.Reader) (*PrivateKey, error) {
+	d, err := randScalar(curve.Q, rand)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(curve, d)
+}
@@ -0,0 +1,167 @@
+package gost3410
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/drbg/hmacdrbg"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"hash"
+	"io"
+	"math/big"
+)
+
+// Число e = alpha mod q по хэш-коду (e = 1 при alpha = 0 mod q).
+// Хэш-код задаётся в порядке байтов Стрибога, т.е. как число alpha
+// в little-endian.
+func (c *Curve) digestToInt(digest []byte) *big.Int {
+	be := make([]byte, len(digest))
+	for i, b := range digest {
+		be[len(digest)-1-i] = b
+	}
+	e := new(big.Int).SetBytes(be)
+	e.Mod(e, c.Q)
+	if e.Sign() == 0 {
+		e.SetInt64(1)
+	}
+	return e
+}
+
+// Длина подписи в байтах.
+func (c *Curve) SignatureSize() int {
+	return 2 * c.PointSize
+}
+
+// Подпись хэш-кода digest с одноразовым числом k из генератора rand
+// (при rand == nil - hdrbg.Reader). Подпись - r || s в big-endian
+// (вектор ζ ГОСТ Р 34.10-2012, 6.1).
+func (prv *PrivateKey) Sign(rand io.Reader, digest []byte) ([]byte, error) {
+	e := prv.Curve.digestToInt(digest)
+	for {
+		k, err := randScalar(prv.Curve.Q, rand)
+		if err != nil {
+			return nil, err
+		}
+		r, s := prv.signWithK(e, k)
+		if r != nil {
+			return prv.Curve.encodeSignature(r, s), nil
+		}
+	}
+}
+
+// Детерминированная подпись: одноразовые числа порождает HMAC_DRBG на
+// Стрибоге, инициализированный закрытым ключом и хэш-кодом.
+func (prv *PrivateKey) SignDeterministic(digest []byte) ([]byte, error) {
+	c := prv.Curve
+	e := c.digestToInt(digest)
+	newHash := streebog.New256
+	if c.PointSize == 64 {
+		newHash = streebog.New512
+	}
+	entropy := prv.D.FillBytes(make([]byte, c.PointSize))
+	defer clear(entropy)
+	nonce := e.FillBytes(make([]byte, c.PointSize))
+	drbg, err := hmacdrbg.NewHmacDrbg(newHash, hdrbg.SECURITY_LEVEL_ONE, entropy, nonce, []byte("gost3410-deterministic"))
+	if err != nil {
+		return nil, err
+	}
+	reader := readerFunc(func(b []byte) (int, error) {
+		return len(b), drbg.Generate(b, nil)
+	})
+	for {
+		k, err := randScalar(c.Q, reader)
+		if err != nil {
+			return nil, err
+		}
+		r, s := prv.signWithK(e, k)
+		if r != nil {
+			return c.encodeSignature(r, s), nil
+		}
+	}
+}
+
+type readerFunc func(b []byte) (int, error)
+
+func (f readerFunc) Read(b []byte) (int, error) {
+	return f(b)
+}
+
+// Шаги 4-5 формирования подписи: r = x(kP) mod q, s = rd + ke mod q.
+// Возвращает nil, если r или s равны нулю.
+func (prv *PrivateKey) signWithK(e, k *big.Int) (*big.Int, *big.Int) {
+	q := prv.Curve.Q
+	x, _ := prv.Curve.ScalarMultCT(k, prv.Curve.X, prv.Curve.Y)
+	if x == nil {
+		return nil, nil
+	}
+	r := new(big.Int).Mod(x, q)
+	if r.Sign() == 0 {
+		return nil, nil
+	}
+	s := new(big.Int).Mul(r, prv.D)
+	s.Add(s, new(big.Int).Mul(k, e))
+	s.Mod(s, q)
+	if s.Sign() == 0 {
+		return nil, nil
+	}
+	return r, s
+}
+
+func (c *Curve) encodeSignature(r, s *big.Int) []byte {
+	sig := make([]byte, c.SignatureSize())
+	r.FillBytes(sig[:c.PointSize])
+	s.FillBytes(sig[c.PointSize:])
+	return sig
+}
+
+// Проверка подписи хэш-кода digest.
+func (pub *PublicKey) Verify(digest, signature []byte) (bool, error) {
+	c := pub.Curve
+	if len(signature) != c.SignatureSize() {
+		return false, errors.New(PREFIX + "invalid signature length")
+	}
+	r := new(big.Int).SetBytes(signature[:c.PointSize])
+	s := new(big.Int).SetBytes(signature[c.PointSize:])
+	return pub.verify(c.digestToInt(digest), r, s), nil
+}
+
+func (pub *PublicKey) verify(e, r, s *big.Int) bool {
+	c := pub.Curve
+	q := c.Q
+	if r.Sign() <= 0 || r.Cmp(q) >= 0 || s.Sign() <= 0 || s.Cmp(q) >= 0 {
+		return false
+	}
+	v := new(big.Int).ModInverse(e, q)
+	z1 := new(big.Int).Mul(s, v)
+	z1.Mod(z1, q)
+	z2 := new(big.Int).Mul(r, v)
+	z2.Neg(z2).Mod(z2, q)
+
+	x1, y1 := c.ScalarBaseMult(z1)
+	x2, y2 := c.ScalarMult(z2, pub.X, pub.Y)
+	x, _ := c.Add(x1, y1, x2, y2)
+	if x == nil {
+		return false
+	}
+	return x.Mod(x, q).Cmp(r) == 0
+}
+
+// Подпись сообщения с хэшированием Стрибогом соответствующей длины.
+func (prv *PrivateKey) SignMessage(rand io.Reader, msg []byte) ([]byte, error) {
+	return prv.Sign(rand, prv.Curve.hashMessage(msg))
+}
+
+// Проверка подписи сообщения.
+func (pub *PublicKey) VerifyMessage(msg, signature []byte) (bool, error) {
+	return pub.Verify(pub.Curve.hashMessage(msg), signature)
+}
+
+func (c *Curve) hashMessage(msg []byte) []byte {
+	var h hash.Hash
+	if c.PointSize == 64 {
+		h = streebog.New512()
+	} else {
+		h = streebog.New256()
+	}
+	h.Write(msg)
+	return h.Sum(nil)
+}
@@ -0,0 +1,66 @@
+package gost3410
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"math/big"
+)
+
+// Число UKM по байтам в little-endian (UKM = 1 при нулевом значении).
+func NewUKM(raw []byte) *big.Int {
+	be := make([]byte, len(raw))
+	for i, b := range raw {
+		be[len(raw)-1-i] = b
+	}
+	ukm := new(big.Int).SetBytes(be)
+	if ukm.Sign() == 0 {
+		ukm.SetInt64(1)
+	}
+	return ukm
+}
+
+// Общая точка K = m/q * ((UKM * d mod q) * Q_peer) (Р 50.1.113-2016, 4.3.1),
+// где m/q - кофактор кривой. Кофактор применяется после приведения по
+// модулю q, чтобы исключить компоненту малого порядка в ключе партнёра.
+func (prv *PrivateKey) sharedPoint(pub *PublicKey, ukm *big.Int) ([]byte, error) {
+	c := prv.Curve
+	if pub.Curve != c {
+		return nil, errors.New(PREFIX + "keys are on different curves")
+	}
+	if !c.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New(PREFIX + "public key is not on curve")
+	}
+	t := new(big.Int).Mul(ukm, prv.D)
+	t.Mod(t, c.Q)
+	defer t.SetInt64(0)
+	x, y := c.ScalarMultCT(t, pub.X, pub.Y)
+	x, y = c.ScalarMult(c.Co, x, y)
+	if x == nil {
+		return nil, errors.New(PREFIX + "shared point is at infinity")
+	}
+	return c.pointBytes(x, y), nil
+}
+
+// VKO_GOSTR3410_2012_256: Стрибог-256 от общей точки.
+func (prv *PrivateKey) VKO256(pub *PublicKey, ukm *big.Int) ([]byte, error) {
+	k, err := prv.sharedPoint(pub, ukm)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(k)
+	h := streebog.New256()
+	h.Write(k)
+	return h.Sum(nil), nil
+}
+
+// VKO_GOSTR3410_2012_512: Стрибог-512 от общей точки.
+func (prv *PrivateKey) VKO512(pub *PublicKey, ukm *big.Int) ([]byte, error) {
+	k, err := prv.sharedPoint(pub, ukm)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(k)
+	h := streebog.New512()
+	h.Write(k)
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,109 @@
+package gost3410
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func hexLE(t *testing.T, s string) *big.Int {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reverse(b)
+	return new(big.Int).SetBytes(b)
+}
+
+// Пример из Р 50.1.113-2016 (приложение А), кривая 512-paramSetA.
+// Значения записаны в little-endian.
+func TestVKOExample(t *testing.T) {
+	c := CurveTC26_512A
+	ukm, _ := hex.DecodeString("1d80603c8544c727")
+
+	prvA, err := NewPrivateKey(c, hexLE(t, "c990ecd972fce84ec4db022778f50fcac726f46708384b8d458304962d7147f8c2db41cef22c90b102f2968404f9b9be6d47c79692d81826b32b8daca43cb667"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prvA.X.Cmp(hexLE(t, "aab0eda4abff21208d18799fb9a8556654ba783070eba10cb9abb253ec56dcf5d3ccba6192e464e6e5bcb6dea137792f2431f6c897eb1b3c0cc14327b1adc0a7")) != 0 ||
+		prvA.Y.Cmp(hexLE(t, "914613a3074e363aedb204d38d3563971bd8758e878c9db11403721b48002d38461f92472d40ea92f9958c0ffa4c93756401b97f89fdbe0b5e46e4a4631cdb5a")) != 0 {
+		t.Fatal("incorrect public key A")
+	}
+	prvB, err := NewPrivateKey(c, hexLE(t, "48c859f7b6f11585887cc05ec6ef1390cfea739b1a18c0d4662293ef63b79e3b8014070b44918590b4b996acfea4edfbbbcccc8c06edd8bf5bda92a51392d0db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prvB.X.Cmp(hexLE(t, "192fe183b9713a077253c72c8735de2ea42a3dbc66ea317838b65fa32523cd5efca974eda7c863f4954d1147f1f2b25c395fce1c129175e876d132e94ed5a651")) != 0 ||
+		prvB.Y.Cmp(hexLE(t, "04883b414c9b592ec4dc84826f07d0b6d9006dda176ce48c391e3f97d102e03bb598bf132a228a45f7201aba08fc524a2d77e43a362ab022ad4028f75bde3b79")) != 0 {
+		t.Fatal("incorrect public key B")
+	}
+
+	want256, _ := hex.DecodeString("c9a9a77320e2cc559ed72dce6f47e2192ccea95fa648670582c054c0ef36c221")
+	want512, _ := hex.DecodeString("79f002a96940ce7bde3259a52e015297adaad84597a0d205b50e3e1719f97bfa7ee1d2661fa9979a5aa235b558a7e6d9f88f982dd63fc35a8ec0dd5e242d3bdf")
+
+	for _, pair := range [][2]*PrivateKey{{prvA, prvB}, {prvB, prvA}} {
+		k256, err := pair[0].VKO256(&pair[1].PublicKey, NewUKM(ukm))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(k256, want256) {
+			t.Errorf("incorrect VKO256 %x", k256)
+		}
+		k512, err := pair[0].VKO512(&pair[1].PublicKey, NewUKM(ukm))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(k512, want512) {
+			t.Errorf("incorrect VKO512 %x", k512)
+		}
+	}
+}
+
+// Кофактор исключает вклад компоненты малого порядка в открытом ключе.
+func TestVKOCofactor(t *testing.T) {
+	c := CurveTC26_256A
+	// Точка кручения: q * R для произвольной точки R (p = 3 mod 4)
+	var tx, ty *big.Int
+	for x := int64(1); tx == nil; x++ {
+		bx := big.NewInt(x)
+		rhs := new(big.Int).Mul(bx, bx)
+		rhs.Add(rhs, c.A)
+		rhs.Mul(rhs, bx)
+		rhs.Add(rhs, c.B)
+		rhs.Mod(rhs, c.P)
+		e := new(big.Int).Add(c.P, one)
+		y := new(big.Int).Exp(rhs, e.Rsh(e, 2), c.P)
+		if !c.IsOnCurve(bx, y) {
+			continue
+		}
+		tx, ty = c.ScalarMult(c.Q, bx, y)
+	}
+
+	prvA, _ := GenerateKey(c, nil)
+	prvB, _ := GenerateKey(c, nil)
+	px, py := c.Add(prvB.X, prvB.Y, tx, ty)
+	ukm := NewUKM([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	k1, err := prvA.VKO256(&prvB.PublicKey, ukm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := prvA.VKO256(&PublicKey{Curve: c, X: px, Y: py}, ukm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k1, k2) {
+		t.Error("small order component affects shared key")
+	}
+	k3, _ := prvB.VKO256(&prvA.PublicKey, ukm)
+	if !bytes.Equal(k1, k3) {
+		t.Error("shared keys differ")
+	}
+	if _, err := prvA.VKO256(&prvB.PublicKey, NewUKM(nil)); err != nil {
+		t.Errorf("zero UKM is not replaced: %v", err)
+	}
+	if _, err := prvA.VKO256(&PublicKey{Curve: CurveTC26_256B, X: CurveTC26_256B.X, Y: CurveTC26_256B.Y}, ukm); err == nil {
+		t.Error("key on other curve accepted")
+	}
+}
@@ -0,0 +1,268 @@
+package handshake
+
+import (
+	"crypto/subtle"
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/gost3410"
+	"gost_magma_cbc/crypto/hash/hmac"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"gost_magma_cbc/crypto/kdf"
+	"hash"
+	"io"
+)
+
+// Рукопожатие с взаимной аутентификацией (схема SIGMA): эфемерный обмен
+// VKO_GOSTR3410_2012_256, подписи ГОСТ Р 34.10-2012 над хэшем
+// протокола (транскрипта) на Стрибоге, MAC подтверждения ключа и
+// выработка ключей шифрования и имитозащиты для каждого направления
+// через KDF256.
+//
+//	C -> S: CLIENT_HELLO    version, nonce_c, E_c
+//	S -> C: SERVER_HELLO    nonce_s, E_s, S_pub, sig_s, mac_s
+//	C -> S: CLIENT_FINISHED C_pub, sig_c, mac_c
+
+const PREFIX = "crypto:handshake: "
+
+const PROTOCOL_VERSION = 1
+
+// Длина случайных значений nonce_c и nonce_s.
+const NONCE_SIZE = 32
+
+// Длина выработанных ключей.
+const KEY_SIZE = 32
+
+// Метки KDF256 и хэшей транскрипта.
+const (
+	LABEL_MASTER     = "hs master"
+	LABEL_SERVER_SIG = "hs server signature"
+	LABEL_CLIENT_SIG = "hs client signature"
+	LABEL_SERVER_FIN = "hs server finished"
+	LABEL_CLIENT_FIN = "hs client finished"
+	LABEL_CLIENT_ENC = "hs c2s enc"
+	LABEL_CLIENT_MAC = "hs c2s mac"
+	LABEL_SERVER_ENC = "hs s2c enc"
+	LABEL_SERVER_MAC = "hs s2c mac"
+)
+
+var (
+	ErrUnexpected   = errors.New(PREFIX + "unexpected message")
+	ErrAborted      = errors.New(PREFIX + "handshake aborted by peer")
+	ErrBadSignature = errors.New(PREFIX + "invalid peer signature")
+	ErrBadFinished  = errors.New(PREFIX + "invalid key confirmation")
+)
+
+// Параметры стороны. Эфемерные ключи вырабатываются на кривой Curve,
+// долговременные ключи обеих сторон должны лежать на той же кривой.
+type Config struct {
+	Curve *gost3410.Curve
+	// Собственный долговременный ключ подписи.
+	Key *gost3410.PrivateKey
+	// Проверка долговременного ключа партнёра (сравнение с известным
+	// ключом, проверка сертификата и т.п.).
+	VerifyPeer func(pub *gost3410.PublicKey) error
+	// Генератор случайных чисел; при nil используется hdrbg.Reader.
+	Rand io.Reader
+}
+
+func (c *Config) check() error {
+	if c == nil || c.Curve == nil || c.Key == nil {
+		return errors.New(PREFIX + "incomplete config")
+	}
+	if c.VerifyPeer == nil {
+		return errors.New(PREFIX + "nil peer verification")
+	}
+	if c.Key.Curve != c.Curve {
+		return errors.New(PREFIX + "static key is on another curve")
+	}
+	return nil
+}
+
+// Ключи, выработанные по завершении рукопожатия.
+type Keys struct {
+	ClientEnc []byte // шифрование C -> S
+	ClientMac []byte // имитозащита C -> S
+	ServerEnc []byte // шифрование S -> C
+	ServerMac []byte // имитозащита S -> C
+}
+
+func (k *Keys) Clear() {
+	clear(k.ClientEnc)
+	clear(k.ClientMac)
+	clear(k.ServerEnc)
+	clear(k.ServerMac)
+}
+
+type state int
+
+const (
+	stateStart state = iota
+	stateWaitServerHello
+	stateWaitClientFinished
+	stateDone
+	stateFailed
+)
+
+// Общие данные сторон: транскрипт и ключевое расписание.
+type session struct {
+	config     *Config
+	state      state
+	transcript []byte
+	eph        *gost3410.PrivateKey
+	nonceC     []byte
+	master     []byte
+	keys       *Keys
+	peer       *gost3410.PublicKey
+}
+
+func (s *session) newHash() hash.Hash {
+	if s.config.Curve.PointSize == 64 {
+		return streebog.New512()
+	}
+	return streebog.New256()
+}
+
+// H(label || transcript)
+func (s *session) hash(label string) []byte {
+	h := s.newHash()
+	h.Write([]byte(label))
+	h.Write(s.transcript)
+	return h.Sum(nil)
+}
+
+func (s *session) derive(label string, seed []byte) ([]byte, error) {
+	return kdf.NewKDF256().Create(s.master, []byte(label), seed)
+}
+
+// master = KDF256(VKO(e, E_peer, UKM = nonce_c), LABEL_MASTER, H(транскрипт)).
+func (s *session) deriveMaster(peerEph []byte) error {
+	c := s.config.Curve
+	pub, err := gost3410.NewPublicKeyFromRaw(c, peerEph)
+	if err != nil {
+		return err
+	}
+	z, err := s.eph.VKO256(pub, gost3410.NewUKM(s.nonceC[:8]))
+	if err != nil {
+		return err
+	}
+	defer clear(z)
+	s.eph.D.SetInt64(0)
+	s.master, err = kdf.NewKDF256().Create(z, []byte(LABEL_MASTER), s.hash(""))
+	return err
+}
+
+// MAC подтверждения: HMAC_GOSTR3411_2012_256(K_fin, H(label || транскрипт)),
+// K_fin = KDF256(master, label, H(транскрипт до подписей)).
+func (s *session) finished(label string, seed []byte) ([]byte, error) {
+	k, err := s.derive(label, seed)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(k)
+	h := hmac.New(streebog.New256, k)
+	h.Write(s.hash(label))
+	return h.Sum(nil), nil
+}
+
+func (s *session) sign(label string) ([]byte, error) {
+	return s.config.Key.Sign(s.config.Rand, s.hash(label))
+}
+
+func (s *session) verifyPeer(raw, sig []byte, label string) error {
+	pub, err := gost3410.NewPublicKeyFromRaw(s.config.Curve, raw)
+	if err != nil {
+		return err
+	}
+	if err := s.config.VerifyPeer(pub); err != nil {
+		return err
+	}
+	ok, err := pub.Verify(s.hash(label), sig)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrBadSignature
+	}
+	s.peer = pub
+	return nil
+}
+
+func (s *session) checkFinished(label string, seed, mac []byte) error {
+	want, err := s.finished(label, seed)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(want, mac) != 1 {
+		return ErrBadFinished
+	}
+	return nil
+}
+
+// Ключи направлений от хэша полного транскрипта.
+func (s *session) deriveKeys() error {
+	seed := s.hash("")
+	keys := &Keys{}
+	for _, d := range []struct {
+		dst   *[]byte
+		label string
+	}{
+		{&keys.ClientEnc, LABEL_CLIENT_ENC},
+		{&keys.ClientMac, LABEL_CLIENT_MAC},
+		{&keys.ServerEnc, LABEL_SERVER_ENC},
+		{&keys.ServerMac, LABEL_SERVER_MAC},
+	} {
+		k, err := s.derive(d.label, seed)
+		if err != nil {
+			return err
+		}
+		*d.dst = k
+	}
+	s.keys = keys
+	clear(s.master)
+	s.state = stateDone
+	return nil
+}
+
+// Транскрипт составляется из закодированных частей сообщений в порядке
+// их обработки; подписи и MAC добавляются отдельными частями типа 0.
+func (s *session) appendMessage(typ byte, fields ...[]byte) {
+	s.transcript = append(s.transcript, (&Message{Type: typ, Fields: fields}).Marshal()...)
+}
+
+func (s *session) fail() {
+	s.state = stateFailed
+	if s.eph != nil {
+		s.eph.D.SetInt64(0)
+	}
+	clear(s.master)
+	if s.keys != nil {
+		s.keys.Clear()
+		s.keys = nil
+	}
+}
+
+// Ключи сеанса, доступны после успешного завершения.
+func (s *session) Keys() (*Keys, error) {
+	if s.state != stateDone {
+		return nil, errors.New(PREFIX + "handshake is not completed")
+	}
+	return s.keys, nil
+}
+
+// Проверенный долговременный ключ партнёра.
+func (s *session) PeerKey() *gost3410.PublicKey {
+	if s.state != stateDone {
+		return nil
+	}
+	return s.peer
+}
+
+func (s *session) random(n int) ([]byte, error) {
+	rand := s.config.Rand
+	if rand == nil {
+		rand = hdrbg.Reader
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(rand, b)
+	return b, err
+}
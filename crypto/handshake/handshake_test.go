@@ -0,0 +1,245 @@
+package handshake
+
+import (
+	"bytes"
+	"errors"
+	"gost_magma_cbc/crypto/gost3410"
+	"net"
+	"testing"
+)
+
+var errUnknownPeer = errors.New("unknown peer")
+
+func pinned(key *gost3410.PublicKey) func(*gost3410.PublicKey) error {
+	return func(pub *gost3410.PublicKey) error {
+		if pub.X.Cmp(key.X) != 0 || pub.Y.Cmp(key.Y) != 0 {
+			return errUnknownPeer
+		}
+		return nil
+	}
+}
+
+type peers struct {
+	client *Config
+	server *Config
+}
+
+func newPeers(t *testing.T, curve *gost3410.Curve) peers {
+	t.Helper()
+	kc, err := gost3410.GenerateKey(curve, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := gost3410.GenerateKey(curve, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return peers{
+		client: &Config{Curve: curve, Key: kc, VerifyPeer: pinned(&ks.PublicKey)},
+		server: &Config{Curve: curve, Key: ks, VerifyPeer: pinned(&kc.PublicKey)},
+	}
+}
+
+type result struct {
+	keys *Keys
+	err  error
+}
+
+// Рукопожатие через пару каналов с посредником, который может изменять
+// сообщения (modify == nil - прозрачная пересылка).
+func run(t *testing.T, p peers, modify func(toServer bool, m *Message)) (result, result) {
+	t.Helper()
+	c, mc := net.Pipe()
+	ms, s := net.Pipe()
+	relay := func(from, to net.Conn, toServer bool) {
+		defer to.Close()
+		for {
+			m, err := proto.Read(from)
+			if err != nil {
+				return
+			}
+			if modify != nil {
+				modify(toServer, m)
+			}
+			if proto.Write(to, m) != nil {
+				return
+			}
+		}
+	}
+	go relay(mc, ms, true)
+	go relay(ms, mc, false)
+
+	done := make(chan result)
+	go func() {
+		defer s.Close()
+		srv, err := NewServer(p.server)
+		if err != nil {
+			done <- result{nil, err}
+			return
+		}
+		k, err := srv.Run(s)
+		done <- result{k, err}
+	}()
+	cli, err := NewClient(p.client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := cli.Run(c)
+	c.Close()
+	return result{k, err}, <-done
+}
+
+func TestHandshake(t *testing.T) {
+	for _, curve := range []*gost3410.Curve{gost3410.CurveTC26_256A, gost3410.CurveTC26_512B} {
+		p := newPeers(t, curve)
+		a, b := run(t, p, nil)
+		if a.err != nil || b.err != nil {
+			t.Fatalf("%s: %v, %v", curve.Name, a.err, b.err)
+		}
+		ka, kb := a.keys, b.keys
+		if !bytes.Equal(ka.ClientEnc, kb.ClientEnc) || !bytes.Equal(ka.ClientMac, kb.ClientMac) ||
+			!bytes.Equal(ka.ServerEnc, kb.ServerEnc) || !bytes.Equal(ka.ServerMac, kb.ServerMac) {
+			t.Fatalf("%s: keys differ", curve.Name)
+		}
+		all := [][]byte{ka.ClientEnc, ka.ClientMac, ka.ServerEnc, ka.ServerMac}
+		for i := range all {
+			if len(all[i]) != KEY_SIZE {
+				t.Errorf("%s: incorrect key size", curve.Name)
+			}
+			for j := i + 1; j < len(all); j++ {
+				if bytes.Equal(all[i], all[j]) {
+					t.Errorf("%s: keys %d and %d are equal", curve.Name, i, j)
+				}
+			}
+		}
+	}
+}
+
+func TestHandshakeMITM(t *testing.T) {
+	curve := gost3410.CurveTC26_256B
+	p := newPeers(t, curve)
+	mallory, err := gost3410.GenerateKey(curve, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		modify    func(toServer bool, m *Message)
+		clientErr error
+		serverErr error
+	}{
+		{
+			// Подмена эфемерного ключа клиента ключом посредника
+			name: "client ephemeral",
+			modify: func(toServer bool, m *Message) {
+				if toServer && m.Type == MSG_CLIENT_HELLO {
+					m.Fields[2] = mallory.PublicKey.Raw()
+				}
+			},
+			clientErr: ErrBadSignature,
+			serverErr: ErrAborted,
+		},
+		{
+			// Подмена эфемерного ключа сервера
+			name: "server ephemeral",
+			modify: func(toServer bool, m *Message) {
+				if !toServer && m.Type == MSG_SERVER_HELLO {
+					m.Fields[1] = mallory.PublicKey.Raw()
+				}
+			},
+			clientErr: ErrBadSignature,
+			serverErr: ErrAborted,
+		},
+		{
+			name: "server nonce",
+			modify: func(toServer bool, m *Message) {
+				if !toServer && m.Type == MSG_SERVER_HELLO {
+					m.Fields[0][0] ^= 1
+				}
+			},
+			clientErr: ErrBadSignature,
+			serverErr: ErrAborted,
+		},
+		{
+			name: "server finished",
+			modify: func(toServer bool, m *Message) {
+				if !toServer && m.Type == MSG_SERVER_HELLO {
+					m.Fields[4][0] ^= 1
+				}
+			},
+			clientErr: ErrBadFinished,
+			serverErr: ErrAborted,
+		},
+		{
+			// Подстановка ключа посредника с его подписью
+			name: "server key",
+			modify: func(toServer bool, m *Message) {
+				if !toServer && m.Type == MSG_SERVER_HELLO {
+					m.Fields[2] = mallory.PublicKey.Raw()
+				}
+			},
+			clientErr: errUnknownPeer,
+			serverErr: ErrAborted,
+		},
+		{
+			name: "client finished",
+			modify: func(toServer bool, m *Message) {
+				if toServer && m.Type == MSG_CLIENT_FINISHED {
+					m.Fields[2][0] ^= 1
+				}
+			},
+			serverErr: ErrBadFinished,
+		},
+		{
+			name: "client signature",
+			modify: func(toServer bool, m *Message) {
+				if toServer && m.Type == MSG_CLIENT_FINISHED {
+					m.Fields[1][0] ^= 1
+				}
+			},
+			serverErr: ErrBadSignature,
+		},
+	}
+	for _, tt := range tests {
+		a, b := run(t, p, tt.modify)
+		if !errors.Is(b.err, tt.serverErr) {
+			t.Errorf("%s: server error %v, want %v", tt.name, b.err, tt.serverErr)
+		}
+		if tt.clientErr != nil && !errors.Is(a.err, tt.clientErr) {
+			t.Errorf("%s: client error %v, want %v", tt.name, a.err, tt.clientErr)
+		}
+		if b.keys != nil {
+			t.Errorf("%s: server completed handshake", tt.name)
+		}
+	}
+
+	// Активный посредник с собственным долговременным ключом
+	fake := *p.server
+	fake.Key = mallory
+	a, _ := run(t, peers{client: p.client, server: &fake}, nil)
+	if !errors.Is(a.err, errUnknownPeer) {
+		t.Errorf("impersonated server accepted: %v", a.err)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	p := newPeers(t, gost3410.CurveTC26_256A)
+	bad := *p.client
+	bad.Curve = gost3410.CurveTC26_256B
+	if _, err := NewClient(&bad); err == nil {
+		t.Error("key on another curve accepted")
+	}
+	bad = *p.client
+	bad.VerifyPeer = nil
+	if _, err := NewServer(&bad); err == nil {
+		t.Error("nil peer verification accepted")
+	}
+	c, _ := NewClient(p.client)
+	if _, err := c.Keys(); err == nil {
+		t.Error("keys available before handshake")
+	}
+	if _, err := c.Next(&Message{Type: MSG_SERVER_HELLO}); !errors.Is(err, ErrUnexpected) {
+		t.Error("message before start accepted")
+	}
+}
@@ -0,0 +1,23 @@
+package handshake
+
+import "gost_magma_cbc/crypto/protocol"
+
+// Типы сообщений рукопожатия.
+const (
+	MSG_CLIENT_HELLO    byte = 1 // version, nonce_c, E_c
+	MSG_SERVER_HELLO    byte = 2 // nonce_s, E_s, S_pub, sig_s, mac_s
+	MSG_CLIENT_FINISHED byte = 3 // C_pub, sig_c, mac_c
+	MSG_ABORT           byte = 4
+)
+
+// Максимальная длина кадра сообщения.
+const MAX_MESSAGE_SIZE = 4096
+
+type Message = protocol.Message
+
+var proto = &protocol.Protocol{
+	MaxSize:       MAX_MESSAGE_SIZE,
+	Abort:         MSG_ABORT,
+	ErrAbort:      ErrAborted,
+	ErrUnexpected: ErrUnexpected,
+}
@@ -0,0 +1,242 @@
+package handshake
+
+import (
+	"gost_magma_cbc/crypto/gost3410"
+	"io"
+)
+
+// Инициатор рукопожатия.
+type Client struct {
+	session
+	finSeed []byte
+}
+
+func NewClient(config *Config) (*Client, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	return &Client{session: session{config: config}}, nil
+}
+
+// CLIENT_HELLO.
+func (c *Client) Start() (*Message, error) {
+	if c.state != stateStart {
+		return nil, ErrUnexpected
+	}
+	var err error
+	c.nonceC, err = c.random(NONCE_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	c.eph, err = gost3410.GenerateKey(c.config.Curve, c.config.Rand)
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{Type: MSG_CLIENT_HELLO, Fields: [][]byte{{PROTOCOL_VERSION}, c.nonceC, c.eph.PublicKey.Raw()}}
+	c.appendMessage(m.Type, m.Fields...)
+	c.state = stateWaitServerHello
+	return m, nil
+}
+
+// Обработка SERVER_HELLO; возвращает CLIENT_FINISHED. При ошибке
+// возвращается MSG_ABORT для отправки серверу.
+func (c *Client) Next(m *Message) (*Message, error) {
+	if c.state != stateWaitServerHello {
+		return nil, ErrUnexpected
+	}
+	out, err := c.onServerHello(m)
+	if err != nil {
+		c.fail()
+		if m != nil && m.Type == MSG_ABORT {
+			return nil, err
+		}
+		return proto.AbortMessage(), err
+	}
+	return out, nil
+}
+
+func (c *Client) onServerHello(m *Message) (*Message, error) {
+	if err := proto.Expect(m, MSG_SERVER_HELLO, 5); err != nil {
+		return nil, err
+	}
+	nonceS, ephS, pubS, sigS, macS := m.Fields[0], m.Fields[1], m.Fields[2], m.Fields[3], m.Fields[4]
+	if len(nonceS) != NONCE_SIZE {
+		return nil, ErrUnexpected
+	}
+	c.appendMessage(MSG_SERVER_HELLO, nonceS, ephS, pubS)
+	c.finSeed = c.hash("")
+	if err := c.deriveMaster(ephS); err != nil {
+		return nil, err
+	}
+	if err := c.verifyPeer(pubS, sigS, LABEL_SERVER_SIG); err != nil {
+		return nil, err
+	}
+	c.appendMessage(0, sigS)
+	if err := c.checkFinished(LABEL_SERVER_FIN, c.finSeed, macS); err != nil {
+		return nil, err
+	}
+	c.appendMessage(0, macS)
+
+	pubC := c.config.Key.PublicKey.Raw()
+	c.appendMessage(MSG_CLIENT_FINISHED, pubC)
+	sigC, err := c.sign(LABEL_CLIENT_SIG)
+	if err != nil {
+		return nil, err
+	}
+	c.appendMessage(0, sigC)
+	macC, err := c.finished(LABEL_CLIENT_FIN, c.finSeed)
+	if err != nil {
+		return nil, err
+	}
+	c.appendMessage(0, macC)
+	if err := c.deriveKeys(); err != nil {
+		return nil, err
+	}
+	return &Message{Type: MSG_CLIENT_FINISHED, Fields: [][]byte{pubC, sigC, macC}}, nil
+}
+
+// Выполнение рукопожатия по соединению conn.
+func (c *Client) Run(conn io.ReadWriter) (*Keys, error) {
+	out, err := c.Start()
+	if err != nil {
+		return nil, err
+	}
+	if err := proto.Write(conn, out); err != nil {
+		return nil, err
+	}
+	in, err := proto.Read(conn)
+	if err != nil {
+		return nil, err
+	}
+	out, err = c.Next(in)
+	if out != nil {
+		if werr := proto.Write(conn, out); err == nil {
+			err = werr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.Keys()
+}
+
+// Отвечающая сторона.
+type Server struct {
+	session
+	finSeed []byte
+}
+
+func NewServer(config *Config) (*Server, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	return &Server{session: session{config: config}}, nil
+}
+
+// Обработка сообщений клиента: на CLIENT_HELLO возвращается
+// SERVER_HELLO, на CLIENT_FINISHED - nil.
+func (s *Server) Next(m *Message) (*Message, error) {
+	var out *Message
+	var err error
+	switch s.state {
+	case stateStart:
+		out, err = s.onClientHello(m)
+	case stateWaitClientFinished:
+		err = s.onClientFinished(m)
+		if err != nil {
+			s.fail()
+		}
+		return nil, err
+	default:
+		return nil, ErrUnexpected
+	}
+	if err != nil {
+		s.fail()
+		if m != nil && m.Type == MSG_ABORT {
+			return nil, err
+		}
+		return proto.AbortMessage(), err
+	}
+	return out, nil
+}
+
+func (s *Server) onClientHello(m *Message) (*Message, error) {
+	if err := proto.Expect(m, MSG_CLIENT_HELLO, 3); err != nil {
+		return nil, err
+	}
+	if len(m.Fields[0]) != 1 || m.Fields[0][0] != PROTOCOL_VERSION || len(m.Fields[1]) != NONCE_SIZE {
+		return nil, ErrUnexpected
+	}
+	s.nonceC = append([]byte(nil), m.Fields[1]...)
+	s.appendMessage(m.Type, m.Fields...)
+
+	nonceS, err := s.random(NONCE_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	s.eph, err = gost3410.GenerateKey(s.config.Curve, s.config.Rand)
+	if err != nil {
+		return nil, err
+	}
+	ephS := s.eph.PublicKey.Raw()
+	pubS := s.config.Key.PublicKey.Raw()
+	s.appendMessage(MSG_SERVER_HELLO, nonceS, ephS, pubS)
+	s.finSeed = s.hash("")
+	if err := s.deriveMaster(m.Fields[2]); err != nil {
+		return nil, err
+	}
+	sigS, err := s.sign(LABEL_SERVER_SIG)
+	if err != nil {
+		return nil, err
+	}
+	s.appendMessage(0, sigS)
+	macS, err := s.finished(LABEL_SERVER_FIN, s.finSeed)
+	if err != nil {
+		return nil, err
+	}
+	s.appendMessage(0, macS)
+	s.state = stateWaitClientFinished
+	return &Message{Type: MSG_SERVER_HELLO, Fields: [][]byte{nonceS, ephS, pubS, sigS, macS}}, nil
+}
+
+func (s *Server) onClientFinished(m *Message) error {
+	if err := proto.Expect(m, MSG_CLIENT_FINISHED, 3); err != nil {
+		return err
+	}
+	pubC, sigC, macC := m.Fields[0], m.Fields[1], m.Fields[2]
+	s.appendMessage(MSG_CLIENT_FINISHED, pubC)
+	if err := s.verifyPeer(pubC, sigC, LABEL_CLIENT_SIG); err != nil {
+		return err
+	}
+	s.appendMessage(0, sigC)
+	if err := s.checkFinished(LABEL_CLIENT_FIN, s.finSeed, macC); err != nil {
+		return err
+	}
+	s.appendMessage(0, macC)
+	return s.deriveKeys()
+}
+
+// Выполнение рукопожатия по соединению conn.
+func (s *Server) Run(conn io.ReadWriter) (*Keys, error) {
+	in, err := proto.Read(conn)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.Next(in)
+	if out != nil {
+		if werr := proto.Write(conn, out); err == nil {
+			err = werr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	in, err = proto.Read(conn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Next(in); err != nil {
+		return nil, err
+	}
+	return s.Keys()
+}
@@ -0,0 +1,207 @@
+package gost94
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/models"
+	"hash"
+)
+
+const (
+	Size      = 32
+	BlockSize = 32
+)
+
+// Хэш-функция ГОСТ Р 34.11-94 на основе шифра ГОСТ 28147-89 (Magma с
+// настраиваемыми узлами замены).
+type context struct {
+	base  models.BaseAlgorithm
+	key   models.Key
+	block models.Block
+
+	h      [BlockSize]byte
+	sigma  [BlockSize]byte
+	length [BlockSize]byte
+	m      [BlockSize]byte
+	mLen   int
+}
+
+func New(sbox *magma.Sbox) (hash.Hash, error) {
+	if sbox == nil {
+		return nil, errors.New("hash/gost94: nil sbox")
+	}
+	c := &context{}
+	c.base = magma.NewMagmaWithSbox(sbox)
+	c.key = c.base.NewKey()
+	c.block = c.base.NewBlock()
+	c.Reset()
+	return c, nil
+}
+
+func NewTest() hash.Hash {
+	h, _ := New(&TestParamSet)
+	return h
+}
+
+func NewCryptoPro() hash.Hash {
+	h, _ := New(&CryptoProParamSet)
+	return h
+}
+
+func (c *context) Reset() {
+	c.h = [BlockSize]byte{}
+	c.sigma = [BlockSize]byte{}
+	c.length = [BlockSize]byte{}
+	c.m = [BlockSize]byte{}
+	c.mLen = 0
+}
+
+func (c *context) Size() int {
+	return Size
+}
+
+func (c *context) BlockSize() int {
+	return BlockSize
+}
+
+func (c *context) Write(p []byte) (nn int, err error) {
+	nn = len(p)
+	for c.mLen+len(p) >= BlockSize {
+		offset := BlockSize - c.mLen
+		copy(c.m[c.mLen:], p[:offset])
+		c.process(&c.m, BlockSize)
+		p = p[offset:]
+		c.mLen = 0
+	}
+	copy(c.m[c.mLen:], p)
+	c.mLen += len(p)
+	return
+}
+
+func (c *context) Sum(in []byte) []byte {
+	h := c.h
+	sigma := c.sigma
+	length := c.length
+
+	if c.mLen > 0 {
+		var m [BlockSize]byte
+		copy(m[:], c.m[:c.mLen])
+		c.step(&h, &m)
+		addMod256(&sigma, &m)
+		addLength(&length, c.mLen)
+	}
+	c.step(&h, &length)
+	c.step(&h, &sigma)
+
+	return append(in, h[:]...)
+}
+
+func (c *context) process(m *[BlockSize]byte, n int) {
+	c.step(&c.h, m)
+	addMod256(&c.sigma, m)
+	addLength(&c.length, n)
+}
+
+// Шаговая функция хэширования: H = f(H, M).
+func (c *context) step(h *[BlockSize]byte, m *[BlockSize]byte) {
+	var keys [4][BlockSize]byte
+	var w [BlockSize]byte
+	u := *h
+	v := *m
+
+	xor32(&w, &u, &v)
+	p(&keys[0], &w)
+	for j := 1; j < 4; j++ {
+		a(&u)
+		if j == 2 {
+			xor32(&u, &u, &c3)
+		}
+		a(&v)
+		a(&v)
+		xor32(&w, &u, &v)
+		p(&keys[j], &w)
+	}
+
+	// Шифрующее преобразование
+	var s [BlockSize]byte
+	for i := 0; i < 4; i++ {
+		c.encrypt(&keys[i], h[i*8:i*8+8], s[i*8:i*8+8])
+	}
+
+	// Перемешивающее преобразование
+	for i := 0; i < 12; i++ {
+		psi(&s)
+	}
+	xor32(&s, &s, m)
+	psi(&s)
+	xor32(&s, &s, h)
+	for i := 0; i < 61; i++ {
+		psi(&s)
+	}
+	*h = s
+}
+
+// Зашифрование блока ГОСТ 28147-89. Подключи K1..K8 ГОСТ 28147-89 в
+// ключе Magma хранятся в обратном порядке.
+func (c *context) encrypt(key *[BlockSize]byte, src, dst []byte) {
+	kd := c.key.Data()
+	for i := 0; i < 8; i++ {
+		copy(kd[(7-i)*4:(7-i)*4+4], key[i*4:i*4+4])
+	}
+	copy(c.block.Data(), src)
+	c.base.Encrypt(c.key, c.block, c.block)
+	copy(dst, c.block.Data())
+	c.key.Clear()
+}
+
+func xor32(dst, x, y *[BlockSize]byte) {
+	for i := 0; i < BlockSize; i++ {
+		dst[i] = x[i] ^ y[i]
+	}
+}
+
+// A(y4||y3||y2||y1) = (y1 ^ y2)||y4||y3||y2
+func a(y *[BlockSize]byte) {
+	var t [8]byte
+	for i := 0; i < 8; i++ {
+		t[i] = y[i] ^ y[8+i]
+	}
+	copy(y[:24], y[8:])
+	copy(y[24:], t[:])
+}
+
+// Перестановка P: φ(i + 1 + 4(k-1)) = 8i + k.
+func p(dst, w *[BlockSize]byte) {
+	for i := 0; i < 4; i++ {
+		for k := 0; k < 8; k++ {
+			dst[i+4*k] = w[8*i+k]
+		}
+	}
+}
+
+// ψ(y16||...||y1) = (y1^y2^y3^y4^y13^y16)||y16||...||y2
+func psi(y *[BlockSize]byte) {
+	t0 := y[0] ^ y[2] ^ y[4] ^ y[6] ^ y[24] ^ y[30]
+	t1 := y[1] ^ y[3] ^ y[5] ^ y[7] ^ y[25] ^ y[31]
+	copy(y[:30], y[2:])
+	y[30] = t0
+	y[31] = t1
+}
+
+func addMod256(sum, m *[BlockSize]byte) {
+	var carry uint16
+	for i := 0; i < BlockSize; i++ {
+		carry += uint16(sum[i]) + uint16(m[i])
+		sum[i] = byte(carry)
+		carry >>= 8
+	}
+}
+
+func addLength(length *[BlockSize]byte, n int) {
+	var l [BlockSize]byte
+	bits := uint64(n) * 8
+	for i := 0; i < 8; i++ {
+		l[i] = byte(bits >> (8 * i))
+	}
+	addMod256(length, &l)
+}
@@ -0,0 +1,59 @@
+package gost94
+
+import (
+	"encoding/hex"
+	"hash"
+	"strings"
+	"testing"
+)
+
+type testData struct {
+	msg string
+	md  string
+}
+
+func checkVectors(t *testing.T, newHash func() hash.Hash, tests []testData) {
+	h := newHash()
+	for i, test := range tests {
+		h.Reset()
+		h.Write([]byte(test.msg))
+		sum := h.Sum(nil)
+		if hex.EncodeToString(sum) != test.md {
+			t.Errorf("[%d] fail, got %x, want %s", i, sum, test.md)
+		}
+
+		// Побайтовая запись должна давать тот же результат
+		h.Reset()
+		for j := 0; j < len(test.msg); j++ {
+			h.Write([]byte{test.msg[j]})
+		}
+		sum = h.Sum(nil)
+		if hex.EncodeToString(sum) != test.md {
+			t.Errorf("[%d] bytewise fail, got %x, want %s", i, sum, test.md)
+		}
+	}
+}
+
+func Test_TestParamSet(t *testing.T) {
+	checkVectors(t, NewTest, []testData{
+		{"", "ce85b99cc46752fffee35cab9a7b0278abb4c2d2055cff685af4912c49490f8d"},
+		{"a", "d42c539e367c66e9c88a801f6649349c21871b4344c6a573f849fdce62f314dd"},
+		{"abc", "f3134348c44fb1b2a277729e2285ebb5cb5e0f29c975bc753b70497c06a4d51d"},
+		{"message digest", "ad4434ecb18f2c99b60cbe59ec3d2469582b65273f48de72db2fde16a4889a4d"},
+		{"The quick brown fox jumps over the lazy dog", "77b7fa410c9ac58a25f49bca7d0468c9296529315eaca76bd1a10f376d1f4294"},
+		{strings.Repeat("U", 128), "53a3a3ed25180cef0c1d85a074273e551c25660a87062a52d926a9e8fe5733a4"},
+		{strings.Repeat("a", 1000000), "5c00ccc2734cdd3332d3d4749576e3c1a7dbaf0e7ea74e9fa602413c90a129fa"},
+	})
+}
+
+func Test_CryptoProParamSet(t *testing.T) {
+	checkVectors(t, NewCryptoPro, []testData{
+		{"", "981e5f3ca30c841487830f84fb433e13ac1101569b9c13584ac483234cd656c0"},
+		{"a", "e74c52dd282183bf37af0079c9f78055715a103f17e3133ceff1aacf2f403011"},
+		{"abc", "b285056dbf18d7392d7677369524dd14747459ed8143997e163b2986f92fd42c"},
+		{"message digest", "bc6041dd2aa401ebfa6e9886734174febdb4729aa972d60f549ac39b29721ba0"},
+		{"The quick brown fox jumps over the lazy dog", "9004294a361a508c586fe53d1f1b02746765e71b765472786e4770d565830a76"},
+		{strings.Repeat("U", 128), "1c4ac7614691bbf427fa2316216be8f10d92edfd37cd1027514c1008f649c4e8"},
+		{strings.Repeat("a", 1000000), "8693287aa62f9478f7cb312ec0866b6c4e4a0f11160441e8f4ffcd2715dd554f"},
+	})
+}
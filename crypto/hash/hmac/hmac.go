@@ -0,0 +1,64 @@
+package hmac
+
+import (
+	"hash"
+)
+
+// Потоковый HMAC (Р 50.1.113-2016) над произвольной хэш-функцией,
+// реализующий hash.Hash. Ключ длиннее блока хэш-функции предварительно
+// хэшируется.
+type streamHMAC struct {
+	inner   hash.Hash
+	outer   hash.Hash
+	ipadKey []byte
+	opadKey []byte
+}
+
+func New(newHash func() hash.Hash, key []byte) hash.Hash {
+	h := &streamHMAC{inner: newHash(), outer: newHash()}
+	blockSize := h.inner.BlockSize()
+
+	k := make([]byte, blockSize)
+	if len(key) > blockSize {
+		h.outer.Write(key)
+		key = h.outer.Sum(nil)
+		h.outer.Reset()
+	}
+	copy(k, key)
+
+	h.ipadKey = make([]byte, blockSize)
+	h.opadKey = make([]byte, blockSize)
+	for i := range k {
+		h.ipadKey[i] = k[i] ^ 0x36
+		h.opadKey[i] = k[i] ^ 0x5c
+		k[i] = 0
+	}
+
+	h.inner.Write(h.ipadKey)
+	return h
+}
+
+func (h *streamHMAC) Write(p []byte) (int, error) {
+	return h.inner.Write(p)
+}
+
+func (h *streamHMAC) Sum(in []byte) []byte {
+	sum := h.inner.Sum(nil)
+	h.outer.Reset()
+	h.outer.Write(h.opadKey)
+	h.outer.Write(sum)
+	return h.outer.Sum(in)
+}
+
+func (h *streamHMAC) Reset() {
+	h.inner.Reset()
+	h.inner.Write(h.ipadKey)
+}
+
+func (h *streamHMAC) Size() int {
+	return h.outer.Size()
+}
+
+func (h *streamHMAC) BlockSize() int {
+	return h.inner.BlockSize()
+}
@@ -0,0 +1,47 @@
+package hmac
+
+import (
+	"bytes"
+	"encoding/hex"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"hash"
+	"testing"
+)
+
+var hmacKey = []byte{
+	0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+	0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+}
+
+var hmacData = []byte{
+	0x01, 0x26, 0xbd, 0xb8, 0x78, 0x00, 0xaf, 0x21,
+	0x43, 0x41, 0x45, 0x65, 0x63, 0x78, 0x01, 0x00,
+}
+
+func Test_StreamHMAC(t *testing.T) {
+	tests := []struct {
+		newHash func() hash.Hash
+		mac     string
+	}{
+		{streebog.New256, "a1aa5f7de402d7b3d323f2991c8d4534013137010a83754fd0af6d7cd4922ed9"},
+		{streebog.New512, "a59bab22ecae19c65fbde6e5f4e9f5d8549d31f037f9df9b905500e171923a773d5f1530f2ed7e964cb2eedc29e9ad2f3afe93b2814f79f5000ffc0366c251e6"},
+	}
+
+	for i, test := range tests {
+		want, _ := hex.DecodeString(test.mac)
+
+		h := New(test.newHash, hmacKey)
+		h.Write(hmacData)
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("[%d] got %x, want %x", i, got, want)
+		}
+
+		// Потоковая запись по частям и повторное использование после Reset
+		h.Reset()
+		h.Write(hmacData[:5])
+		h.Write(hmacData[5:])
+		if got := h.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("[%d] after reset got %x, want %x", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package streebog
+
+import (
+	"testing"
+)
+
+func Test_WriteBlockNoAllocs(t *testing.T) {
+	h := New512()
+	block := make([]byte, BlockSize)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		h.Write(block)
+	})
+	if allocs != 0 {
+		t.Errorf("Write of a full block allocates %v times", allocs)
+	}
+
+	sum := make([]byte, 0, Size512)
+	allocs = testing.AllocsPerRun(100, func() {
+		sum = h.Sum(sum[:0])
+	})
+	if allocs != 0 {
+		t.Errorf("Sum allocates %v times", allocs)
+	}
+}
+
+func Test_SumNoAllocs(t *testing.T) {
+	data := make([]byte, 3*BlockSize+5)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		Sum256(data)
+		Sum512(data)
+	})
+	if allocs != 0 {
+		t.Errorf("Sum256/Sum512 allocate %v times", allocs)
+	}
+}
+
+func benchmarkWrite(b *testing.B, size int) {
+	h := New256()
+	buf := make([]byte, size)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Write(buf)
+	}
+}
+
+func Benchmark_WriteBlock(b *testing.B) {
+	benchmarkWrite(b, BlockSize)
+}
+
+func Benchmark_Write8K(b *testing.B) {
+	benchmarkWrite(b, 8192)
+}
+
+func Benchmark_Sum256(b *testing.B) {
+	buf := make([]byte, 1024)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		Sum256(buf)
+	}
+}
+
+func Benchmark_Sum512(b *testing.B) {
+	buf := make([]byte, 1024)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		Sum512(buf)
+	}
+}
@@ -86,40 +86,47 @@ func (c *context) Write(p []byte) (nn int, err error) {
 
 func (c *context) Sum(in []byte) []byte {
 	c0 := *c
-	hash := c0.checkSum()
-	return append(in, hash...)
+	var hash [Size512]byte
+	c0.checkSum(hash[:c.Size()])
+	return append(in, hash[:c.Size()]...)
 }
 
-func (c *context) checkSum() []byte {
+// Завершение вычисления хэша с записью результата в out (len(out) == Size()).
+func (c *context) checkSum(out []byte) {
 	c.m[c.mLen] = 0x01
 	c.mLen++
 
-	zeros := [BlockSize]byte{}
-
-	copy(c.m[c.mLen:], zeros[:])
+	clear(c.m[c.mLen:])
 	c.transform(false)
 	c.msgLen += uint64(c.mLen-1) * 8
 
-	copy(c.m[:], zeros[:])
+	clear(c.m[:])
 	PutU64(c.m[:], c.msgLen)
 	c.transform(true)
 
-	SS := uint64sToBytes(c.S[:])
-	copy(c.m[:], SS)
+	for i := 0; i < 8; i++ {
+		PutU64(c.m[i*8:], c.S[i])
+	}
 	c.transform(true)
 
-	ss := uint64sToBytes(c.hash[8-c.hashSize/BlockSize:])
-	return ss[:c.hashSize/8]
+	var hash [BlockSize]byte
+	for i := 0; i < 8; i++ {
+		PutU64(hash[i*8:], c.hash[i])
+	}
+	copy(out, hash[BlockSize-c.Size():])
 }
 
 func (c *context) transform(last bool) {
+	var m [8]uint64
+	loadBlock(&m, &c.m)
+
 	if last {
-		compress(&c.hash, c.m[:], 0)
+		compress(&c.hash, &m, 0)
 	} else {
-		compress(&c.hash, c.m[:], c.msgLen)
+		compress(&c.hash, &m, c.msgLen)
 	}
 
 	if !last {
-		add(c.m[:], &c.S)
+		add(&m, &c.S)
 	}
 }
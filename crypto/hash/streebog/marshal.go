@@ -0,0 +1,80 @@
+package streebog
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Формат сериализованного состояния:
+// magic || hash || m || mLen || msgLen || S
+const (
+	magic256      = "stb256\x01"
+	magic512      = "stb512\x01"
+	marshaledSize = len(magic256) + 8*8 + BlockSize + 8 + 8 + 8*8
+)
+
+func (c *context) magic() string {
+	if c.hashSize == 512 {
+		return magic512
+	}
+	return magic256
+}
+
+// Сохранение текущего состояния вычисления хэша (encoding.BinaryMarshaler).
+func (c *context) MarshalBinary() ([]byte, error) {
+	return c.AppendBinary(make([]byte, 0, marshaledSize))
+}
+
+// Добавление сериализованного состояния к b (encoding.BinaryAppender).
+func (c *context) AppendBinary(b []byte) ([]byte, error) {
+	b = append(b, c.magic()...)
+	for i := 0; i < 8; i++ {
+		b = binary.LittleEndian.AppendUint64(b, c.hash[i])
+	}
+	b = append(b, c.m[:]...)
+	b = binary.LittleEndian.AppendUint64(b, uint64(c.mLen))
+	b = binary.LittleEndian.AppendUint64(b, c.msgLen)
+	for i := 0; i < 8; i++ {
+		b = binary.LittleEndian.AppendUint64(b, c.S[i])
+	}
+	return b, nil
+}
+
+// Восстановление состояния вычисления хэша (encoding.BinaryUnmarshaler).
+// Размер хэша должен совпадать с сохранённым.
+func (c *context) UnmarshalBinary(b []byte) error {
+	if len(b) < len(magic256) || string(b[:len(magic256)]) != c.magic() {
+		return errors.New("hash/streebog: invalid hash state identifier")
+	}
+	if len(b) != marshaledSize {
+		return errors.New("hash/streebog: invalid hash state size")
+	}
+	b = b[len(magic256):]
+
+	var hash, S [8]uint64
+	for i := 0; i < 8; i++ {
+		hash[i] = binary.LittleEndian.Uint64(b)
+		b = b[8:]
+	}
+	var m [BlockSize]byte
+	copy(m[:], b)
+	b = b[BlockSize:]
+	mLen := binary.LittleEndian.Uint64(b)
+	msgLen := binary.LittleEndian.Uint64(b[8:])
+	b = b[16:]
+	for i := 0; i < 8; i++ {
+		S[i] = binary.LittleEndian.Uint64(b)
+		b = b[8:]
+	}
+
+	if mLen >= BlockSize || msgLen%512 != 0 {
+		return errors.New("hash/streebog: invalid hash state")
+	}
+
+	c.hash = hash
+	c.m = m
+	c.mLen = int(mLen)
+	c.msgLen = msgLen
+	c.S = S
+	return nil
+}
@@ -0,0 +1,68 @@
+package streebog
+
+import (
+	"bytes"
+	"encoding"
+	"hash"
+	"testing"
+)
+
+func testMarshalResume(t *testing.T, newHash func() hash.Hash) {
+	msg := make([]byte, 3*BlockSize+17)
+	for i := range msg {
+		msg[i] = byte(i * 7)
+	}
+
+	h := newHash()
+	h.Write(msg)
+	want := h.Sum(nil)
+
+	for split := 0; split <= len(msg); split++ {
+		h1 := newHash()
+		h1.Write(msg[:split])
+
+		state, err := h1.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatalf("[%d] marshal error: %v", split, err)
+		}
+
+		h2 := newHash()
+		if err := h2.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+			t.Fatalf("[%d] unmarshal error: %v", split, err)
+		}
+		h2.Write(msg[split:])
+
+		if got := h2.Sum(nil); !bytes.Equal(got, want) {
+			t.Errorf("[%d] resume fail, got %x, want %x", split, got, want)
+		}
+	}
+}
+
+func Test_MarshalResume256(t *testing.T) {
+	testMarshalResume(t, New256)
+}
+
+func Test_MarshalResume512(t *testing.T) {
+	testMarshalResume(t, New512)
+}
+
+func Test_UnmarshalErrors(t *testing.T) {
+	h256 := New256()
+	h256.Write([]byte("data"))
+	state, _ := h256.(encoding.BinaryMarshaler).MarshalBinary()
+
+	h512 := New512()
+	if err := h512.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err == nil {
+		t.Error("256 state accepted by 512 hash")
+	}
+
+	if err := New256().(encoding.BinaryUnmarshaler).UnmarshalBinary(state[:len(state)-1]); err == nil {
+		t.Error("truncated state accepted")
+	}
+
+	bad := bytes.Clone(state)
+	bad[len(magic256)-1] = 0x02
+	if err := New256().(encoding.BinaryUnmarshaler).UnmarshalBinary(bad); err == nil {
+		t.Error("unknown state version accepted")
+	}
+}
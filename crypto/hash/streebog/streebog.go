@@ -17,19 +17,17 @@ func New512() hash.Hash {
 }
 
 func Sum256(data []byte) (sum256 [Size256]byte) {
-	h := New256()
-	h.Write(data)
-	sum := h.Sum(nil)
-
-	copy(sum256[:], sum[:Size256])
+	c := context{hashSize: 256}
+	c.Reset()
+	c.Write(data)
+	c.checkSum(sum256[:])
 	return
 }
 
 func Sum512(data []byte) (sum512 [Size512]byte) {
-	h := New512()
-	h.Write(data)
-	sum := h.Sum(nil)
-
-	copy(sum512[:], sum[:Size512])
+	c := context{hashSize: 512}
+	c.Reset()
+	c.Write(data)
+	c.checkSum(sum512[:])
 	return
 }
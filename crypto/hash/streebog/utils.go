@@ -2,6 +2,7 @@ package streebog
 
 import (
 	"encoding/binary"
+	"math/bits"
 )
 
 func GetU64(ptr []byte) uint64 {
@@ -12,46 +13,23 @@ func PutU64(ptr []byte, a uint64) {
 	binary.LittleEndian.PutUint64(ptr, a)
 }
 
-func bytesToUint64s(b []byte) []uint64 {
-	size := len(b) / 8
-	dst := make([]uint64, size)
-
-	for i := 0; i < size; i++ {
-		j := i * 8
-
-		dst[i] = binary.LittleEndian.Uint64(b[j:])
+func loadBlock(dst *[8]uint64, b *[BlockSize]byte) {
+	for i := 0; i < 8; i++ {
+		dst[i] = binary.LittleEndian.Uint64(b[i*8:])
 	}
-
-	return dst
-}
-
-func uint64sToBytes(w []uint64) []byte {
-	size := len(w) * 8
-	dst := make([]byte, size)
-
-	for i := 0; i < len(w); i++ {
-		j := i * 8
-
-		binary.LittleEndian.PutUint64(dst[j:], w[i])
-	}
-
-	return dst
 }
 
 func lps(block *[8]uint64) {
-	ch := uint64sToBytes(block[:])
+	b := *block
 
-	block[0] = T[0][(ch[0])] ^ T[1][(ch[8+0])] ^ T[2][(ch[16+0])] ^ T[3][(ch[24+0])] ^ T[4][(ch[32+0])] ^ T[5][(ch[40+0])] ^ T[6][(ch[48+0])] ^ T[7][(ch[56+0])]
-	block[1] = T[0][(ch[1])] ^ T[1][(ch[8+1])] ^ T[2][(ch[16+1])] ^ T[3][(ch[24+1])] ^ T[4][(ch[32+1])] ^ T[5][(ch[40+1])] ^ T[6][(ch[48+1])] ^ T[7][(ch[56+1])]
-	block[2] = T[0][(ch[2])] ^ T[1][(ch[8+2])] ^ T[2][(ch[16+2])] ^ T[3][(ch[24+2])] ^ T[4][(ch[32+2])] ^ T[5][(ch[40+2])] ^ T[6][(ch[48+2])] ^ T[7][(ch[56+2])]
-	block[3] = T[0][(ch[3])] ^ T[1][(ch[8+3])] ^ T[2][(ch[16+3])] ^ T[3][(ch[24+3])] ^ T[4][(ch[32+3])] ^ T[5][(ch[40+3])] ^ T[6][(ch[48+3])] ^ T[7][(ch[56+3])]
-	block[4] = T[0][(ch[4])] ^ T[1][(ch[8+4])] ^ T[2][(ch[16+4])] ^ T[3][(ch[24+4])] ^ T[4][(ch[32+4])] ^ T[5][(ch[40+4])] ^ T[6][(ch[48+4])] ^ T[7][(ch[56+4])]
-	block[5] = T[0][(ch[5])] ^ T[1][(ch[8+5])] ^ T[2][(ch[16+5])] ^ T[3][(ch[24+5])] ^ T[4][(ch[32+5])] ^ T[5][(ch[40+5])] ^ T[6][(ch[48+5])] ^ T[7][(ch[56+5])]
-	block[6] = T[0][(ch[6])] ^ T[1][(ch[8+6])] ^ T[2][(ch[16+6])] ^ T[3][(ch[24+6])] ^ T[4][(ch[32+6])] ^ T[5][(ch[40+6])] ^ T[6][(ch[48+6])] ^ T[7][(ch[56+6])]
-	block[7] = T[0][(ch[7])] ^ T[1][(ch[8+7])] ^ T[2][(ch[16+7])] ^ T[3][(ch[24+7])] ^ T[4][(ch[32+7])] ^ T[5][(ch[40+7])] ^ T[6][(ch[48+7])] ^ T[7][(ch[56+7])]
+	for i := 0; i < 8; i++ {
+		s := uint(i * 8)
+		block[i] = T[0][byte(b[0]>>s)] ^ T[1][byte(b[1]>>s)] ^ T[2][byte(b[2]>>s)] ^ T[3][byte(b[3]>>s)] ^
+			T[4][byte(b[4]>>s)] ^ T[5][byte(b[5]>>s)] ^ T[6][byte(b[6]>>s)] ^ T[7][byte(b[7]>>s)]
+	}
 }
 
-func xor(block *[8]uint64, data [8]uint64) {
+func xor(block *[8]uint64, data *[8]uint64) {
 	block[0] ^= data[0]
 	block[1] ^= data[1]
 	block[2] ^= data[2]
@@ -62,61 +40,33 @@ func xor(block *[8]uint64, data [8]uint64) {
 	block[7] ^= data[7]
 }
 
-func encrypt(K *[8]uint64, m []byte) {
-	var tmp, mm [8]uint64
-	copy(tmp[:], K[:])
-
-	ms := bytesToUint64s(m)
-	copy(mm[:], ms)
+func encrypt(K *[8]uint64, m *[8]uint64) {
+	tmp := *K
 
-	xor(K, mm)
+	xor(K, m)
 	for i := 0; i < 12; i++ {
 		lps(K)
-		xor(&tmp, IterConst[i])
+		xor(&tmp, &IterConst[i])
 
 		lps(&tmp)
-		xor(K, tmp)
+		xor(K, &tmp)
 	}
 }
 
-func compress(h *[8]uint64, m []byte, N uint64) {
-	var hN, mm [8]uint64
-	copy(hN[:], h[:])
-
-	ms := bytesToUint64s(m)
-	copy(mm[:], ms)
+func compress(h *[8]uint64, m *[8]uint64, N uint64) {
+	hN := *h
 
 	hN[0] ^= N
 
 	lps(&hN)
 	encrypt(&hN, m)
-	xor(h, hN)
-	xor(h, mm)
+	xor(h, &hN)
+	xor(h, m)
 }
 
-func add(m []byte, h *[8]uint64) {
-	var carry uint64 = 0
-	var overflow bool = false
-	var t uint64
-
-	m64 := bytesToUint64s(m)
-
-	var i int
-	for i = 0; i < 8; i++ {
-		t = h[i] + m64[i]
-
-		if t < h[i] || t < m64[i] {
-			overflow = true
-		} else {
-			overflow = false
-		}
-
-		h[i] = t + carry
-
-		if overflow {
-			carry = 1
-		} else {
-			carry = 0
-		}
+func add(m *[8]uint64, h *[8]uint64) {
+	var carry uint64
+	for i := 0; i < 8; i++ {
+		h[i], carry = bits.Add64(h[i], m[i], carry)
 	}
 }
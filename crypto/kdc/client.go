@@ -0,0 +1,160 @@
+package kdc
+
+import (
+	"bytes"
+	"errors"
+	"gost_magma_cbc/crypto/models"
+	"io"
+	"time"
+)
+
+// Билет с сеансовым ключом, полученные клиентом.
+type Credentials struct {
+	Client   string
+	Server   string
+	Ticket   []byte
+	Key      models.Key
+	AuthTime time.Time
+	EndTime  time.Time
+}
+
+// Зануление сеансового ключа.
+func (c *Credentials) Clear() {
+	if c.Key != nil {
+		c.Key.Clear()
+	}
+}
+
+// Клиент службы распределения ключей с долговременным ключом key.
+type Client struct {
+	config *Config
+	name   string
+	key    models.Key
+}
+
+func NewClient(config *Config, name string, key models.Key) (*Client, error) {
+	if name == "" || key == nil || key.Len() != KEY_SIZE {
+		return nil, errors.New(PREFIX + "invalid client name or key")
+	}
+	if config == nil {
+		config = &Config{}
+	}
+	return &Client{config: config, name: name, key: key}, nil
+}
+
+// Обмен с KDC: запрос и ответ.
+func roundTrip(conn io.ReadWriter, req *Message) (*Message, error) {
+	if err := proto.Write(conn, req); err != nil {
+		return nil, err
+	}
+	return proto.Read(conn)
+}
+
+// Получение билета на сервис server (для TGS_NAME - TGT) у службы
+// аутентификации; lifetime - запрашиваемое время жизни.
+func (c *Client) AS(conn io.ReadWriter, server string, lifetime time.Duration) (*Credentials, error) {
+	nonce, err := c.config.random(NONCE_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	now := c.config.now()
+	pa, err := c.config.seal(c.key, USAGE_PA_TIMESTAMP, putTime(now))
+	if err != nil {
+		return nil, err
+	}
+	rep, err := roundTrip(conn, &Message{Type: MSG_AS_REQ, Fields: [][]byte{
+		[]byte(c.name), []byte(server), nonce, putTime(now.Add(lifetime)), pa}})
+	if err != nil {
+		return nil, err
+	}
+	return c.reply(rep, MSG_AS_REP, c.key, USAGE_AS_REP, server, nonce)
+}
+
+// Получение билета на сервис server по TGT.
+func (c *Client) TGS(conn io.ReadWriter, tgt *Credentials, server string, lifetime time.Duration) (*Credentials, error) {
+	if tgt == nil || tgt.Server != TGS_NAME {
+		return nil, errors.New(PREFIX + "tgt required")
+	}
+	nonce, err := c.config.random(NONCE_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	now := c.config.now()
+	auth, err := c.config.seal(tgt.Key, USAGE_TGS_AUTH, []byte(c.name), putTime(now))
+	if err != nil {
+		return nil, err
+	}
+	rep, err := roundTrip(conn, &Message{Type: MSG_TGS_REQ, Fields: [][]byte{
+		tgt.Ticket, auth, []byte(server), nonce, putTime(now.Add(lifetime))}})
+	if err != nil {
+		return nil, err
+	}
+	return c.reply(rep, MSG_TGS_REP, tgt.Key, USAGE_TGS_REP, server, nonce)
+}
+
+// Проверка ответа KDC: клиент, сервис и nonce должны совпадать с запросом.
+func (c *Client) reply(rep *Message, typ byte, key models.Key, usage byte,
+	server string, nonce []byte) (*Credentials, error) {
+	if err := expect(rep, typ, 3); err != nil {
+		return nil, err
+	}
+	if string(rep.Fields[0]) != c.name {
+		return nil, ErrUnexpected
+	}
+	f, err := open(key, usage, rep.Fields[2], 5)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(f[1], nonce) || string(f[2]) != server {
+		return nil, ErrIntegrity
+	}
+	if name, _, err := ticketServer(rep.Fields[1]); err != nil || name != server {
+		return nil, ErrUnexpected
+	}
+	cred := &Credentials{Client: c.name, Server: server,
+		Ticket: append([]byte(nil), rep.Fields[1]...)}
+	if cred.AuthTime, err = getTime(f[3]); err != nil {
+		return nil, err
+	}
+	if cred.EndTime, err = getTime(f[4]); err != nil {
+		return nil, err
+	}
+	if !cred.EndTime.After(c.config.now()) {
+		return nil, ErrExpired
+	}
+	if cred.Key, err = newKey(f[0]); err != nil {
+		return nil, ErrIntegrity
+	}
+	return cred, nil
+}
+
+// Предъявление билета сервису и взаимная аутентификация.
+func (c *Client) Authenticate(conn io.ReadWriter, cred *Credentials) error {
+	if cred == nil || cred.Client != c.name {
+		return errors.New(PREFIX + "credentials of another client")
+	}
+	now := c.config.now()
+	if now.After(cred.EndTime) {
+		return ErrExpired
+	}
+	ts := putTime(now)
+	auth, err := c.config.seal(cred.Key, USAGE_AP_AUTH, []byte(c.name), ts)
+	if err != nil {
+		return err
+	}
+	rep, err := roundTrip(conn, &Message{Type: MSG_AP_REQ, Fields: [][]byte{cred.Ticket, auth}})
+	if err != nil {
+		return err
+	}
+	if err := expect(rep, MSG_AP_REP, 1); err != nil {
+		return err
+	}
+	f, err := open(cred.Key, USAGE_AP_REP, rep.Fields[0], 1)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(f[0], ts) {
+		return ErrIntegrity
+	}
+	return nil
+}
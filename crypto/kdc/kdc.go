@@ -0,0 +1,238 @@
+package kdc
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/manage"
+	"gost_magma_cbc/crypto/models"
+	"gost_magma_cbc/crypto/protocol"
+	"io"
+	"sync"
+	"time"
+)
+
+// Доверенный сервер: служба аутентификации (AS) и выдачи билетов (TGS).
+type KDC struct {
+	config *Config
+	km     *manage.KeysManager
+	keys   map[string]models.Key
+	cache  *protocol.ReplayCache
+	mtx    sync.RWMutex
+}
+
+// Создание KDC со случайным ключом TGS_NAME.
+func NewKDC(config *Config) (*KDC, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	k := &KDC{
+		config: config,
+		km:     manage.NewKeysManager(0),
+		keys:   map[string]models.Key{},
+		cache:  protocol.NewReplayCache(2 * config.skew()),
+	}
+	b, err := config.random(KEY_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(b)
+	if err := k.AddPrincipal(TGS_NAME, &manage.BuildData{Bytes: &b}, manage.BuildFromBytes); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Регистрация участника с долговременным ключом, выработанным
+// менеджером ключей.
+func (k *KDC) AddPrincipal(name string, data *manage.BuildData, method manage.BuildMethod) error {
+	if name == "" {
+		return errors.New(PREFIX + "empty principal name")
+	}
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	if _, ok := k.keys[name]; ok {
+		return errors.New(PREFIX + "principal already exists")
+	}
+	key, err := k.km.GetNextKey(kuznyechik.NewKuznyechik(), data, method)
+	if err != nil {
+		return err
+	}
+	k.keys[name] = key
+	return nil
+}
+
+// Удаление участника и зануление его ключа.
+func (k *KDC) RemovePrincipal(name string) error {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	key, ok := k.keys[name]
+	if !ok {
+		return ErrUnknownPrincipal
+	}
+	delete(k.keys, name)
+	return k.km.Clear(key)
+}
+
+// Копия ключа участника. Хранимый ключ может быть занулён RemovePrincipal
+// во время обработки запроса, поэтому обработчики работают с копией и
+// зануляют её сами.
+func (k *KDC) key(name string) (models.Key, error) {
+	k.mtx.RLock()
+	defer k.mtx.RUnlock()
+	key, ok := k.keys[name]
+	if !ok {
+		return nil, ErrUnknownPrincipal
+	}
+	return newKey(key.Data())
+}
+
+// Обработка запроса; при ошибке возвращается MSG_ERROR.
+func (k *KDC) Handle(req *Message) *Message {
+	var rep *Message
+	var err error
+	switch {
+	case req == nil:
+		err = ErrUnexpected
+	case req.Type == MSG_AS_REQ:
+		rep, err = k.as(req)
+	case req.Type == MSG_TGS_REQ:
+		rep, err = k.tgs(req)
+	default:
+		err = ErrUnexpected
+	}
+	if err != nil {
+		return errorMessage(err)
+	}
+	return rep
+}
+
+// Обслуживание запросов по соединению до его закрытия.
+func (k *KDC) Serve(conn io.ReadWriter) error {
+	for {
+		req, err := proto.Read(conn)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := proto.Write(conn, k.Handle(req)); err != nil {
+			return err
+		}
+	}
+}
+
+// Время окончания действия билета.
+func (k *KDC) endTime(now time.Time, till []byte, limit time.Time) (time.Time, error) {
+	end, err := getTime(till)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if max := now.Add(k.config.lifetime()); end.After(max) {
+		end = max
+	}
+	if !limit.IsZero() && end.After(limit) {
+		end = limit
+	}
+	if !end.After(now) {
+		return time.Time{}, ErrExpired
+	}
+	return end, nil
+}
+
+// Выдача билета и зашифрованной части ответа {K_s, nonce, сервис,
+// authtime, endtime} на ключе replyKey.
+func (k *KDC) issue(typ byte, client, server string, nonce []byte, authTime, endTime time.Time,
+	replyKey models.Key, usage byte) (*Message, error) {
+	serverKey, err := k.key(server)
+	if err != nil {
+		return nil, err
+	}
+	defer serverKey.Clear()
+	sk, err := k.config.random(KEY_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(sk)
+	ticket, err := k.config.sealTicket(serverKey, &Ticket{Server: server, Client: client,
+		SessionKey: sk, AuthTime: authTime, EndTime: endTime})
+	if err != nil {
+		return nil, err
+	}
+	enc, err := k.config.seal(replyKey, usage, sk, nonce, []byte(server),
+		putTime(authTime), putTime(endTime))
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Type: typ, Fields: [][]byte{[]byte(client), ticket, enc}}, nil
+}
+
+// AS_REQ: клиент, сервис, nonce, till, {ts}K_c.
+func (k *KDC) as(req *Message) (*Message, error) {
+	if err := expect(req, MSG_AS_REQ, 5); err != nil {
+		return nil, err
+	}
+	client, server, nonce := string(req.Fields[0]), string(req.Fields[1]), req.Fields[2]
+	if len(nonce) != NONCE_SIZE {
+		return nil, ErrUnexpected
+	}
+	clientKey, err := k.key(client)
+	if err != nil {
+		return nil, err
+	}
+	defer clientKey.Clear()
+	// Предварительная аутентификация зашифрованной меткой времени
+	pa, err := open(clientKey, USAGE_PA_TIMESTAMP, req.Fields[4], 1)
+	if err != nil {
+		return nil, ErrPreauth
+	}
+	ts, err := getTime(pa[0])
+	if err != nil {
+		return nil, ErrPreauth
+	}
+	if err := k.config.checkSkew(ts); err != nil {
+		return nil, err
+	}
+	now := k.config.now()
+	end, err := k.endTime(now, req.Fields[3], time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return k.issue(MSG_AS_REP, client, server, nonce, now, end, clientKey, USAGE_AS_REP)
+}
+
+// TGS_REQ: TGT, {клиент, ts}K_s, сервис, nonce, till.
+func (k *KDC) tgs(req *Message) (*Message, error) {
+	if err := expect(req, MSG_TGS_REQ, 5); err != nil {
+		return nil, err
+	}
+	server, nonce := string(req.Fields[2]), req.Fields[3]
+	if len(nonce) != NONCE_SIZE {
+		return nil, ErrUnexpected
+	}
+	tgsKey, err := k.key(TGS_NAME)
+	if err != nil {
+		return nil, err
+	}
+	defer tgsKey.Clear()
+	tgt, err := k.config.openTicket(TGS_NAME, tgsKey, req.Fields[0])
+	if err != nil {
+		return nil, err
+	}
+	sk, err := newKey(tgt.SessionKey)
+	if err != nil {
+		return nil, err
+	}
+	defer sk.Clear()
+	if _, err := k.config.checkAuthenticator(sk, USAGE_TGS_AUTH, req.Fields[1], tgt.Client, k.cache); err != nil {
+		return nil, err
+	}
+	if server == TGS_NAME {
+		return nil, ErrUnexpected
+	}
+	end, err := k.endTime(k.config.now(), req.Fields[4], tgt.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	return k.issue(MSG_TGS_REP, tgt.Client, server, nonce, tgt.AuthTime, end, sk, USAGE_TGS_REP)
+}
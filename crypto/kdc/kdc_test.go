@@ -0,0 +1,282 @@
+package kdc
+
+import (
+	"bytes"
+	"errors"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/manage"
+	"gost_magma_cbc/crypto/models"
+	"net"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+type env struct {
+	clk     *clock
+	kdc     *KDC
+	client  *Client
+	service *Service
+}
+
+// Долговременный ключ участника из его менеджера ключей.
+func principalKey(t *testing.T, b []byte) models.Key {
+	t.Helper()
+	km := manage.NewKeysManager(0)
+	key, err := km.GetNextKey(kuznyechik.NewKuznyechik(), &manage.BuildData{Bytes: &b}, manage.BuildFromBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newEnv(t *testing.T) *env {
+	t.Helper()
+	e := &env{clk: &clock{t: time.Unix(1700000000, 0)}}
+	kdc, err := NewKDC(&Config{Now: e.clk.now, MaxLifetime: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.kdc = kdc
+	alice := bytes.Repeat([]byte{0x11}, KEY_SIZE)
+	files := bytes.Repeat([]byte{0x22}, KEY_SIZE)
+	if err := kdc.AddPrincipal("alice", &manage.BuildData{Bytes: &alice}, manage.BuildFromBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := kdc.AddPrincipal("files", &manage.BuildData{Bytes: &files}, manage.BuildFromBytes); err != nil {
+		t.Fatal(err)
+	}
+	if e.client, err = NewClient(&Config{Now: e.clk.now}, "alice", principalKey(t, alice)); err != nil {
+		t.Fatal(err)
+	}
+	if e.service, err = NewService(&Config{Now: e.clk.now}, "files", principalKey(t, files)); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// Соединение с KDC, обслуживаемое в отдельной горутине.
+func (e *env) dialKDC(t *testing.T) net.Conn {
+	ca, cb := net.Pipe()
+	go func() {
+		e.kdc.Serve(cb)
+		cb.Close()
+	}()
+	t.Cleanup(func() { ca.Close() })
+	return ca
+}
+
+// Предъявление билета сервису через net.Pipe.
+func (e *env) authenticate(c *Client, cred *Credentials) (error, *Session, error) {
+	ca, cb := net.Pipe()
+	defer ca.Close()
+	defer cb.Close()
+	type result struct {
+		s   *Session
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		s, err := e.service.Accept(cb)
+		done <- result{s, err}
+	}()
+	cerr := c.Authenticate(ca, cred)
+	r := <-done
+	return cerr, r.s, r.err
+}
+
+func TestKerberos(t *testing.T) {
+	e := newEnv(t)
+	conn := e.dialKDC(t)
+
+	tgt, err := e.client.AS(conn, TGS_NAME, 8*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tgt.Server != TGS_NAME || !tgt.EndTime.Equal(e.clk.t.Add(time.Hour)) {
+		t.Errorf("incorrect tgt %s, %v", tgt.Server, tgt.EndTime)
+	}
+
+	e.clk.t = e.clk.t.Add(10 * time.Minute)
+	cred, err := e.client.TGS(conn, tgt, "files", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Срок действия билета не превышает срока TGT
+	if !cred.EndTime.Equal(tgt.EndTime) || !cred.AuthTime.Equal(tgt.AuthTime) {
+		t.Errorf("incorrect ticket times %v, %v", cred.AuthTime, cred.EndTime)
+	}
+
+	cerr, sess, serr := e.authenticate(e.client, cred)
+	if cerr != nil || serr != nil {
+		t.Fatal(cerr, serr)
+	}
+	if sess.Client != "alice" || !bytes.Equal(sess.Key.Data(), cred.Key.Data()) {
+		t.Error("session keys differ")
+	}
+	cred.Clear()
+	tgt.Clear()
+}
+
+// Получение билета на сервис непосредственно у AS (Нидхем-Шрёдер).
+func TestDirectTicket(t *testing.T) {
+	e := newEnv(t)
+	cred, err := e.client.AS(e.dialKDC(t), "files", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cerr, _, serr := e.authenticate(e.client, cred); cerr != nil || serr != nil {
+		t.Fatal(cerr, serr)
+	}
+}
+
+func TestPreauth(t *testing.T) {
+	e := newEnv(t)
+	conn := e.dialKDC(t)
+	mallory, _ := NewClient(&Config{Now: e.clk.now}, "alice",
+		principalKey(t, bytes.Repeat([]byte{0x33}, KEY_SIZE)))
+	if _, err := mallory.AS(conn, TGS_NAME, time.Hour); !errors.Is(err, ErrPreauth) {
+		t.Errorf("wrong key: %v", err)
+	}
+	bob, _ := NewClient(&Config{Now: e.clk.now}, "bob", principalKey(t, bytes.Repeat([]byte{0x11}, KEY_SIZE)))
+	if _, err := bob.AS(conn, TGS_NAME, time.Hour); !errors.Is(err, ErrUnknownPrincipal) {
+		t.Errorf("unknown client: %v", err)
+	}
+	if _, err := e.client.AS(conn, "printer", time.Hour); !errors.Is(err, ErrUnknownPrincipal) {
+		t.Errorf("unknown service: %v", err)
+	}
+	// Часы клиента расходятся с часами KDC
+	skewed, _ := NewClient(&Config{Now: func() time.Time { return e.clk.t.Add(-10 * time.Minute) }},
+		"alice", e.client.key)
+	if _, err := skewed.AS(conn, TGS_NAME, time.Hour); !errors.Is(err, ErrSkew) {
+		t.Errorf("skewed clock: %v", err)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	e := newEnv(t)
+	conn := e.dialKDC(t)
+	tgt, err := e.client.AS(conn, TGS_NAME, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := e.client.TGS(conn, tgt, "files", 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.clk.t = e.clk.t.Add(20 * time.Minute)
+	if err := e.client.Authenticate(conn, cred); !errors.Is(err, ErrExpired) {
+		t.Errorf("client accepted expired ticket: %v", err)
+	}
+	// Сервис проверяет срок действия независимо от клиента
+	cred.EndTime = e.clk.t.Add(time.Hour)
+	if _, _, serr := e.authenticate(e.client, cred); !errors.Is(serr, ErrExpired) {
+		t.Errorf("service accepted expired ticket: %v", serr)
+	}
+
+	e.clk.t = e.clk.t.Add(time.Hour)
+	if _, err := e.client.TGS(conn, tgt, "files", time.Hour); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired tgt: %v", err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	e := newEnv(t)
+	cred, err := e.client.AS(e.dialKDC(t), "files", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth, _ := e.client.config.seal(cred.Key, USAGE_AP_AUTH, []byte("alice"), putTime(e.clk.t))
+	req := &Message{Type: MSG_AP_REQ, Fields: [][]byte{cred.Ticket, auth}}
+	if _, _, err := e.service.Verify(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, rep, err := e.service.Verify(req); !errors.Is(err, ErrReplay) || rep.Type != MSG_ERROR {
+		t.Errorf("replayed authenticator: %v", err)
+	}
+	// Аутентификатор, вышедший за допустимое расхождение
+	e.clk.t = e.clk.t.Add(10 * time.Minute)
+	if _, _, err := e.service.Verify(req); !errors.Is(err, ErrSkew) {
+		t.Errorf("old authenticator: %v", err)
+	}
+	// Аутентификатор другого клиента
+	auth, _ = e.client.config.seal(cred.Key, USAGE_AP_AUTH, []byte("bob"), putTime(e.clk.t))
+	req.Fields[1] = auth
+	if _, _, err := e.service.Verify(req); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("foreign authenticator: %v", err)
+	}
+}
+
+func TestTampered(t *testing.T) {
+	e := newEnv(t)
+	conn := e.dialKDC(t)
+	cred, err := e.client.AS(conn, "files", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket := cred.Ticket
+	cred.Ticket = append([]byte(nil), ticket...)
+	cred.Ticket[len(cred.Ticket)-1] ^= 1
+	if cerr, _, serr := e.authenticate(e.client, cred); !errors.Is(serr, ErrIntegrity) || !errors.Is(cerr, ErrIntegrity) {
+		t.Errorf("tampered ticket: %v, %v", cerr, serr)
+	}
+
+	// Билет, выданный для другого сервиса
+	tgt, err := e.client.AS(conn, TGS_NAME, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, serr := e.authenticate(e.client, tgt); !errors.Is(serr, ErrUnknownPrincipal) {
+		t.Errorf("ticket for another service: %v", serr)
+	}
+	// Билет на сервис вместо TGT
+	cred.Ticket, cred.Server = ticket, TGS_NAME
+	if _, err := e.client.TGS(conn, cred, "files", time.Hour); !errors.Is(err, ErrUnknownPrincipal) {
+		t.Errorf("service ticket as tgt: %v", err)
+	}
+}
+
+func TestPrincipals(t *testing.T) {
+	e := newEnv(t)
+	b := bytes.Repeat([]byte{0x44}, KEY_SIZE)
+	if err := e.kdc.AddPrincipal("files", &manage.BuildData{Bytes: &b}, manage.BuildFromBytes); err == nil {
+		t.Error("duplicate principal accepted")
+	}
+	if err := e.kdc.AddPrincipal("short", &manage.BuildData{Bytes: &[]byte{1}}, manage.BuildFromBytes); err == nil {
+		t.Error("short key accepted")
+	}
+	if err := e.kdc.RemovePrincipal("files"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.client.AS(e.dialKDC(t), "files", time.Hour); !errors.Is(err, ErrUnknownPrincipal) {
+		t.Errorf("removed principal: %v", err)
+	}
+	if err := e.kdc.RemovePrincipal("files"); !errors.Is(err, ErrUnknownPrincipal) {
+		t.Errorf("second remove: %v", err)
+	}
+}
+
+// Удаление участника не зануляет ключ, с которым работает обработчик.
+func TestRemoveInFlight(t *testing.T) {
+	e := newEnv(t)
+	key, err := e.kdc.key("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bytes.Clone(key.Data())
+	if err := e.kdc.RemovePrincipal("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key.Data(), want) {
+		t.Error("key in use was cleared")
+	}
+	key.Clear()
+}
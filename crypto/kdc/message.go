@@ -0,0 +1,94 @@
+package kdc
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/protocol"
+)
+
+// Типы сообщений.
+const (
+	MSG_AS_REQ  byte = 1 // клиент, сервис, nonce, till, padata
+	MSG_AS_REP  byte = 2 // клиент, билет, {K_s, nonce, ...}K_c
+	MSG_TGS_REQ byte = 3 // TGT, аутентификатор, сервис, nonce, till
+	MSG_TGS_REP byte = 4 // клиент, билет, {K_s, nonce, ...}K_tgs
+	MSG_AP_REQ  byte = 5 // билет, аутентификатор
+	MSG_AP_REP  byte = 6 // {ts}K_s
+	MSG_ERROR   byte = 7 // код ошибки
+	MSG_TICKET  byte = 8 // сервис, {билет}K_v
+)
+
+// Коды ошибок MSG_ERROR.
+const (
+	ERR_GENERIC           byte = 1
+	ERR_PRINCIPAL_UNKNOWN byte = 2
+	ERR_PREAUTH_FAILED    byte = 3
+	ERR_INTEGRITY         byte = 4
+	ERR_SKEW              byte = 5
+	ERR_TKT_EXPIRED       byte = 6
+	ERR_REPLAY            byte = 7
+	ERR_BAD_REQUEST       byte = 8
+)
+
+// Максимальная длина кадра сообщения.
+const MAX_MESSAGE_SIZE = 4096
+
+type Message = protocol.Message
+
+var proto = &protocol.Protocol{
+	MaxSize:       MAX_MESSAGE_SIZE,
+	Abort:         MSG_ERROR,
+	ErrAbort:      ErrFailed,
+	ErrUnexpected: ErrUnexpected,
+}
+
+func errorMessage(err error) *Message {
+	code := ERR_GENERIC
+	switch {
+	case errors.Is(err, ErrUnknownPrincipal):
+		code = ERR_PRINCIPAL_UNKNOWN
+	case errors.Is(err, ErrPreauth):
+		code = ERR_PREAUTH_FAILED
+	case errors.Is(err, ErrIntegrity):
+		code = ERR_INTEGRITY
+	case errors.Is(err, ErrSkew):
+		code = ERR_SKEW
+	case errors.Is(err, ErrExpired):
+		code = ERR_TKT_EXPIRED
+	case errors.Is(err, ErrReplay):
+		code = ERR_REPLAY
+	case errors.Is(err, ErrUnexpected):
+		code = ERR_BAD_REQUEST
+	}
+	return &Message{Type: MSG_ERROR, Fields: [][]byte{{code}}}
+}
+
+func codeError(code byte) error {
+	switch code {
+	case ERR_PRINCIPAL_UNKNOWN:
+		return ErrUnknownPrincipal
+	case ERR_PREAUTH_FAILED:
+		return ErrPreauth
+	case ERR_INTEGRITY:
+		return ErrIntegrity
+	case ERR_SKEW:
+		return ErrSkew
+	case ERR_TKT_EXPIRED:
+		return ErrExpired
+	case ERR_REPLAY:
+		return ErrReplay
+	case ERR_BAD_REQUEST:
+		return ErrUnexpected
+	}
+	return ErrFailed
+}
+
+// Проверка типа и числа полей; MSG_ERROR преобразуется в ошибку по коду.
+func expect(m *Message, typ byte, fields int) error {
+	if m != nil && m.Type == MSG_ERROR {
+		if len(m.Fields) != 1 || len(m.Fields[0]) != 1 {
+			return ErrFailed
+		}
+		return codeError(m.Fields[0][0])
+	}
+	return proto.Expect(m, typ, fields)
+}
@@ -0,0 +1,84 @@
+package kdc
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/models"
+	"gost_magma_cbc/crypto/protocol"
+	"io"
+	"time"
+)
+
+// Сеанс, установленный сервисом по предъявленному билету.
+type Session struct {
+	Client  string
+	Key     models.Key
+	EndTime time.Time
+}
+
+// Сервис, проверяющий билеты на своём долговременном ключе.
+type Service struct {
+	config *Config
+	name   string
+	key    models.Key
+	cache  *protocol.ReplayCache
+}
+
+func NewService(config *Config, name string, key models.Key) (*Service, error) {
+	if name == "" || key == nil || key.Len() != KEY_SIZE {
+		return nil, errors.New(PREFIX + "invalid service name or key")
+	}
+	if config == nil {
+		config = &Config{}
+	}
+	return &Service{config: config, name: name, key: key, cache: protocol.NewReplayCache(2 * config.skew())}, nil
+}
+
+// Проверка AP_REQ; возвращает сеанс и ответ AP_REP или MSG_ERROR.
+func (s *Service) Verify(req *Message) (*Session, *Message, error) {
+	sess, rep, err := s.verify(req)
+	if err != nil {
+		return nil, errorMessage(err), err
+	}
+	return sess, rep, nil
+}
+
+func (s *Service) verify(req *Message) (*Session, *Message, error) {
+	if err := expect(req, MSG_AP_REQ, 2); err != nil {
+		return nil, nil, err
+	}
+	t, err := s.config.openTicket(s.name, s.key, req.Fields[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(t.SessionKey)
+	sk, err := newKey(t.SessionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	ts, err := s.config.checkAuthenticator(sk, USAGE_AP_AUTH, req.Fields[1], t.Client, s.cache)
+	if err != nil {
+		sk.Clear()
+		return nil, nil, err
+	}
+	enc, err := s.config.seal(sk, USAGE_AP_REP, putTime(ts))
+	if err != nil {
+		sk.Clear()
+		return nil, nil, err
+	}
+	return &Session{Client: t.Client, Key: sk, EndTime: t.EndTime},
+		&Message{Type: MSG_AP_REP, Fields: [][]byte{enc}}, nil
+}
+
+// Приём AP_REQ по соединению и отправка ответа.
+func (s *Service) Accept(conn io.ReadWriter) (*Session, error) {
+	req, err := proto.Read(conn)
+	if err != nil {
+		return nil, err
+	}
+	sess, rep, err := s.Verify(req)
+	if werr := proto.Write(conn, rep); werr != nil && err == nil {
+		sess.Key.Clear()
+		return nil, werr
+	}
+	return sess, err
+}
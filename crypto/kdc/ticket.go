@@ -0,0 +1,257 @@
+package kdc
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/mode"
+	"gost_magma_cbc/crypto/models"
+	"gost_magma_cbc/crypto/protocol"
+	"io"
+	"time"
+)
+
+// Служба распределения ключей по схеме Нидхема-Шрёдера/Kerberos.
+// Доверенный сервер (KDC) хранит долговременные ключи участников и
+// выдаёт билеты {K_s, клиент, сервис, authtime, endtime}K_v, зашифрованные
+// на ключе сервиса. Все зашифрованные части защищаются режимом MGM
+// на Кузнечике: nonce(16) || C || MAC(16), дополнительные данные -
+// номер назначения (usage), который также является типом вложенного
+// сообщения.
+
+const PREFIX = "crypto:kdc: "
+
+// Имя участника службы выдачи билетов (TGS).
+const TGS_NAME = "krbtgt"
+
+// Длина сеансового и долговременного ключей.
+const KEY_SIZE = 32
+
+// Длина nonce запросов AS и TGS.
+const NONCE_SIZE = 8
+
+const (
+	DEFAULT_MAX_SKEW        = 5 * time.Minute
+	DEFAULT_TICKET_LIFETIME = 10 * time.Hour
+)
+
+// Назначения зашифрованных частей.
+const (
+	USAGE_PA_TIMESTAMP byte = 1
+	USAGE_TICKET       byte = 2
+	USAGE_AS_REP       byte = 3
+	USAGE_TGS_AUTH     byte = 4
+	USAGE_TGS_REP      byte = 5
+	USAGE_AP_AUTH      byte = 6
+	USAGE_AP_REP       byte = 7
+)
+
+var (
+	ErrUnknownPrincipal = errors.New(PREFIX + "unknown principal")
+	ErrPreauth          = errors.New(PREFIX + "pre-authentication failed")
+	ErrIntegrity        = errors.New(PREFIX + "integrity check failed")
+	ErrSkew             = errors.New(PREFIX + "clock skew too great")
+	ErrExpired          = errors.New(PREFIX + "ticket expired")
+	ErrReplay           = errors.New(PREFIX + "replayed authenticator")
+	ErrUnexpected       = errors.New(PREFIX + "unexpected message")
+	ErrFailed           = errors.New(PREFIX + "request failed")
+)
+
+// Общие параметры KDC, клиента и сервиса.
+type Config struct {
+	// Источник случайных чисел; при nil используется hdrbg.Reader.
+	Rand io.Reader
+	// Часы; при nil используется time.Now.
+	Now func() time.Time
+	// Допустимое расхождение часов.
+	MaxSkew time.Duration
+	// Максимальное время жизни билета (для KDC).
+	MaxLifetime time.Duration
+}
+
+func (c *Config) now() time.Time {
+	if c != nil && c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func (c *Config) skew() time.Duration {
+	if c != nil && c.MaxSkew > 0 {
+		return c.MaxSkew
+	}
+	return DEFAULT_MAX_SKEW
+}
+
+func (c *Config) lifetime() time.Duration {
+	if c != nil && c.MaxLifetime > 0 {
+		return c.MaxLifetime
+	}
+	return DEFAULT_TICKET_LIFETIME
+}
+
+func (c *Config) random(n int) ([]byte, error) {
+	var rand io.Reader = hdrbg.Reader
+	if c != nil && c.Rand != nil {
+		rand = c.Rand
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(rand, b)
+	return b, err
+}
+
+// Расхождение t с текущим временем не больше допустимого.
+func (c *Config) checkSkew(t time.Time) error {
+	d := c.now().Sub(t)
+	if d > c.skew() || d < -c.skew() {
+		return ErrSkew
+	}
+	return nil
+}
+
+func putTime(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano()))
+}
+
+func getTime(b []byte) (time.Time, error) {
+	if len(b) != 8 {
+		return time.Time{}, ErrUnexpected
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b))), nil
+}
+
+// Ключ Кузнечика из строки байт.
+func newKey(b []byte) (models.Key, error) {
+	key := kuznyechik.NewKuznyechikKey()
+	if len(b) != key.Len() {
+		return nil, errors.New(PREFIX + "invalid key len")
+	}
+	subtle.ConstantTimeCopy(1, key.Data(), b)
+	return key, nil
+}
+
+// Зашифрование полей на ключе key с назначением usage.
+func (c *Config) seal(key models.Key, usage byte, fields ...[]byte) ([]byte, error) {
+	aead, err := mode.NewMGM(kuznyechik.NewKuznyechik(), key, 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := c.random(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	nonce[0] &= 0x7f
+	pt := (&Message{Type: usage, Fields: fields}).Marshal()
+	defer clear(pt)
+	return aead.Seal(nonce, nonce, pt, []byte{usage}), nil
+}
+
+// Расшифрование и проверка части с назначением usage и n полями.
+func open(key models.Key, usage byte, data []byte, n int) ([][]byte, error) {
+	aead, err := mode.NewMGM(kuznyechik.NewKuznyechik(), key, 16)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize()+aead.Overhead() || data[0]&0x80 != 0 {
+		return nil, ErrIntegrity
+	}
+	ns := aead.NonceSize()
+	pt, err := aead.Open(nil, data[:ns], data[ns:], []byte{usage})
+	if err != nil {
+		return nil, ErrIntegrity
+	}
+	m, err := protocol.Unmarshal(pt)
+	if err != nil || m.Type != usage || len(m.Fields) != n {
+		return nil, ErrIntegrity
+	}
+	return m.Fields, nil
+}
+
+// Содержимое билета.
+type Ticket struct {
+	Server     string
+	Client     string
+	SessionKey []byte
+	AuthTime   time.Time
+	EndTime    time.Time
+}
+
+// Зашифрованный билет: MSG_TICKET{сервис, {K_s, клиент, authtime, endtime}K_v}.
+func (c *Config) sealTicket(key models.Key, t *Ticket) ([]byte, error) {
+	enc, err := c.seal(key, USAGE_TICKET, t.SessionKey, []byte(t.Client),
+		putTime(t.AuthTime), putTime(t.EndTime))
+	if err != nil {
+		return nil, err
+	}
+	return (&Message{Type: MSG_TICKET, Fields: [][]byte{[]byte(t.Server), enc}}).Marshal(), nil
+}
+
+// Имя сервиса из зашифрованного билета.
+func ticketServer(b []byte) (string, []byte, error) {
+	m, err := protocol.Unmarshal(b)
+	if err != nil {
+		return "", nil, ErrUnexpected
+	}
+	if err := expect(m, MSG_TICKET, 2); err != nil {
+		return "", nil, ErrUnexpected
+	}
+	return string(m.Fields[0]), m.Fields[1], nil
+}
+
+// Расшифрование билета на ключе сервиса и проверка срока действия.
+func (c *Config) openTicket(server string, key models.Key, b []byte) (*Ticket, error) {
+	name, enc, err := ticketServer(b)
+	if err != nil {
+		return nil, err
+	}
+	if name != server {
+		return nil, ErrUnknownPrincipal
+	}
+	f, err := open(key, USAGE_TICKET, enc, 4)
+	if err != nil {
+		return nil, err
+	}
+	t := &Ticket{Server: name, Client: string(f[1]), SessionKey: f[0]}
+	if t.AuthTime, err = getTime(f[2]); err != nil {
+		return nil, err
+	}
+	if t.EndTime, err = getTime(f[3]); err != nil {
+		return nil, err
+	}
+	if len(t.SessionKey) != KEY_SIZE {
+		return nil, ErrIntegrity
+	}
+	now := c.now()
+	if now.After(t.EndTime.Add(c.skew())) {
+		return nil, ErrExpired
+	}
+	if now.Before(t.AuthTime.Add(-c.skew())) {
+		return nil, ErrSkew
+	}
+	return t, nil
+}
+
+// Аутентификатор {клиент, ts}K_s; повтор отклоняется по кэшу.
+func (c *Config) checkAuthenticator(key models.Key, usage byte, data []byte,
+	client string, cache *protocol.ReplayCache) (time.Time, error) {
+	f, err := open(key, usage, data, 2)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if string(f[0]) != client {
+		return time.Time{}, ErrIntegrity
+	}
+	ts, err := getTime(f[1])
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := c.checkSkew(ts); err != nil {
+		return time.Time{}, err
+	}
+	if !cache.Add(append([]byte(client+"\x00"), f[1]...), c.now()) {
+		return time.Time{}, ErrReplay
+	}
+	return ts, nil
+}
@@ -1,7 +1,11 @@
 package kdf
 
 import (
+	"bytes"
+	"crypto/sha1"
 	"crypto/subtle"
+	"encoding/hex"
+	"gost_magma_cbc/crypto/hash/streebog"
 	"testing"
 )
 
@@ -28,4 +32,39 @@ func Test_KDF256(t *testing.T) {
 	if subtle.ConstantTimeCompare(s, data_t) != 1 {
 		t.Error("result not equal true data")
 	}
-}
\ No newline at end of file
+}
+
+func Test_PBKDF2(t *testing.T) {
+	// RFC 6070
+	tests := []struct {
+		iter int
+		want string
+	}{
+		{1, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{2, "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{4096, "4b007901b765489abead49d926f721d065a429c1"},
+	}
+	for _, tt := range tests {
+		dk := PBKDF2(sha1.New, []byte("password"), []byte("salt"), tt.iter, 20)
+		if hex.EncodeToString(dk) != tt.want {
+			t.Errorf("[%d] incorrect derived key %x", tt.iter, dk)
+		}
+	}
+	dk := PBKDF2(sha1.New, []byte("passwordPASSWORDpassword"),
+		[]byte("saltSALTsaltSALTsaltSALTsaltSALTsalt"), 4096, 25)
+	if hex.EncodeToString(dk) != "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038" {
+		t.Errorf("incorrect derived key %x", dk)
+	}
+
+	// Р 50.1.111-2016, HMAC_GOSTR3411_2012_512
+	dk = PBKDF2(streebog.New512, []byte("password"), []byte("salt"), 1, 64)
+	if hex.EncodeToString(dk) != "64770af7f748c3b1c9ac831dbcfd85c26111b30a8a657ddc3056b80ca73e040d"+
+		"2854fd36811f6d825cc4ab66ec0a68a490a9e5cf5156b3a2b7eecddbf9a16b47" {
+		t.Errorf("incorrect streebog derived key %x", dk)
+	}
+
+	long := PBKDF2(streebog.New512, []byte("password"), []byte("salt"), 2, 100)
+	if len(long) != 100 || !bytes.Equal(long[:64], PBKDF2(streebog.New512, []byte("password"), []byte("salt"), 2, 64)) {
+		t.Error("incorrect multi-block output")
+	}
+}
@@ -0,0 +1,38 @@
+package kdf
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"gost_magma_cbc/crypto/hash/hmac"
+	"hash"
+)
+
+// PBKDF2 (Р 50.1.111-2016, RFC 8018) с HMAC над хэш-функцией newHash:
+// выработка keyLen байт из пароля и соли за iter итераций.
+func PBKDF2(newHash func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(newHash, password)
+	hLen := prf.Size()
+	res := make([]byte, 0, (keyLen+hLen-1)/hLen*hLen)
+	var idx [4]byte
+	u := make([]byte, 0, hLen)
+	t := make([]byte, hLen)
+	for block := uint32(1); len(res) < keyLen; block++ {
+		// U_1 = PRF(P, S || INT(i)), T_i = U_1 ^ U_2 ^ ... ^ U_c
+		binary.BigEndian.PutUint32(idx[:], block)
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(idx[:])
+		u = prf.Sum(u[:0])
+		copy(t, u)
+		for n := 1; n < iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			subtle.XORBytes(t, t, u)
+		}
+		res = append(res, t...)
+	}
+	clear(u)
+	clear(t)
+	return res[:keyLen]
+}
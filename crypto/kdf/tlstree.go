@@ -0,0 +1,116 @@
+package kdf
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/models"
+	"sync"
+)
+
+// Маски C1, C2, C3 функции TLSTREE (Р 1323565.1.043).
+type TLSTreeParams struct {
+	C1 uint64
+	C2 uint64
+	C3 uint64
+}
+
+var (
+	TLSTreeMagma = TLSTreeParams{
+		C1: 0xFFFFFFC000000000,
+		C2: 0xFFFFFFFFFE000000,
+		C3: 0xFFFFFFFFFFFFF000,
+	}
+	TLSTreeKuznyechik = TLSTreeParams{
+		C1: 0xFFFFFFFF00000000,
+		C2: 0xFFFFFFFFFFF80000,
+		C3: 0xFFFFFFFFFFFFFFC0,
+	}
+)
+
+var tlsTreeLabels = [3][]byte{
+	[]byte("level1"),
+	[]byte("level2"),
+	[]byte("level3"),
+}
+
+type tlsTreeLevel struct {
+	valid bool
+	index uint64
+	key   []byte
+}
+
+// Выработка ключей записей по корневому ключу и номеру записи:
+// TLSTREE(K, i) = KDF_3(KDF_2(KDF_1(K, STR_8(i & C1)), STR_8(i & C2)), STR_8(i & C3)),
+// где KDF_j(K, D) = KDF_GOSTR3411_2012_256(K, "level" || j, D).
+// Промежуточные ключи сохраняются и используются повторно, пока номер
+// записи остаётся в том же диапазоне маски.
+type TLSTree struct {
+	params TLSTreeParams
+	kdf    models.KDF
+	root   []byte
+	levels [3]tlsTreeLevel
+	mtx    sync.Mutex
+}
+
+func NewTLSTree(params TLSTreeParams, root []byte) (*TLSTree, error) {
+	if len(root) != 32 {
+		return nil, errors.New("tlstree: root key must be 32 bytes")
+	}
+	t := &TLSTree{params: params, kdf: NewKDF256()}
+	t.root = make([]byte, len(root))
+	subtle.ConstantTimeCopy(1, t.root, root)
+	return t, nil
+}
+
+// Ключ для записи с номером seq.
+func (t *TLSTree) Derive(seq uint64) ([]byte, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	masks := [3]uint64{t.params.C1, t.params.C2, t.params.C3}
+	key := t.root
+	for j := 0; j < 3; j++ {
+		l := &t.levels[j]
+		index := seq & masks[j]
+		if !l.valid || l.index != index {
+			var d [8]byte
+			binary.BigEndian.PutUint64(d[:], index)
+			k, err := t.kdf.Create(key, tlsTreeLabels[j], d[:])
+			if err != nil {
+				return nil, err
+			}
+			clearBytes(l.key)
+			l.key = k
+			l.index = index
+			l.valid = true
+			// Нижние уровни зависят от этого ключа
+			for n := j + 1; n < 3; n++ {
+				t.levels[n].valid = false
+			}
+		}
+		key = l.key
+	}
+
+	res := make([]byte, len(key))
+	subtle.ConstantTimeCopy(1, res, key)
+	return res, nil
+}
+
+// Зануление корневого и промежуточных ключей.
+func (t *TLSTree) Clear() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	clearBytes(t.root)
+	for j := range t.levels {
+		clearBytes(t.levels[j].key)
+		t.levels[j] = tlsTreeLevel{}
+	}
+}
+
+func clearBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
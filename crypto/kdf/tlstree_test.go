@@ -0,0 +1,110 @@
+package kdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"gost_magma_cbc/crypto/models"
+	"testing"
+)
+
+type countingKDF struct {
+	models.KDF
+	calls int
+}
+
+func (k *countingKDF) Create(key []byte, label []byte, seed []byte) ([]byte, error) {
+	k.calls++
+	return k.KDF.Create(key, label, seed)
+}
+
+var tlsTreeRoot = []byte{
+	0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77,
+	0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xee, 0xff, 0x0a,
+	0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88,
+	0x99, 0xaa, 0xbb, 0xcc, 0xee, 0xff, 0x0a, 0x00,
+}
+
+// Прямое вычисление TLSTREE без кэширования.
+func tlsTreeDirect(t *testing.T, params TLSTreeParams, root []byte, seq uint64) []byte {
+	k := NewKDF256()
+	key := root
+	for j, c := range []uint64{params.C1, params.C2, params.C3} {
+		var d [8]byte
+		binary.BigEndian.PutUint64(d[:], seq&c)
+		var err error
+		key, err = k.Create(key, tlsTreeLabels[j], d[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return key
+}
+
+func Test_TLSTree(t *testing.T) {
+	for _, params := range []TLSTreeParams{TLSTreeMagma, TLSTreeKuznyechik} {
+		tree, err := NewTLSTree(params, tlsTreeRoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		step := ^params.C3 + 1
+		seqs := []uint64{0, 1, step - 1, step, step + 1, ^params.C2, ^params.C2 + 1,
+			^params.C1, ^params.C1 + 1, 3, 0xffffffffffffffff, 0}
+		for _, seq := range seqs {
+			got, err := tree.Derive(seq)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tlsTreeDirect(t, params, tlsTreeRoot, seq)
+			if !bytes.Equal(got, want) {
+				t.Errorf("[%x] got %x, want %x", seq, got, want)
+			}
+		}
+
+		k0, _ := tree.Derive(0)
+		k1, _ := tree.Derive(step - 1)
+		k2, _ := tree.Derive(step)
+		if !bytes.Equal(k0, k1) {
+			t.Errorf("keys differ inside one C3 range")
+		}
+		if bytes.Equal(k0, k2) {
+			t.Errorf("keys equal for different C3 ranges")
+		}
+	}
+}
+
+func Test_TLSTreeCache(t *testing.T) {
+	tree, err := NewTLSTree(TLSTreeMagma, tlsTreeRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ck := &countingKDF{KDF: NewKDF256()}
+	tree.kdf = ck
+
+	for seq := uint64(0); seq < 0x1000; seq++ {
+		if _, err := tree.Derive(seq); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ck.calls != 3 {
+		t.Errorf("kdf calls for first C3 range is %d, not 3", ck.calls)
+	}
+
+	// Переход в следующий диапазон C3 пересчитывает только последний уровень
+	tree.Derive(0x1000)
+	if ck.calls != 4 {
+		t.Errorf("kdf calls after C3 change is %d, not 4", ck.calls)
+	}
+
+	// Переход в следующий диапазон C2 пересчитывает два уровня
+	tree.Derive(0x2000000)
+	if ck.calls != 6 {
+		t.Errorf("kdf calls after C2 change is %d, not 6", ck.calls)
+	}
+}
+
+func Test_TLSTreeRootLen(t *testing.T) {
+	if _, err := NewTLSTree(TLSTreeMagma, tlsTreeRoot[:16]); err == nil {
+		t.Error("short root key accepted")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"gost_magma_cbc/crypto/gost3410"
 	"gost_magma_cbc/crypto/models"
 	"os"
 )
@@ -19,8 +20,17 @@ const (
 	BuildFromFile     BuildMethod = 3
 	BuildFromRandom   BuildMethod = 4
 	BuildFromKDF      BuildMethod = 5
+	BuildFromVKO      BuildMethod = 6
 )
 
+// Параметры выработки ключа VKO_GOSTR3410_2012 (Р 50.1.113-2016):
+// собственный закрытый ключ, открытый ключ партнёра и UKM (little-endian).
+type VKOParams struct {
+	Private *gost3410.PrivateKey
+	Peer    *gost3410.PublicKey
+	UKM     []byte
+}
+
 type BuildData struct {
 	BEString *string
 	LEString *string
@@ -28,6 +38,7 @@ type BuildData struct {
 	Bytes    *[]byte
 	Kdf      models.KDFParams
 	Prng     models.DRBGPrng
+	Vko      VKOParams
 }
 
 func ConvertHexBigEndian(h string) ([]byte, error) {
@@ -118,6 +129,19 @@ func BuildFrom(data *BuildData, method BuildMethod, l int) ([]byte, error) {
 			return nil, errors.New("len above supported len")
 		}
 		return data.Kdf.Kdf.Create(data.Kdf.Key, data.Kdf.Label, data.Kdf.Seed)
+	case BuildFromVKO:
+		if data.Vko.Private == nil || data.Vko.Peer == nil {
+			return nil, errors.New("nil vko keys")
+		}
+		ukm := gost3410.NewUKM(data.Vko.UKM)
+		switch l {
+		case 32:
+			return data.Vko.Private.VKO256(data.Vko.Peer, ukm)
+		case 64:
+			return data.Vko.Private.VKO512(data.Vko.Peer, ukm)
+		default:
+			return nil, errors.New("unsupported vko key len")
+		}
 	default:
 		return nil, errors.New("unknown method")
 	}
@@ -1,7 +1,16 @@
 package manage
 
 import (
+	"bytes"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/drbg/ctrdrbg"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/drbg/hmacdrbg"
+	"gost_magma_cbc/crypto/gost3410"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"gost_magma_cbc/crypto/models"
 	"os"
+	"sync"
 	"testing"
 )
 
@@ -87,3 +96,85 @@ func TestBuildRandom(t *testing.T) {
 		t.Error("zero data")
 	}
 }
+
+func TestBuildRandomWithDrbg(t *testing.T) {
+	hash, err := hdrbg.NewHashDrbgPrng(streebog.New256, nil, 32, hdrbg.SECURITY_LEVEL_TEST, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmac, err := hmacdrbg.NewHmacDrbgPrng(streebog.New256, nil, 32, hdrbg.SECURITY_LEVEL_TEST, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctr, err := ctrdrbg.NewCtrDrbgPrng(kuznyechik.NewKuznyechik, true, nil, 32, hdrbg.SECURITY_LEVEL_TEST, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, prng := range []models.DRBGPrng{hash, hmac, ctr} {
+		b := BuildData{Prng: prng}
+		d, err := BuildFrom(&b, BuildFromRandom, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d) != 32 {
+			t.Errorf("[%d] incorrect random data len %d", i, len(d))
+		}
+	}
+}
+
+func TestBuildRandomWithPoolConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := BuildData{Prng: hdrbg.Reader}
+			d, err := BuildFrom(&b, BuildFromRandom, 32)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(d) != 32 {
+				t.Errorf("incorrect random data len %d", len(d))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBuildVKO(t *testing.T) {
+	prvA, err := gost3410.GenerateKey(gost3410.CurveTC26_256A, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prvB, err := gost3410.GenerateKey(gost3410.CurveTC26_256A, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ukm := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	for _, l := range []int{32, 64} {
+		a := BuildData{Vko: VKOParams{Private: prvA, Peer: &prvB.PublicKey, UKM: ukm}}
+		b := BuildData{Vko: VKOParams{Private: prvB, Peer: &prvA.PublicKey, UKM: ukm}}
+		ka, err := BuildFrom(&a, BuildFromVKO, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kb, err := BuildFrom(&b, BuildFromVKO, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ka) != l || !bytes.Equal(ka, kb) {
+			t.Errorf("[%d] shared keys differ", l)
+		}
+	}
+
+	if _, err := BuildFrom(&BuildData{}, BuildFromVKO, 32); err == nil {
+		t.Error("empty vko params accepted")
+	}
+	d := BuildData{Vko: VKOParams{Private: prvA, Peer: &prvB.PublicKey}}
+	if _, err := BuildFrom(&d, BuildFromVKO, 16); err == nil {
+		t.Error("unsupported len accepted")
+	}
+}
@@ -0,0 +1,41 @@
+package mode
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/models"
+)
+
+const PREFIX = "crypto:mode: "
+
+// Шифрование блоков в порядке байт ГОСТ Р 34.13-2015 (старший байт
+// первым). Блок базового алгоритма хранится в little-endian, поэтому
+// при вызове шифра порядок байт обращается.
+type blockCipher struct {
+	base  models.BaseAlgorithm
+	key   models.Key
+	block models.Block
+	n     int
+}
+
+func newBlockCipher(base models.BaseAlgorithm, key models.Key) (*blockCipher, error) {
+	if base == nil || key == nil {
+		return nil, errors.New(PREFIX + "nil base algorithm or key")
+	}
+	if key.Len() != base.KeyLen() {
+		return nil, errors.New(PREFIX + "invalid key length")
+	}
+	block := base.NewBlock()
+	return &blockCipher{base: base, key: key, block: block, n: block.Len()}, nil
+}
+
+func (c *blockCipher) encrypt(dst, src []byte) {
+	d := c.block.Data()
+	for i := 0; i < c.n; i++ {
+		d[i] = src[c.n-1-i]
+	}
+	c.base.Encrypt(c.key, c.block, c.block)
+	for i := 0; i < c.n; i++ {
+		dst[i] = d[c.n-1-i]
+	}
+	c.block.Clear()
+}
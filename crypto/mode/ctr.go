@@ -0,0 +1,58 @@
+package mode
+
+import (
+	"crypto/subtle"
+	"errors"
+	"gost_magma_cbc/crypto/models"
+)
+
+// Режим гаммирования CTR (ГОСТ Р 34.13-2015, 4.2). Начальное значение
+// счётчика IV || 0...0, IV длиной в половину блока. Реализует
+// cipher.Stream.
+type CTR struct {
+	c   *blockCipher
+	ctr []byte
+	ks  []byte
+	pos int
+}
+
+func NewCTR(base models.BaseAlgorithm, key models.Key, iv []byte) (*CTR, error) {
+	c, err := newBlockCipher(base, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != c.n/2 {
+		return nil, errors.New(PREFIX + "iv must be half of block len")
+	}
+	ctr := make([]byte, c.n)
+	copy(ctr, iv)
+	return &CTR{c: c, ctr: ctr, ks: make([]byte, c.n), pos: c.n}, nil
+}
+
+// Шифрование и расшифрование совпадают: dst = src ^ гамма. Неполный
+// последний блок использует старшие байты гаммы.
+func (m *CTR) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic(PREFIX + "output smaller than input")
+	}
+	for len(src) > 0 {
+		if m.pos == len(m.ks) {
+			m.c.encrypt(m.ks, m.ctr)
+			increment(m.ctr)
+			m.pos = 0
+		}
+		n := subtle.XORBytes(dst, src, m.ks[m.pos:])
+		m.pos += n
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+// ctr = ctr + 1 mod 2^n
+func increment(ctr []byte) {
+	for i := len(ctr) - 1; i >= 0; i-- {
+		ctr[i]++
+		if ctr[i] != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package mode
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/models"
+)
+
+// Режим аутентифицированного шифрования MGM (Р 1323565.1.026-2019),
+// реализует cipher.AEAD. Одноразовое значение длиной в блок со старшим
+// битом 0, имитовставка длиной tagSize байт.
+type MGM struct {
+	c       *blockCipher
+	tagSize int
+}
+
+var ErrOpen = errors.New(PREFIX + "message authentication failed")
+
+func NewMGM(base models.BaseAlgorithm, key models.Key, tagSize int) (*MGM, error) {
+	c, err := newBlockCipher(base, key)
+	if err != nil {
+		return nil, err
+	}
+	if c.n != 8 && c.n != 16 {
+		return nil, errors.New(PREFIX + "unsupported block len")
+	}
+	if tagSize < 4 || tagSize > c.n {
+		return nil, errors.New(PREFIX + "invalid tag size")
+	}
+	return &MGM{c: c, tagSize: tagSize}, nil
+}
+
+func (m *MGM) NonceSize() int {
+	return m.c.n
+}
+
+func (m *MGM) Overhead() int {
+	return m.tagSize
+}
+
+func (m *MGM) checkNonce(nonce []byte) {
+	if len(nonce) != m.c.n {
+		panic(PREFIX + "incorrect nonce length")
+	}
+	if nonce[0]&0x80 != 0 {
+		panic(PREFIX + "nonce msb must be zero")
+	}
+}
+
+func (m *MGM) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	m.checkNonce(nonce)
+	ret, out := sliceForAppend(dst, len(plaintext)+m.tagSize)
+	m.crypt(out[:len(plaintext)], plaintext, nonce)
+	m.tag(out[len(plaintext):], nonce, additionalData, out[:len(plaintext)])
+	return ret
+}
+
+func (m *MGM) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	m.checkNonce(nonce)
+	if len(ciphertext) < m.tagSize {
+		return nil, ErrOpen
+	}
+	ct, tag := ciphertext[:len(ciphertext)-m.tagSize], ciphertext[len(ciphertext)-m.tagSize:]
+	want := make([]byte, m.tagSize)
+	m.tag(want, nonce, additionalData, ct)
+	if subtle.ConstantTimeCompare(want, tag) != 1 {
+		return nil, ErrOpen
+	}
+	ret, out := sliceForAppend(dst, len(ct))
+	m.crypt(out, ct, nonce)
+	return ret, nil
+}
+
+// Шифрование: Y_1 = E_K(0 || ICN), Y_{i+1} = incr_r(Y_i) (правая половина), C_i = P_i ^ E_K(Y_i).
+func (m *MGM) crypt(dst, src, nonce []byte) {
+	n := m.c.n
+	y := make([]byte, n)
+	copy(y, nonce)
+	y[0] &= 0x7f
+	m.c.encrypt(y, y)
+	ks := make([]byte, n)
+	for len(src) > 0 {
+		m.c.encrypt(ks, y)
+		increment(y[n/2:])
+		k := subtle.XORBytes(dst, src, ks)
+		dst, src = dst[k:], src[k:]
+	}
+	clear(ks)
+}
+
+// Имитовставка: Z_1 = E_K(1 || ICN), H_i = E_K(Z_i), Z_{i+1} = incr_l(Z_i),
+// T = MSB(E_K(sum H_i * A_i ^ sum H_j * C_j ^ H * (len(A) || len(C)))).
+func (m *MGM) tag(out, nonce, ad, ct []byte) {
+	n := m.c.n
+	z := make([]byte, n)
+	copy(z, nonce)
+	z[0] |= 0x80
+	m.c.encrypt(z, z)
+
+	sum := make([]byte, n)
+	h := make([]byte, n)
+	block := make([]byte, n)
+	mul := m.mul64
+	if n == 16 {
+		mul = m.mul128
+	}
+	process := func(data []byte) {
+		for len(data) > 0 {
+			clear(block)
+			k := copy(block, data)
+			data = data[k:]
+			m.c.encrypt(h, z)
+			increment(z[:n/2])
+			mul(h, block)
+			subtle.XORBytes(sum, sum, h)
+		}
+	}
+	process(ad)
+	process(ct)
+
+	// len(A) || len(C) в битах, по n/2 бит
+	if n == 16 {
+		binary.BigEndian.PutUint64(block[:8], uint64(len(ad))*8)
+		binary.BigEndian.PutUint64(block[8:], uint64(len(ct))*8)
+	} else {
+		binary.BigEndian.PutUint32(block[:4], uint32(len(ad))*8)
+		binary.BigEndian.PutUint32(block[4:], uint32(len(ct))*8)
+	}
+	m.c.encrypt(h, z)
+	mul(h, block)
+	subtle.XORBytes(sum, sum, h)
+	m.c.encrypt(sum, sum)
+	copy(out, sum[:m.tagSize])
+}
+
+// h = h * b в GF(2^64), многочлен x^64 + x^4 + x^3 + x + 1.
+func (m *MGM) mul64(h, b []byte) {
+	x := binary.BigEndian.Uint64(h)
+	y := binary.BigEndian.Uint64(b)
+	var z uint64
+	for i := 0; i < 64; i++ {
+		z ^= x & -(y & 1)
+		y >>= 1
+		x = x<<1 ^ 0x1b&-(x>>63)
+	}
+	binary.BigEndian.PutUint64(h, z)
+}
+
+// h = h * b в GF(2^128), многочлен x^128 + x^7 + x^2 + x + 1.
+func (m *MGM) mul128(h, b []byte) {
+	xh, xl := binary.BigEndian.Uint64(h), binary.BigEndian.Uint64(h[8:])
+	yh, yl := binary.BigEndian.Uint64(b), binary.BigEndian.Uint64(b[8:])
+	var zh, zl uint64
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = yl >> i & 1
+		} else {
+			bit = yh >> (i - 64) & 1
+		}
+		zh ^= xh & -bit
+		zl ^= xl & -bit
+		carry := xh >> 63
+		xh = xh<<1 | xl>>63
+		xl = xl<<1 ^ 0x87&-carry
+	}
+	binary.BigEndian.PutUint64(h, zh)
+	binary.BigEndian.PutUint64(h[8:], zl)
+}
+
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,182 @@
+package mode
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/manage"
+	"gost_magma_cbc/crypto/models"
+)
+
+// Данные примеров ГОСТ Р 34.13-2015 (приложение А) в порядке байт стандарта.
+type example struct {
+	base  models.BaseAlgorithm
+	key   string
+	plain string
+	iv    string
+	ctr   string
+	mac   string
+}
+
+var examples = []example{
+	{
+		base:  kuznyechik.NewKuznyechik(),
+		key:   "8899aabbccddeeff0011223344556677fedcba98765432100123456789abcdef",
+		plain: "1122334455667700ffeeddccbbaa9988 00112233445566778899aabbcceeff0a 112233445566778899aabbcceeff0a00 2233445566778899aabbcceeff0a0011",
+		iv:    "1234567890abcef0",
+		ctr:   "f195d8bec10ed1dbd57b5fa240bda1b8 85eee733f6a13e5df33ce4b33c45dee4 a5eae88be6356ed3d5e877f13564a3a5 cb91fab1f20cbab6d1c6d15820bdba73",
+		mac:   "336f4d296059fbe34ddeb35b37749c67",
+	},
+	{
+		base:  magma.NewMagma(),
+		key:   "ffeeddccbbaa99887766554433221100f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		plain: "92def06b3c130a59 db54c704f8189d20 4a98fb2e67a8024c 8912409b17b57e41",
+		iv:    "12345678",
+		ctr:   "4e98110c97b7b93c 3e250d93d6e85d69 136d868807b2dbef 568eb680ab52a12d",
+		mac:   "154e72102030c5bb",
+	},
+}
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func newKey(t *testing.T, base models.BaseAlgorithm, s string) models.Key {
+	t.Helper()
+	b, err := manage.ConvertHexBigEndian(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := base.NewKey()
+	copy(key.Data(), b)
+	return key
+}
+
+func TestCTR(t *testing.T) {
+	for _, e := range examples {
+		key := newKey(t, e.base, e.key)
+		plain, want := unhex(t, e.plain), unhex(t, e.ctr)
+
+		ctr, err := NewCTR(e.base, key, unhex(t, e.iv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]byte, len(plain))
+		ctr.XORKeyStream(out, plain)
+		if !bytes.Equal(out, want) {
+			t.Errorf("[%d] ctr: %x", e.base.BlockLen(), out)
+		}
+
+		// Потоковая обработка фрагментами произвольной длины
+		ctr, _ = NewCTR(e.base, key, unhex(t, e.iv))
+		out = make([]byte, len(plain))
+		for i, step := 0, 1; i < len(plain); i, step = i+step, step+2 {
+			end := min(i+step, len(plain))
+			ctr.XORKeyStream(out[i:end], plain[i:end])
+		}
+		if !bytes.Equal(out, want) {
+			t.Errorf("[%d] ctr stream: %x", e.base.BlockLen(), out)
+		}
+	}
+	if _, err := NewCTR(magma.NewMagma(), magma.NewMagma().NewKey(), make([]byte, 8)); err == nil {
+		t.Error("invalid iv accepted")
+	}
+}
+
+func TestOMAC(t *testing.T) {
+	for _, e := range examples {
+		key := newKey(t, e.base, e.key)
+		plain, want := unhex(t, e.plain), unhex(t, e.mac)
+		m, err := NewOMAC(e.base, key, len(want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Write(plain[:3])
+		m.Write(plain[3:])
+		if res := m.Sum(nil); !bytes.Equal(res, want) {
+			t.Errorf("[%d] omac: %x", e.base.BlockLen(), res)
+		}
+		// Sum не изменяет состояние
+		if res := m.Sum(nil); !bytes.Equal(res, want) {
+			t.Errorf("[%d] omac repeated sum: %x", e.base.BlockLen(), res)
+		}
+		m.Reset()
+		m.Write(plain[:len(plain)-1])
+		if res := m.Sum(nil); bytes.Equal(res, want) {
+			t.Errorf("[%d] omac of partial block equals full", e.base.BlockLen())
+		}
+
+		short, _ := NewOMAC(e.base, key, 4)
+		short.Write(plain)
+		if res := short.Sum(nil); !bytes.Equal(res, want[:4]) {
+			t.Errorf("[%d] truncated omac: %x", e.base.BlockLen(), res)
+		}
+	}
+}
+
+func TestMGM(t *testing.T) {
+	// Р 1323565.1.026-2019, приложение А
+	tests := []struct {
+		base   models.BaseAlgorithm
+		key    string
+		nonce  string
+		ad     string
+		plain  string
+		cipher string
+		tag    string
+	}{
+		{
+			base:   kuznyechik.NewKuznyechik(),
+			key:    "8899aabbccddeeff0011223344556677fedcba98765432100123456789abcdef",
+			nonce:  "1122334455667700ffeeddccbbaa9988",
+			ad:     "02020202020202020101010101010101 04040404040404040303030303030303 ea0505050505050505",
+			plain:  "1122334455667700ffeeddccbbaa9988 00112233445566778899aabbcceeff0a 112233445566778899aabbcceeff0a00 2233445566778899aabbcceeff0a0011 aabbcc",
+			cipher: "a9757b8147956e9055b8a33de89f42fc 8075d2212bf9fd5bd3f7069aadc16b39 497ab15915a6ba85936b5d0ea9f6851c c60c14d4d3f883d0ab94420695c76deb 2c7552",
+			tag:    "cf5d656f40c34f5c46e8bb0e29fcdb4c",
+		},
+		{
+			base:   magma.NewMagma(),
+			key:    "ffeeddccbbaa99887766554433221100f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+			nonce:  "12def06b3c130a59",
+			ad:     "0101010101010101 0202020202020202 0303030303030303 0404040404040404 0505050505050505 ea",
+			plain:  "ffeeddccbbaa9988 1122334455667700 8899aabbcceeff0a 0011223344556677 99aabbcceeff0a00 1122334455667788 aabbcceeff0a0011 2233445566778899 aabbcc",
+			cipher: "c795066c5f9ea03b 85113342459185ae 1f2e00d6bf2b785d 940470b8bb9c8e7d 9a5dd3731f7ddc70 ec27cb0ace6fa576 70f65c646abb75d5 47aa37c3bcb5c34e 03bb9c",
+			tag:    "a7928069aa10fd10",
+		},
+	}
+	for _, tt := range tests {
+		key := newKey(t, tt.base, tt.key)
+		tag := unhex(t, tt.tag)
+		aead, err := NewMGM(tt.base, key, len(tag))
+		if err != nil {
+			t.Fatal(err)
+		}
+		nonce, ad, plain := unhex(t, tt.nonce), unhex(t, tt.ad), unhex(t, tt.plain)
+		want := append(unhex(t, tt.cipher), tag...)
+		sealed := aead.Seal(nil, nonce, plain, ad)
+		if !bytes.Equal(sealed, want) {
+			t.Errorf("[%d] mgm: %x", tt.base.BlockLen(), sealed)
+		}
+		opened, err := aead.Open(nil, nonce, sealed, ad)
+		if err != nil || !bytes.Equal(opened, plain) {
+			t.Errorf("[%d] mgm open: %v", tt.base.BlockLen(), err)
+		}
+		sealed[0] ^= 1
+		if _, err := aead.Open(nil, nonce, sealed, ad); err != ErrOpen {
+			t.Errorf("[%d] modified ciphertext accepted", tt.base.BlockLen())
+		}
+		sealed[0] ^= 1
+		if _, err := aead.Open(nil, nonce, sealed, ad[1:]); err != ErrOpen {
+			t.Errorf("[%d] modified additional data accepted", tt.base.BlockLen())
+		}
+	}
+}
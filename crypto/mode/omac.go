@@ -0,0 +1,99 @@
+package mode
+
+import (
+	"crypto/subtle"
+	"errors"
+	"gost_magma_cbc/crypto/models"
+	"hash"
+)
+
+// Режим выработки имитовставки OMAC (ГОСТ Р 34.13-2015, 4.6), реализует
+// hash.Hash. size - длина имитовставки в байтах (не больше блока).
+type omac struct {
+	c    *blockCipher
+	size int
+	k1   []byte
+	k2   []byte
+	x    []byte
+	buf  []byte
+}
+
+func NewOMAC(base models.BaseAlgorithm, key models.Key, size int) (hash.Hash, error) {
+	c, err := newBlockCipher(base, key)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 || size > c.n {
+		return nil, errors.New(PREFIX + "invalid mac size")
+	}
+	m := &omac{c: c, size: size, x: make([]byte, c.n), buf: make([]byte, 0, c.n)}
+
+	// R = E_K(0^n), K1 = R << 1 (^ B_n), K2 = K1 << 1 (^ B_n)
+	r := make([]byte, c.n)
+	c.encrypt(r, r)
+	m.k1 = shiftKey(r)
+	m.k2 = shiftKey(m.k1)
+	clear(r)
+	return m, nil
+}
+
+// Сдвиг влево на один бит с приведением по модулю многочлена:
+// B_64 = 0x1b, B_128 = 0x87.
+func shiftKey(r []byte) []byte {
+	k := make([]byte, len(r))
+	var carry byte
+	for i := len(r) - 1; i >= 0; i-- {
+		k[i] = r[i]<<1 | carry
+		carry = r[i] >> 7
+	}
+	poly := byte(0x87)
+	if len(r) == 8 {
+		poly = 0x1b
+	}
+	k[len(k)-1] ^= poly & -carry
+	return k
+}
+
+func (m *omac) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// Последний блок обрабатывается только в Sum.
+		if len(m.buf) == m.c.n {
+			subtle.XORBytes(m.x, m.x, m.buf)
+			m.c.encrypt(m.x, m.x)
+			m.buf = m.buf[:0]
+		}
+		k := copy(m.buf[len(m.buf):m.c.n], p)
+		m.buf = m.buf[:len(m.buf)+k]
+		p = p[k:]
+	}
+	return n, nil
+}
+
+func (m *omac) Sum(in []byte) []byte {
+	last := make([]byte, m.c.n)
+	copy(last, m.buf)
+	if len(m.buf) == m.c.n {
+		subtle.XORBytes(last, last, m.k1)
+	} else {
+		// Дополнение 1 || 0...0
+		last[len(m.buf)] = 0x80
+		subtle.XORBytes(last, last, m.k2)
+	}
+	subtle.XORBytes(last, last, m.x)
+	m.c.encrypt(last, last)
+	return append(in, last[:m.size]...)
+}
+
+func (m *omac) Reset() {
+	clear(m.x)
+	m.buf = m.buf[:0]
+}
+
+func (m *omac) Size() int {
+	return m.size
+}
+
+func (m *omac) BlockSize() int {
+	return m.c.n
+}
@@ -0,0 +1,69 @@
+package protocol
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Кэш принятых значений (меток времени, случайных чисел, аутентификаторов)
+// для защиты от повтора. Значение хранится ttl, после чего удаляется.
+// Записи упорядочены по времени истечения, поэтому добавление удаляет
+// только истёкшие записи.
+type ReplayCache struct {
+	ttl     time.Duration
+	entries map[string]struct{}
+	queue   expiryQueue
+	mtx     sync.Mutex
+}
+
+type expiryEntry struct {
+	value  string
+	expiry time.Time
+}
+
+// Очередь записей с минимальным временем истечения в вершине.
+type expiryQueue []expiryEntry
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].expiry.Before(q[j].expiry) }
+func (q expiryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *expiryQueue) Push(x any)        { *q = append(*q, x.(expiryEntry)) }
+func (q *expiryQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+func NewReplayCache(ttl time.Duration) *ReplayCache {
+	return &ReplayCache{ttl: ttl, entries: map[string]struct{}{}}
+}
+
+// Добавление значения; false, если оно уже есть в кэше.
+func (c *ReplayCache) Add(value []byte, now time.Time) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for len(c.queue) > 0 && now.After(c.queue[0].expiry) {
+		e := heap.Pop(&c.queue).(expiryEntry)
+		delete(c.entries, e.value)
+	}
+	if _, ok := c.entries[string(value)]; ok {
+		return false
+	}
+	e := expiryEntry{value: string(value), expiry: now.Add(c.ttl)}
+	c.entries[e.value] = struct{}{}
+	heap.Push(&c.queue, e)
+	return true
+}
+
+// Время хранения значения.
+func (c *ReplayCache) TTL() time.Duration {
+	return c.ttl
+}
+
+func (c *ReplayCache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,121 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Общий формат сообщений протоколов обмена ключами и аутентификации:
+// сообщение type || (len16 || field)* передаётся в кадре len16 || message.
+// Типы сообщений, предельная длина кадра и ошибки задаются пакетом
+// протокола в Protocol.
+
+const PREFIX = "crypto:protocol: "
+
+// Предельная длина кадра, допускаемая полем len16.
+const MAX_FRAME_SIZE = 0xffff
+
+type Message struct {
+	Type   byte
+	Fields [][]byte
+}
+
+func (m *Message) Marshal() []byte {
+	n := 1
+	for _, f := range m.Fields {
+		n += 2 + len(f)
+	}
+	b := make([]byte, 1, n)
+	b[0] = m.Type
+	for _, f := range m.Fields {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(f)))
+		b = append(b, f...)
+	}
+	return b
+}
+
+func Unmarshal(b []byte) (*Message, error) {
+	if len(b) < 1 {
+		return nil, errors.New(PREFIX + "empty message")
+	}
+	m := &Message{Type: b[0]}
+	b = b[1:]
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return nil, errors.New(PREFIX + "truncated message")
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+n {
+			return nil, errors.New(PREFIX + "truncated message")
+		}
+		m.Fields = append(m.Fields, b[2:2+n])
+		b = b[2+n:]
+	}
+	return m, nil
+}
+
+// Параметры конкретного протокола.
+type Protocol struct {
+	// Предельная длина кадра (не больше MAX_FRAME_SIZE).
+	MaxSize int
+	// Тип сообщения о прерывании обмена.
+	Abort byte
+	// Ошибка, возвращаемая при получении сообщения о прерывании.
+	ErrAbort error
+	// Ошибка при неожиданном типе или числе полей.
+	ErrUnexpected error
+}
+
+func (p *Protocol) maxSize() int {
+	if p.MaxSize <= 0 || p.MaxSize > MAX_FRAME_SIZE {
+		return MAX_FRAME_SIZE
+	}
+	return p.MaxSize
+}
+
+// Запись кадра len16 || message.
+func (p *Protocol) Write(w io.Writer, m *Message) error {
+	body := m.Marshal()
+	if len(body) > p.maxSize() {
+		return errors.New(PREFIX + "message is too long")
+	}
+	frame := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(body)), uint16(len(body)))
+	_, err := w.Write(append(frame, body...))
+	return err
+}
+
+func (p *Protocol) Read(r io.Reader) (*Message, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint16(hdr[:]))
+	if n > p.maxSize() {
+		return nil, errors.New(PREFIX + "message is too long")
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return Unmarshal(body)
+}
+
+// Проверка типа и числа полей входящего сообщения.
+func (p *Protocol) Expect(m *Message, typ byte, fields int) error {
+	if m == nil {
+		return p.ErrUnexpected
+	}
+	if m.Type == p.Abort {
+		return p.ErrAbort
+	}
+	if m.Type != typ || len(m.Fields) != fields {
+		return p.ErrUnexpected
+	}
+	return nil
+}
+
+// Сообщение о прерывании обмена.
+func (p *Protocol) AbortMessage() *Message {
+	return &Message{Type: p.Abort}
+}
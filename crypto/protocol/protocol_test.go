@@ -0,0 +1,96 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	errAbort      = errors.New("abort")
+	errUnexpected = errors.New("unexpected")
+	testProtocol  = &Protocol{MaxSize: 64, Abort: 9, ErrAbort: errAbort, ErrUnexpected: errUnexpected}
+)
+
+func TestMessageEncoding(t *testing.T) {
+	m := &Message{Type: 2, Fields: [][]byte{[]byte("server"), {1}, {}}}
+	b := m.Marshal()
+	m2, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2.Type != m.Type || len(m2.Fields) != 3 || string(m2.Fields[0]) != "server" || len(m2.Fields[2]) != 0 {
+		t.Error("message roundtrip fail")
+	}
+	// Границы полей дают корректные сообщения с меньшим числом полей
+	for i := 0; i < len(b); i++ {
+		if i == 1 || i == 1+2+6 || i == 1+2+6+2+1 {
+			continue
+		}
+		if _, err := Unmarshal(b[:i]); err == nil {
+			t.Errorf("truncated message %d accepted", i)
+		}
+	}
+}
+
+func TestFrame(t *testing.T) {
+	m := &Message{Type: 2, Fields: [][]byte{[]byte("server"), {1}, {}}}
+	var buf bytes.Buffer
+	if err := testProtocol.Write(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := testProtocol.Read(&buf)
+	if err != nil || !bytes.Equal(m2.Marshal(), m.Marshal()) {
+		t.Error("frame roundtrip fail")
+	}
+	if err := testProtocol.Write(&buf, &Message{Fields: [][]byte{make([]byte, 64)}}); err == nil {
+		t.Error("too long message accepted")
+	}
+	// Длинный кадр отвергается по заголовку
+	buf.Reset()
+	(&Protocol{}).Write(&buf, &Message{Fields: [][]byte{make([]byte, 100)}})
+	if _, err := testProtocol.Read(&buf); err == nil {
+		t.Error("too long frame accepted")
+	}
+}
+
+func TestExpect(t *testing.T) {
+	p := testProtocol
+	if err := p.Expect(&Message{Type: 2, Fields: [][]byte{{}}}, 2, 1); err != nil {
+		t.Error(err)
+	}
+	if err := p.Expect(p.AbortMessage(), 2, 1); err != errAbort {
+		t.Errorf("abort: %v", err)
+	}
+	if err := p.Expect(&Message{Type: 3, Fields: [][]byte{{}}}, 2, 1); err != errUnexpected {
+		t.Errorf("wrong type: %v", err)
+	}
+	if err := p.Expect(&Message{Type: 2}, 2, 1); err != errUnexpected {
+		t.Errorf("wrong fields count: %v", err)
+	}
+	if err := p.Expect(nil, 2, 1); err != errUnexpected {
+		t.Errorf("nil message: %v", err)
+	}
+}
+
+func TestReplayCache(t *testing.T) {
+	c := NewReplayCache(time.Second)
+	now := time.Unix(0, 0)
+	if !c.Add([]byte{1}, now) || c.Add([]byte{1}, now.Add(time.Second)) {
+		t.Fatal("incorrect cache check")
+	}
+	if !c.Add([]byte{2}, now.Add(time.Second)) {
+		t.Fatal("new value rejected")
+	}
+	if !c.Add([]byte{1}, now.Add(2*time.Second)) {
+		t.Error("expired value is not removed")
+	}
+	if c.Len() != 2 || c.Add([]byte{2}, now.Add(2*time.Second)) {
+		t.Errorf("unexpired value is removed, cache length %d", c.Len())
+	}
+	c.Add([]byte{3}, now.Add(10*time.Second))
+	if c.Len() != 1 {
+		t.Errorf("cache length %d", c.Len())
+	}
+}
@@ -0,0 +1,181 @@
+package record
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Защищённый канал поверх net.Conn: сообщения разбиваются на записи
+// длиной не более MAX_PLAINTEXT, каждая запись защищается ключами
+// текущей эпохи своего направления. Реализует net.Conn.
+type Conn struct {
+	conn net.Conn
+
+	rmtx    sync.Mutex
+	in      *halfConn
+	pending []byte
+	rerr    error
+
+	wmtx   sync.Mutex
+	out    *halfConn
+	closed bool
+}
+
+func NewConn(conn net.Conn, config *Config) (*Conn, error) {
+	if conn == nil || config == nil {
+		return nil, errors.New(PREFIX + "nil connection or config")
+	}
+	in, err := newHalfConn(config, config.Read)
+	if err != nil {
+		return nil, err
+	}
+	out, err := newHalfConn(config, config.Write)
+	if err != nil {
+		in.clearKeys()
+		return nil, err
+	}
+	return &Conn{conn: conn, in: in, out: out}, nil
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	c.wmtx.Lock()
+	defer c.wmtx.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	n := 0
+	for len(b) > 0 {
+		chunk := b[:min(len(b), MAX_PLAINTEXT)]
+		if err := c.writeRecord(RECORD_DATA, chunk); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		b = b[len(chunk):]
+	}
+	return n, nil
+}
+
+func (c *Conn) writeRecord(typ byte, payload []byte) error {
+	rec, err := c.out.seal(typ, payload)
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.Write(rec)
+	return err
+}
+
+// Чтение открытых данных. Запись RECORD_CLOSE завершает поток (io.EOF),
+// ошибка проверки записи сохраняется для всех последующих чтений.
+func (c *Conn) Read(b []byte) (int, error) {
+	c.rmtx.Lock()
+	defer c.rmtx.Unlock()
+	for len(c.pending) == 0 {
+		if c.rerr != nil {
+			return 0, c.rerr
+		}
+		typ, payload, err := c.readRecord()
+		if err != nil {
+			c.rerr = err
+			return 0, err
+		}
+		switch typ {
+		case RECORD_DATA:
+			c.pending = payload
+		case RECORD_CLOSE:
+			c.rerr = io.EOF
+		default:
+			c.rerr = errors.New(PREFIX + "unknown record type")
+		}
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *Conn) readRecord() (byte, []byte, error) {
+	hdr := make([]byte, HEADER_SIZE)
+	if _, err := io.ReadFull(c.conn, hdr); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint16(hdr[9:]))
+	if n > MAX_PLAINTEXT+c.in.overhead() {
+		return 0, nil, errors.New(PREFIX + "record is too long")
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return c.in.open(hdr, body)
+}
+
+// Номера текущих эпох ключей чтения и записи.
+func (c *Conn) Epochs() (read, write uint64) {
+	c.rmtx.Lock()
+	read = c.in.epoch
+	c.rmtx.Unlock()
+	c.wmtx.Lock()
+	write = c.out.epoch
+	c.wmtx.Unlock()
+	return
+}
+
+// Отправка RECORD_CLOSE, закрытие соединения и очистка ключей.
+// Время ожидания отправки записи закрытия (изменяется в тестах).
+var closeTimeout = CLOSE_TIMEOUT
+
+// Закрытие канала. Если идёт запись, соединение закрывается сразу, без
+// записи закрытия: ждать освобождения wmtx нельзя, так как Write может
+// быть заблокирован собеседником, который не читает.
+func (c *Conn) Close() error {
+	var err error
+	if c.wmtx.TryLock() {
+		if !c.closed {
+			c.closed = true
+			c.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+			err = c.writeRecord(RECORD_CLOSE, nil)
+			c.out.clearKeys()
+		}
+		c.wmtx.Unlock()
+	}
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	// Закрытие соединения прерывает Write, после чего ключи можно занулить
+	c.wmtx.Lock()
+	if !c.closed {
+		c.closed = true
+		c.out.clearKeys()
+	}
+	c.wmtx.Unlock()
+	c.rmtx.Lock()
+	c.in.clearKeys()
+	c.rmtx.Unlock()
+	return err
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,309 @@
+package record
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/handshake"
+	"gost_magma_cbc/crypto/kdf"
+	"gost_magma_cbc/crypto/manage"
+	"gost_magma_cbc/crypto/mode"
+	"gost_magma_cbc/crypto/models"
+	"hash"
+	"time"
+)
+
+const PREFIX = "crypto:record: "
+
+// Режим защиты записей.
+type Mode int
+
+const (
+	// Гаммирование CTR и имитовставка OMAC над заголовком и шифртекстом.
+	ModeCTRMAC Mode = 0
+	// Аутентифицированное шифрование MGM, заголовок - ассоциированные данные.
+	ModeMGM Mode = 1
+)
+
+// Типы записей.
+const (
+	RECORD_CLOSE byte = 21
+	RECORD_DATA  byte = 23
+)
+
+// Заголовок записи: type || seq (8 байт) || длина тела (2 байта).
+const HEADER_SIZE = 11
+
+// Максимальная длина открытых данных в одной записи.
+const MAX_PLAINTEXT = 1 << 14
+
+// Время ожидания отправки записи закрытия, если собеседник не читает.
+const CLOSE_TIMEOUT = 5 * time.Second
+
+// Границы смены ключей по умолчанию.
+const (
+	DEFAULT_REKEY_BYTES   = 1 << 30
+	DEFAULT_REKEY_RECORDS = 1 << 20
+)
+
+// Метки KDF256 ключей эпохи.
+const (
+	LABEL_ENC = "record enc"
+	LABEL_MAC = "record mac"
+)
+
+var (
+	ErrReplay    = errors.New(PREFIX + "unexpected sequence number")
+	ErrBadRecord = errors.New(PREFIX + "record authentication failed")
+)
+
+// Секреты одного направления. Ключи эпохи i вырабатываются как
+// KDF256(Enc, LABEL_ENC, i) и KDF256(Mac, LABEL_MAC, i); Mac не
+// используется в режиме MGM.
+type Secrets struct {
+	Enc []byte
+	Mac []byte
+}
+
+type Config struct {
+	Base  crypto.CryptoBase
+	Mode  Mode
+	Write Secrets
+	Read  Secrets
+	// Смена ключей после указанного объёма открытых данных или числа
+	// записей в эпохе (0 - значения по умолчанию).
+	RekeyBytes   uint64
+	RekeyRecords uint64
+	Log          models.Log
+}
+
+// Установка секретов по результату рукопожатия.
+func (c *Config) SetHandshakeKeys(keys *handshake.Keys, isClient bool) {
+	client := Secrets{Enc: keys.ClientEnc, Mac: keys.ClientMac}
+	server := Secrets{Enc: keys.ServerEnc, Mac: keys.ServerMac}
+	if isClient {
+		c.Write, c.Read = client, server
+	} else {
+		c.Write, c.Read = server, client
+	}
+}
+
+func newBase(b crypto.CryptoBase) (models.BaseAlgorithm, error) {
+	switch b {
+	case crypto.BaseAlgorithmMagma:
+		return magma.NewMagma(), nil
+	case crypto.BaseAlgorithmKuznyechik:
+		return kuznyechik.NewKuznyechik(), nil
+	default:
+		return nil, errors.New(PREFIX + "unknown base algorithm")
+	}
+}
+
+// Состояние одного направления: ключи текущей эпохи, хранящиеся в
+// KeysManager, номер записи и счётчики для смены ключей.
+type halfConn struct {
+	base    models.BaseAlgorithm
+	mode    Mode
+	secrets Secrets
+	keys    *manage.KeysManager
+	maxB    uint64
+	maxR    uint64
+
+	epoch  uint64
+	seq    uint64
+	recs   uint64
+	bytes  uint64
+	encKey models.Key
+	macKey models.Key
+	aead   *mode.MGM
+	mac    hash.Hash
+}
+
+func newHalfConn(config *Config, secrets Secrets) (*halfConn, error) {
+	base, err := newBase(config.Base)
+	if err != nil {
+		return nil, err
+	}
+	if config.Mode != ModeCTRMAC && config.Mode != ModeMGM {
+		return nil, errors.New(PREFIX + "unknown record mode")
+	}
+	if len(secrets.Enc) == 0 || (config.Mode == ModeCTRMAC && len(secrets.Mac) == 0) {
+		return nil, errors.New(PREFIX + "empty traffic secret")
+	}
+	h := &halfConn{
+		base:    base,
+		mode:    config.Mode,
+		secrets: secrets,
+		// Время жизни 0: ключи сменяются только по границам объёма,
+		// одинаково вычисляемым обеими сторонами.
+		keys: manage.NewKeysManagerWithLog(0, config.Log),
+		maxB: config.RekeyBytes,
+		maxR: config.RekeyRecords,
+	}
+	if h.maxB == 0 {
+		h.maxB = DEFAULT_REKEY_BYTES
+	}
+	if h.maxR == 0 {
+		h.maxR = DEFAULT_REKEY_RECORDS
+	}
+	// Номер записи в эпохе занимает половину блока в IV режима CTR.
+	if base.BlockLen() == 8 && h.maxR > 1<<32 {
+		return nil, errors.New(PREFIX + "rekey records limit is too large")
+	}
+	if err := h.setKeys(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *halfConn) newKey(secret []byte, label string) (models.Key, error) {
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], h.epoch)
+	data := manage.BuildData{Kdf: models.KDFParams{
+		Kdf:   kdf.NewKDF256(),
+		Key:   secret,
+		Label: []byte(label),
+		Seed:  seed[:],
+	}}
+	return h.keys.GetNextKey(h.base, &data, manage.BuildFromKDF)
+}
+
+func (h *halfConn) setKeys() error {
+	var err error
+	h.encKey, err = h.newKey(h.secrets.Enc, LABEL_ENC)
+	if err != nil {
+		return err
+	}
+	if h.mode == ModeMGM {
+		h.aead, err = mode.NewMGM(h.base, h.encKey, h.base.BlockLen())
+		return err
+	}
+	h.macKey, err = h.newKey(h.secrets.Mac, LABEL_MAC)
+	if err != nil {
+		return err
+	}
+	h.mac, err = mode.NewOMAC(h.base, h.macKey, h.base.BlockLen())
+	return err
+}
+
+func (h *halfConn) clearKeys() {
+	if h.encKey != nil {
+		h.keys.Clear(h.encKey)
+		h.encKey = nil
+	}
+	if h.macKey != nil {
+		h.keys.Clear(h.macKey)
+		h.macKey = nil
+	}
+}
+
+// Переход к следующей эпохе при достижении границ.
+func (h *halfConn) maybeRekey() error {
+	if h.recs < h.maxR && h.bytes < h.maxB {
+		return nil
+	}
+	h.clearKeys()
+	h.epoch++
+	h.recs = 0
+	h.bytes = 0
+	return h.setKeys()
+}
+
+func (h *halfConn) header(typ byte, bodyLen int) []byte {
+	hdr := make([]byte, HEADER_SIZE)
+	hdr[0] = typ
+	binary.BigEndian.PutUint64(hdr[1:], h.seq)
+	binary.BigEndian.PutUint16(hdr[9:], uint16(bodyLen))
+	return hdr
+}
+
+// IV режима CTR (половина блока) или nonce MGM (блок со старшим битом 0)
+// из номера записи в эпохе.
+func (h *halfConn) nonce() []byte {
+	n := h.base.BlockLen()
+	if h.mode == ModeCTRMAC {
+		n /= 2
+	}
+	b := make([]byte, n)
+	if n == 4 {
+		binary.BigEndian.PutUint32(b, uint32(h.recs))
+	} else {
+		binary.BigEndian.PutUint64(b[n-8:], h.recs)
+	}
+	return b
+}
+
+func (h *halfConn) overhead() int {
+	return h.base.BlockLen()
+}
+
+// Защита записи: header || body.
+func (h *halfConn) seal(typ byte, payload []byte) ([]byte, error) {
+	if err := h.maybeRekey(); err != nil {
+		return nil, err
+	}
+	hdr := h.header(typ, len(payload)+h.overhead())
+	var rec []byte
+	if h.mode == ModeMGM {
+		rec = h.aead.Seal(hdr, h.nonce(), payload, hdr)
+	} else {
+		ctr, err := mode.NewCTR(h.base, h.encKey, h.nonce())
+		if err != nil {
+			return nil, err
+		}
+		rec = append(hdr, payload...)
+		ctr.XORKeyStream(rec[HEADER_SIZE:], payload)
+		h.mac.Reset()
+		h.mac.Write(rec)
+		rec = h.mac.Sum(rec)
+	}
+	h.advance(len(payload))
+	return rec, nil
+}
+
+// Проверка и расшифрование записи. Номер записи должен совпадать с
+// ожидаемым: повтор, пропуск и перестановка записей отвергаются.
+func (h *halfConn) open(hdr, body []byte) (byte, []byte, error) {
+	if binary.BigEndian.Uint64(hdr[1:]) != h.seq {
+		return 0, nil, ErrReplay
+	}
+	if len(body) < h.overhead() {
+		return 0, nil, ErrBadRecord
+	}
+	if err := h.maybeRekey(); err != nil {
+		return 0, nil, err
+	}
+	var payload []byte
+	if h.mode == ModeMGM {
+		var err error
+		payload, err = h.aead.Open(nil, h.nonce(), body, hdr)
+		if err != nil {
+			return 0, nil, ErrBadRecord
+		}
+	} else {
+		ct, tag := body[:len(body)-h.overhead()], body[len(body)-h.overhead():]
+		h.mac.Reset()
+		h.mac.Write(hdr)
+		h.mac.Write(ct)
+		if subtle.ConstantTimeCompare(h.mac.Sum(nil), tag) != 1 {
+			return 0, nil, ErrBadRecord
+		}
+		ctr, err := mode.NewCTR(h.base, h.encKey, h.nonce())
+		if err != nil {
+			return 0, nil, err
+		}
+		payload = make([]byte, len(ct))
+		ctr.XORKeyStream(payload, ct)
+	}
+	h.advance(len(payload))
+	return hdr[0], payload, nil
+}
+
+func (h *halfConn) advance(n int) {
+	h.seq++
+	h.recs++
+	h.bytes += uint64(n)
+}
@@ -0,0 +1,270 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"gost_magma_cbc/crypto"
+	"gost_magma_cbc/crypto/gost3410"
+	"gost_magma_cbc/crypto/handshake"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func handshakeConfigs(t *testing.T) (*handshake.Config, *handshake.Config) {
+	t.Helper()
+	curve := gost3410.CurveTC26_256A
+	kc, err := gost3410.GenerateKey(curve, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := gost3410.GenerateKey(curve, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accept := func(*gost3410.PublicKey) error { return nil }
+	return &handshake.Config{Curve: curve, Key: kc, VerifyPeer: accept},
+		&handshake.Config{Curve: curve, Key: ks, VerifyPeer: accept}
+}
+
+// Эхо-сервер на локальном адресе: рукопожатие, затем возврат всех
+// полученных данных до закрытия канала клиентом.
+func echoServer(ln net.Listener, hs *handshake.Config, config Config, done chan<- error) {
+	raw, err := ln.Accept()
+	if err != nil {
+		done <- err
+		return
+	}
+	defer raw.Close()
+	srv, err := handshake.NewServer(hs)
+	if err != nil {
+		done <- err
+		return
+	}
+	keys, err := srv.Run(raw)
+	if err != nil {
+		done <- err
+		return
+	}
+	config.SetHandshakeKeys(keys, false)
+	conn, err := NewConn(raw, &config)
+	if err != nil {
+		done <- err
+		return
+	}
+	_, err = io.Copy(conn, conn)
+	if err == nil {
+		err = conn.Close()
+	}
+	done <- err
+}
+
+func TestLoopback(t *testing.T) {
+	for _, base := range []crypto.CryptoBase{crypto.BaseAlgorithmMagma, crypto.BaseAlgorithmKuznyechik} {
+		for _, m := range []Mode{ModeCTRMAC, ModeMGM} {
+			config := Config{Base: base, Mode: m, RekeyBytes: 50000, RekeyRecords: 5}
+			hsClient, hsServer := handshakeConfigs(t)
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			done := make(chan error, 1)
+			go echoServer(ln, hsServer, config, done)
+
+			raw, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			cli, err := handshake.NewClient(hsClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys, err := cli.Run(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cc := config
+			cc.SetHandshakeKeys(keys, true)
+			conn, err := NewConn(raw, &cc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, size := range []int{1, 15, 16, 17, 1000, MAX_PLAINTEXT, 3*MAX_PLAINTEXT + 5, 0, 7} {
+				msg := bytes.Repeat([]byte{byte(size)}, size)
+				for i := range msg {
+					msg[i] ^= byte(i)
+				}
+				if _, err := conn.Write(msg); err != nil {
+					t.Fatal(err)
+				}
+				got := make([]byte, size)
+				if _, err := io.ReadFull(conn, got); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, msg) {
+					t.Fatalf("[%d/%d] echo mismatch for %d bytes", base, m, size)
+				}
+			}
+			r, w := conn.Epochs()
+			if r < 2 || w < 2 || r != w {
+				t.Errorf("[%d/%d] incorrect rekey epochs %d, %d", base, m, r, w)
+			}
+			if err := conn.Close(); err != nil {
+				t.Error(err)
+			}
+			if err := <-done; err != nil {
+				t.Errorf("[%d/%d] server: %v", base, m, err)
+			}
+			ln.Close()
+		}
+	}
+}
+
+// Пара каналов с доступом к записям в открытом канале.
+func pipeConns(t *testing.T, m Mode) (*Conn, net.Conn, net.Conn, *Conn) {
+	t.Helper()
+	config := Config{
+		Base:  crypto.BaseAlgorithmKuznyechik,
+		Mode:  m,
+		Write: Secrets{Enc: bytes.Repeat([]byte{1}, 32), Mac: bytes.Repeat([]byte{2}, 32)},
+		Read:  Secrets{Enc: bytes.Repeat([]byte{3}, 32), Mac: bytes.Repeat([]byte{4}, 32)},
+	}
+	peer := config
+	peer.Write, peer.Read = config.Read, config.Write
+
+	a, wire := net.Pipe()
+	inject, b := net.Pipe()
+	ca, err := NewConn(a, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := NewConn(b, &peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca, wire, inject, cb
+}
+
+func readRaw(t *testing.T, c net.Conn) []byte {
+	t.Helper()
+	hdr := make([]byte, HEADER_SIZE)
+	if _, err := io.ReadFull(c, hdr); err != nil {
+		t.Fatal(err)
+	}
+	body := make([]byte, int(hdr[9])<<8|int(hdr[10]))
+	if _, err := io.ReadFull(c, body); err != nil {
+		t.Fatal(err)
+	}
+	return append(hdr, body...)
+}
+
+func TestReplayAndTamper(t *testing.T) {
+	for _, m := range []Mode{ModeCTRMAC, ModeMGM} {
+		tests := []struct {
+			name   string
+			modify func(first, second []byte) [][]byte
+			want   error
+		}{
+			{"replay", func(f, s []byte) [][]byte { return [][]byte{f, f} }, ErrReplay},
+			{"reorder", func(f, s []byte) [][]byte { return [][]byte{s, f} }, ErrReplay},
+			{"tamper body", func(f, s []byte) [][]byte { f[HEADER_SIZE] ^= 1; return [][]byte{f, s} }, ErrBadRecord},
+			{"tamper type", func(f, s []byte) [][]byte { f[0] = RECORD_CLOSE; return [][]byte{f, s} }, ErrBadRecord},
+			{"truncate", func(f, s []byte) [][]byte {
+				f = f[:len(f)-1]
+				f[10]--
+				return [][]byte{f, s}
+			}, ErrBadRecord},
+		}
+		for _, tt := range tests {
+			ca, wire, inject, cb := pipeConns(t, m)
+			go func() {
+				ca.Write([]byte("first"))
+				ca.Write([]byte("second"))
+			}()
+			recs := tt.modify(readRaw(t, wire), readRaw(t, wire))
+			go func() {
+				for _, r := range recs {
+					inject.Write(r)
+				}
+			}()
+
+			buf := make([]byte, 16)
+			var err error
+			for i := 0; i < 2 && err == nil; i++ {
+				_, err = cb.Read(buf)
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("[%d] %s: error %v, want %v", m, tt.name, err, tt.want)
+			}
+			if _, err2 := cb.Read(buf); err2 != err {
+				t.Errorf("[%d] %s: error is not sticky", m, tt.name)
+			}
+			wire.Close()
+			inject.Close()
+			ca.conn.Close()
+			cb.conn.Close()
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	s := Secrets{Enc: make([]byte, 32), Mac: make([]byte, 32)}
+	bad := []Config{
+		{Base: 5, Write: s, Read: s},
+		{Mode: 5, Write: s, Read: s},
+		{Write: Secrets{Enc: s.Enc}, Read: s},
+		{Write: s, Read: s, RekeyRecords: 1 << 33},
+	}
+	for i, config := range bad {
+		if _, err := NewConn(a, &config); err == nil {
+			t.Errorf("[%d] invalid config accepted", i)
+		}
+	}
+	config := Config{Mode: ModeMGM, Write: Secrets{Enc: s.Enc}, Read: Secrets{Enc: s.Enc}}
+	if _, err := NewConn(a, &config); err != nil {
+		t.Error(err)
+	}
+}
+
+// Close не блокируется, если собеседник не читает.
+func TestCloseStalled(t *testing.T) {
+	defer func(d time.Duration) { closeTimeout = d }(closeTimeout)
+	closeTimeout = 50 * time.Millisecond
+
+	// Запись закрытия без параллельного Write
+	ca, _, _, _ := pipeConns(t, ModeMGM)
+	done := make(chan error, 1)
+	go func() { done <- ca.Close() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("close blocked on close record")
+	}
+
+	// Параллельный Write, заблокированный собеседником
+	ca, _, _, _ = pipeConns(t, ModeMGM)
+	werr := make(chan error, 1)
+	go func() {
+		_, err := ca.Write(make([]byte, 4*MAX_PLAINTEXT))
+		werr <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	go func() { done <- ca.Close() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("close blocked on stalled write")
+	}
+	if err := <-werr; err == nil {
+		t.Error("stalled write succeeded")
+	}
+	if _, err := ca.Write([]byte{1}); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("write after close: %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package sespake
+
+import "gost_magma_cbc/crypto/protocol"
+
+// Типы сообщений протокола.
+const (
+	MSG_HELLO byte = 1 // A -> B: ID_A
+	MSG_SETUP byte = 2 // B -> A: ID_B, ind, salt
+	MSG_U1    byte = 3 // A -> B: u1
+	MSG_U2    byte = 4 // B -> A: u2
+	MSG_MAC_A byte = 5 // A -> B: MAC_A
+	MSG_MAC_B byte = 6 // B -> A: MAC_B
+	MSG_ABORT byte = 7 // прерывание обмена любой стороной
+)
+
+// Максимальная длина кадра сообщения.
+const MAX_MESSAGE_SIZE = 4096
+
+type Message = protocol.Message
+
+var proto = &protocol.Protocol{
+	MaxSize:       MAX_MESSAGE_SIZE,
+	Abort:         MSG_ABORT,
+	ErrAbort:      ErrAuthFailed,
+	ErrUnexpected: ErrUnexpected,
+}
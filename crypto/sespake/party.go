@@ -0,0 +1,371 @@
+package sespake
+
+import (
+	"crypto/subtle"
+	"errors"
+	"gost_magma_cbc/crypto/gost3410"
+	"io"
+	"math/big"
+)
+
+type state int
+
+const (
+	stateStart state = iota
+	stateWaitSetup
+	stateWaitU1
+	stateWaitU2
+	stateWaitMacA
+	stateWaitMacB
+	stateDone
+	stateFailed
+)
+
+// Сторона A, знающая пароль.
+type Client struct {
+	params   *Params
+	id       []byte
+	password []byte
+	rand     io.Reader
+	state    state
+
+	peerID []byte
+	ind    int
+	salt   []byte
+	qx, qy *big.Int
+	alpha  *big.Int
+	u1     []byte
+	u2     []byte
+	macA   []byte
+	key    []byte
+}
+
+// При rand == nil используется hdrbg.Reader.
+func NewClient(params *Params, id, password []byte, rand io.Reader) *Client {
+	return &Client{params: params, id: id, password: password, rand: rand}
+}
+
+// Первое сообщение обмена.
+func (c *Client) Start() (*Message, error) {
+	if c.state != stateStart {
+		return nil, ErrUnexpected
+	}
+	c.state = stateWaitSetup
+	return &Message{Type: MSG_HELLO, Fields: [][]byte{c.id}}, nil
+}
+
+// Обработка сообщения сервера. Возвращает ответ (nil по завершении).
+// При ошибке, если сервер ожидает ответа, возвращается сообщение
+// MSG_ABORT, которое следует отправить.
+func (c *Client) Next(m *Message) (*Message, error) {
+	var out *Message
+	var err error
+	switch c.state {
+	case stateWaitSetup:
+		out, err = c.onSetup(m)
+	case stateWaitU2:
+		out, err = c.onU2(m)
+	case stateWaitMacB:
+		err = c.onMacB(m)
+		if err != nil {
+			c.fail()
+			return nil, err
+		}
+		return nil, nil
+	default:
+		return nil, ErrUnexpected
+	}
+	if err != nil {
+		c.fail()
+		if m != nil && m.Type == MSG_ABORT {
+			return nil, err
+		}
+		return proto.AbortMessage(), err
+	}
+	return out, nil
+}
+
+func (c *Client) onSetup(m *Message) (*Message, error) {
+	if err := proto.Expect(m, MSG_SETUP, 3); err != nil {
+		return nil, err
+	}
+	if len(m.Fields[1]) != 1 || len(m.Fields[2]) != SALT_SIZE {
+		return nil, errors.New(PREFIX + "invalid setup message")
+	}
+	c.peerID = append([]byte(nil), m.Fields[0]...)
+	c.ind = int(m.Fields[1][0])
+	c.salt = append([]byte(nil), m.Fields[2]...)
+
+	var err error
+	c.qx, c.qy, err = passwordPoint(c.params, c.password, c.salt, c.ind)
+	if err != nil {
+		return nil, err
+	}
+	// u1 = alpha * P - Q_PW
+	curve := c.params.Curve
+	eph, err := gost3410.GenerateKey(curve, c.rand)
+	if err != nil {
+		return nil, err
+	}
+	c.alpha = eph.D
+	nx, ny := neg(curve, c.qx, c.qy)
+	ux, uy := curve.Add(eph.X, eph.Y, nx, ny)
+	if ux == nil {
+		return nil, errors.New(PREFIX + "degenerate point u1")
+	}
+	c.u1 = curve.Marshal(ux, uy)
+	c.state = stateWaitU2
+	return &Message{Type: MSG_U1, Fields: [][]byte{c.u1}}, nil
+}
+
+func (c *Client) onU2(m *Message) (*Message, error) {
+	if err := proto.Expect(m, MSG_U2, 1); err != nil {
+		return nil, err
+	}
+	curve := c.params.Curve
+	ux, uy, err := curve.Unmarshal(m.Fields[0])
+	if err != nil {
+		return nil, err
+	}
+	c.u2 = append([]byte(nil), m.Fields[0]...)
+
+	// Q_A = u2 - Q_PW, K_A = H(m/q * alpha * Q_A)
+	nx, ny := neg(curve, c.qx, c.qy)
+	ax, ay := curve.Add(ux, uy, nx, ny)
+	if ax == nil {
+		return nil, ErrAuthFailed
+	}
+	c.key, err = sessionKey(curve, c.alpha, ax, ay)
+	if err != nil {
+		return nil, err
+	}
+	c.macA = transcriptMAC(c.key, 0x01, c.id, c.ind, c.salt, c.u1, c.u2, nil)
+	c.state = stateWaitMacB
+	return &Message{Type: MSG_MAC_A, Fields: [][]byte{c.macA}}, nil
+}
+
+func (c *Client) onMacB(m *Message) error {
+	if err := proto.Expect(m, MSG_MAC_B, 1); err != nil {
+		return err
+	}
+	want := transcriptMAC(c.key, 0x02, c.peerID, c.ind, c.salt, c.u1, c.u2, c.macA)
+	if subtle.ConstantTimeCompare(want, m.Fields[0]) != 1 {
+		return ErrAuthFailed
+	}
+	c.state = stateDone
+	c.alpha.SetInt64(0)
+	return nil
+}
+
+func (c *Client) fail() {
+	c.state = stateFailed
+	if c.alpha != nil {
+		c.alpha.SetInt64(0)
+	}
+	clear(c.key)
+	c.key = nil
+}
+
+// Выработанный ключ K_A длины KEY_SIZE. Ключ доступен только после
+// проверки MAC_B и может быть загружен в manage.KeysManager методом
+// BuildFromBytes.
+func (c *Client) Key() ([]byte, error) {
+	if c.state != stateDone {
+		return nil, errors.New(PREFIX + "exchange is not completed")
+	}
+	return c.key, nil
+}
+
+// Идентификатор сервера, полученный в MSG_SETUP.
+func (c *Client) PeerID() []byte {
+	return c.peerID
+}
+
+// Выполнение обмена по соединению conn.
+func (c *Client) Run(conn io.ReadWriter) ([]byte, error) {
+	out, err := c.Start()
+	if err != nil {
+		return nil, err
+	}
+	return run(conn, out, c.Next, c.Key)
+}
+
+// Поиск верификатора по идентификатору клиента.
+type VerifierLookup func(id []byte) (*Verifier, error)
+
+// Сторона B, хранящая верификаторы паролей.
+type Server struct {
+	params *Params
+	id     []byte
+	lookup VerifierLookup
+	rand   io.Reader
+	state  state
+
+	peerID []byte
+	v      *Verifier
+	beta   *big.Int
+	u1     []byte
+	u2     []byte
+	key    []byte
+}
+
+// При rand == nil используется hdrbg.Reader.
+func NewServer(params *Params, id []byte, lookup VerifierLookup, rand io.Reader) *Server {
+	return &Server{params: params, id: id, lookup: lookup, rand: rand, state: stateStart}
+}
+
+// Обработка сообщения клиента; соглашения те же, что у Client.Next.
+func (s *Server) Next(m *Message) (*Message, error) {
+	var out *Message
+	var err error
+	switch s.state {
+	case stateStart:
+		out, err = s.onHello(m)
+	case stateWaitU1:
+		out, err = s.onU1(m)
+	case stateWaitMacA:
+		out, err = s.onMacA(m)
+	default:
+		return nil, ErrUnexpected
+	}
+	if err != nil {
+		s.fail()
+		if m != nil && m.Type == MSG_ABORT {
+			return nil, err
+		}
+		return proto.AbortMessage(), err
+	}
+	return out, nil
+}
+
+func (s *Server) onHello(m *Message) (*Message, error) {
+	if err := proto.Expect(m, MSG_HELLO, 1); err != nil {
+		return nil, err
+	}
+	v, err := s.lookup(m.Fields[0])
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errors.New(PREFIX + "unknown client")
+	}
+	if err := v.begin(); err != nil {
+		return nil, err
+	}
+	s.peerID = append([]byte(nil), m.Fields[0]...)
+	s.v = v
+	s.state = stateWaitU1
+	return &Message{Type: MSG_SETUP, Fields: [][]byte{s.id, {byte(v.Ind)}, v.Salt}}, nil
+}
+
+func (s *Server) onU1(m *Message) (*Message, error) {
+	if err := proto.Expect(m, MSG_U1, 1); err != nil {
+		return nil, err
+	}
+	curve := s.params.Curve
+	ux, uy, err := curve.Unmarshal(m.Fields[0])
+	if err != nil {
+		return nil, err
+	}
+	s.u1 = append([]byte(nil), m.Fields[0]...)
+
+	// Q_B = u1 + Q_PW
+	bx, by := curve.Add(ux, uy, s.v.X, s.v.Y)
+	if bx == nil {
+		return nil, ErrAuthFailed
+	}
+	// u2 = beta * P + Q_PW, K_B = H(m/q * beta * Q_B)
+	eph, err := gost3410.GenerateKey(curve, s.rand)
+	if err != nil {
+		return nil, err
+	}
+	s.beta = eph.D
+	s.key, err = sessionKey(curve, s.beta, bx, by)
+	if err != nil {
+		return nil, err
+	}
+	vx, vy := curve.Add(eph.X, eph.Y, s.v.X, s.v.Y)
+	if vx == nil {
+		return nil, errors.New(PREFIX + "degenerate point u2")
+	}
+	s.u2 = curve.Marshal(vx, vy)
+	s.state = stateWaitMacA
+	return &Message{Type: MSG_U2, Fields: [][]byte{s.u2}}, nil
+}
+
+func (s *Server) onMacA(m *Message) (*Message, error) {
+	if err := proto.Expect(m, MSG_MAC_A, 1); err != nil {
+		return nil, err
+	}
+	want := transcriptMAC(s.key, 0x01, s.peerID, s.v.Ind, s.v.Salt, s.u1, s.u2, nil)
+	if subtle.ConstantTimeCompare(want, m.Fields[0]) != 1 {
+		return nil, ErrAuthFailed
+	}
+	s.v.success()
+	macB := transcriptMAC(s.key, 0x02, s.id, s.v.Ind, s.v.Salt, s.u1, s.u2, m.Fields[0])
+	s.state = stateDone
+	s.beta.SetInt64(0)
+	return &Message{Type: MSG_MAC_B, Fields: [][]byte{macB}}, nil
+}
+
+func (s *Server) fail() {
+	s.state = stateFailed
+	if s.beta != nil {
+		s.beta.SetInt64(0)
+	}
+	clear(s.key)
+	s.key = nil
+}
+
+// Выработанный ключ K_B, доступен после проверки MAC_A.
+func (s *Server) Key() ([]byte, error) {
+	if s.state != stateDone {
+		return nil, errors.New(PREFIX + "exchange is not completed")
+	}
+	return s.key, nil
+}
+
+// Идентификатор клиента, полученный в MSG_HELLO.
+func (s *Server) PeerID() []byte {
+	return s.peerID
+}
+
+// Выполнение обмена по соединению conn.
+func (s *Server) Run(conn io.ReadWriter) ([]byte, error) {
+	in, err := proto.Read(conn)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.Next(in)
+	if err != nil {
+		if out != nil {
+			proto.Write(conn, out)
+		}
+		return nil, err
+	}
+	return run(conn, out, s.Next, s.Key)
+}
+
+// Обмен сообщениями до завершения: отправка out, приём ответа, next.
+func run(conn io.ReadWriter, out *Message, next func(*Message) (*Message, error), key func() ([]byte, error)) ([]byte, error) {
+	for out != nil {
+		if err := proto.Write(conn, out); err != nil {
+			return nil, err
+		}
+		if k, err := key(); err == nil {
+			return k, nil
+		}
+		in, err := proto.Read(conn)
+		if err != nil {
+			return nil, err
+		}
+		out, err = next(in)
+		if err != nil {
+			if out != nil {
+				proto.Write(conn, out)
+			}
+			return nil, err
+		}
+	}
+	return key()
+}
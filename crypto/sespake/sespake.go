@@ -0,0 +1,253 @@
+package sespake
+
+import (
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/gost3410"
+	"gost_magma_cbc/crypto/hash/hmac"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"gost_magma_cbc/crypto/kdf"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// Протокол выработки общего ключа с аутентификацией на основе пароля
+// по схеме SESPAKE. Сторона A (клиент) знает пароль, сторона B (сервер)
+// хранит только верификатор Q_PW = z * Q_ind.
+//
+// Порождение точек Q_i, вычисление ключа и входные данные имитовставок
+// определены этим пакетом и не совпадают с Р 50.1.115-2016, поэтому
+// обмен не совместим с реализациями стандарта.
+
+const PREFIX = "crypto:sespake: "
+
+// Число итераций PBKDF2 при вычислении z = F(PW, salt, n).
+const PBKDF2_ITERATIONS = 2000
+
+// Длина соли верификатора.
+const SALT_SIZE = 16
+
+// Длина общего ключа K_A = K_B.
+const KEY_SIZE = 32
+
+// Число незавершённых или неудачных попыток подряд, после которого
+// верификатор блокируется.
+const MAX_FAILED_ATTEMPTS = 10
+
+// Максимальное число точек Q_1, ..., Q_N (индекс передаётся одним байтом).
+const MAX_POINTS = 255
+
+// Префикс порождающих данных точек Q_i.
+const POINT_SEED = "crypto:sespake:Q"
+
+var (
+	ErrAuthFailed = errors.New(PREFIX + "authentication failed")
+	ErrBlocked    = errors.New(PREFIX + "verifier is blocked")
+	ErrUnexpected = errors.New(PREFIX + "unexpected message")
+)
+
+type point struct {
+	x, y *big.Int
+}
+
+// Параметры протокола: кривая с базовой точкой P и точки Q_1, ..., Q_N,
+// дискретный логарифм которых по основанию P неизвестен.
+type Params struct {
+	Curve  *gost3410.Curve
+	points []point
+}
+
+// Параметры с n точками, выработанными DerivePoint.
+func NewParams(curve *gost3410.Curve, n int) (*Params, error) {
+	if n < 1 || n > MAX_POINTS {
+		return nil, errors.New(PREFIX + "invalid number of points")
+	}
+	p := &Params{Curve: curve, points: make([]point, n)}
+	for i := range p.points {
+		x, y, err := DerivePoint(curve, i+1)
+		if err != nil {
+			return nil, err
+		}
+		p.points[i] = point{x, y}
+	}
+	return p, nil
+}
+
+func (p *Params) PointsCount() int {
+	return len(p.points)
+}
+
+// Точка Q_ind, 1 <= ind <= PointsCount().
+func (p *Params) Point(ind int) (*big.Int, *big.Int, error) {
+	if ind < 1 || ind > len(p.points) {
+		return nil, nil, errors.New(PREFIX + "invalid point index")
+	}
+	q := p.points[ind-1]
+	return q.x, q.y, nil
+}
+
+// Выработка точки Q_ind: X = Стрибог-512(POINT_SEED || имя кривой || ind ||
+// счётчик) mod p с перебором счётчика до квадратичного вычета X^3 + aX + b,
+// Y - чётный корень, точка умножается на кофактор. Процедура
+// детерминирована и проверяема, поэтому логарифм Q_ind никому не известен.
+func DerivePoint(curve *gost3410.Curve, ind int) (*big.Int, *big.Int, error) {
+	if ind < 1 || ind > MAX_POINTS {
+		return nil, nil, errors.New(PREFIX + "invalid point index")
+	}
+	var ctr [6]byte
+	binary.BigEndian.PutUint16(ctr[:], uint16(ind))
+	for i := uint32(0); i < 1<<16; i++ {
+		binary.BigEndian.PutUint32(ctr[2:], i)
+		h := streebog.New512()
+		h.Write([]byte(POINT_SEED))
+		h.Write([]byte(curve.Name))
+		h.Write(ctr[:])
+		x := leInt(h.Sum(nil))
+		x.Mod(x, curve.P)
+
+		r := new(big.Int).Mul(x, x)
+		r.Add(r, curve.A)
+		r.Mul(r, x)
+		r.Add(r, curve.B)
+		r.Mod(r, curve.P)
+		y := new(big.Int).ModSqrt(r, curve.P)
+		if y == nil {
+			continue
+		}
+		if y.Bit(0) == 1 {
+			y.Sub(curve.P, y)
+		}
+		qx, qy := curve.ScalarMult(curve.Co, x, y)
+		if qx == nil {
+			continue
+		}
+		return qx, qy, nil
+	}
+	return nil, nil, errors.New(PREFIX + "point derivation failed")
+}
+
+func leInt(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i, v := range b {
+		be[len(b)-1-i] = v
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// z = INT(F(PW, salt, n)) mod q, F - PBKDF2 с HMAC на Стрибог-512.
+func passwordScalar(curve *gost3410.Curve, password, salt []byte) (*big.Int, error) {
+	f := kdf.PBKDF2(streebog.New512, password, salt, PBKDF2_ITERATIONS, 64)
+	defer clear(f)
+	z := leInt(f)
+	z.Mod(z, curve.Q)
+	if z.Sign() == 0 {
+		return nil, errors.New(PREFIX + "degenerate password")
+	}
+	return z, nil
+}
+
+// Q_PW = z * Q_ind.
+func passwordPoint(params *Params, password, salt []byte, ind int) (*big.Int, *big.Int, error) {
+	qx, qy, err := params.Point(ind)
+	if err != nil {
+		return nil, nil, err
+	}
+	z, err := passwordScalar(params.Curve, password, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer z.SetInt64(0)
+	x, y := params.Curve.ScalarMultCT(z, qx, qy)
+	return x, y, nil
+}
+
+// Верификатор пароля на стороне сервера: индекс точки, соль и Q_PW.
+// Сам пароль сервером не хранится. Счётчик попыток общий для всех
+// сеансов с этим верификатором.
+type Verifier struct {
+	Ind  int
+	Salt []byte
+	X    *big.Int
+	Y    *big.Int
+
+	mtx      sync.Mutex
+	failures int
+}
+
+// Создание верификатора со случайной солью; при rand == nil
+// используется hdrbg.Reader.
+func NewVerifier(params *Params, password []byte, ind int, rand io.Reader) (*Verifier, error) {
+	if rand == nil {
+		rand = hdrbg.Reader
+	}
+	salt := make([]byte, SALT_SIZE)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return nil, err
+	}
+	x, y, err := passwordPoint(params, password, salt, ind)
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{Ind: ind, Salt: salt, X: x, Y: y}, nil
+}
+
+// Число незавершённых или неудачных попыток подряд.
+func (v *Verifier) Failures() int {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	return v.failures
+}
+
+// Счётчик увеличивается до проверки MAC_A, поэтому прерванный
+// на середине обмен также считается неудачной попыткой.
+func (v *Verifier) begin() error {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if v.failures >= MAX_FAILED_ATTEMPTS {
+		return ErrBlocked
+	}
+	v.failures++
+	return nil
+}
+
+func (v *Verifier) success() {
+	v.mtx.Lock()
+	v.failures = 0
+	v.mtx.Unlock()
+}
+
+// -Q
+func neg(c *gost3410.Curve, x, y *big.Int) (*big.Int, *big.Int) {
+	if x == nil || y.Sign() == 0 {
+		return x, y
+	}
+	return x, new(big.Int).Sub(c.P, y)
+}
+
+// K = Стрибог-256(m/q * (s * Q)), s - секретный скаляр стороны. Как и в
+// VKO, кофактор применяется после умножения на s.
+func sessionKey(c *gost3410.Curve, s, x, y *big.Int) ([]byte, error) {
+	x, y = c.ScalarMultCT(s, x, y)
+	x, y = c.ScalarMult(c.Co, x, y)
+	if x == nil {
+		return nil, ErrAuthFailed
+	}
+	h := streebog.New256()
+	h.Write(c.Marshal(x, y))
+	return h.Sum(nil), nil
+}
+
+// MAC = HMAC_GOSTR3411_2012_256(K, label || ID || ind || salt || u1 || u2 || extra).
+func transcriptMAC(k []byte, label byte, id []byte, ind int, salt, u1, u2, extra []byte) []byte {
+	h := hmac.New(streebog.New256, k)
+	h.Write([]byte{label})
+	h.Write(id)
+	h.Write([]byte{byte(ind)})
+	h.Write(salt)
+	h.Write(u1)
+	h.Write(u2)
+	h.Write(extra)
+	return h.Sum(nil)
+}
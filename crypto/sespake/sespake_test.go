@@ -0,0 +1,173 @@
+package sespake
+
+import (
+	"bytes"
+	"errors"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/gost3410"
+	"gost_magma_cbc/crypto/manage"
+	"net"
+	"testing"
+)
+
+type result struct {
+	key []byte
+	err error
+}
+
+// Полный обмен через net.Pipe.
+func exchange(t *testing.T, params *Params, v *Verifier, password []byte) (result, result) {
+	t.Helper()
+	lookup := func(id []byte) (*Verifier, error) {
+		if !bytes.Equal(id, []byte("device-01")) {
+			return nil, errors.New("unknown id")
+		}
+		return v, nil
+	}
+	ca, cb := net.Pipe()
+	defer ca.Close()
+	defer cb.Close()
+
+	done := make(chan result)
+	go func() {
+		s := NewServer(params, []byte("server"), lookup, nil)
+		k, err := s.Run(cb)
+		done <- result{k, err}
+	}()
+	c := NewClient(params, []byte("device-01"), password, nil)
+	k, err := c.Run(ca)
+	return result{k, err}, <-done
+}
+
+func TestExchange(t *testing.T) {
+	for _, curve := range []*gost3410.Curve{gost3410.CurveTC26_256A, gost3410.CurveTC26_512A} {
+		params, err := NewParams(curve, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pin := []byte("1234")
+		v, err := NewVerifier(params, pin, 2, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, b := exchange(t, params, v, pin)
+		if a.err != nil || b.err != nil {
+			t.Fatalf("%s: %v, %v", curve.Name, a.err, b.err)
+		}
+		if len(a.key) != KEY_SIZE || !bytes.Equal(a.key, b.key) {
+			t.Fatalf("%s: keys differ", curve.Name)
+		}
+		if v.Failures() != 0 {
+			t.Errorf("%s: failures counter is not reset", curve.Name)
+		}
+
+		// Загрузка общего ключа в менеджер ключей
+		km := manage.NewKeysManager(60)
+		key, err := km.GetNextKey(kuznyechik.NewKuznyechik(),
+			&manage.BuildData{Bytes: &a.key}, manage.BuildFromBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(key.Data(), b.key) {
+			t.Errorf("%s: incorrect managed key", curve.Name)
+		}
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	params, err := NewParams(gost3410.CurveTC26_256B, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(params, []byte("1234"), 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := exchange(t, params, v, []byte("1235"))
+	if !errors.Is(a.err, ErrAuthFailed) || !errors.Is(b.err, ErrAuthFailed) {
+		t.Fatalf("wrong password accepted: %v, %v", a.err, b.err)
+	}
+	if a.key != nil || b.key != nil {
+		t.Error("key returned after failure")
+	}
+	if v.Failures() != 1 {
+		t.Errorf("incorrect failures counter %d", v.Failures())
+	}
+
+	v.failures = MAX_FAILED_ATTEMPTS
+	a, b = exchange(t, params, v, []byte("1234"))
+	if !errors.Is(b.err, ErrBlocked) || !errors.Is(a.err, ErrAuthFailed) {
+		t.Fatalf("blocked verifier accepted: %v, %v", a.err, b.err)
+	}
+}
+
+func TestDerivePoint(t *testing.T) {
+	for _, curve := range []*gost3410.Curve{gost3410.CurveTC26_256A, gost3410.CurveTC26_256B, gost3410.CurveTC26_512C} {
+		params, err := NewParams(curve, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := map[string]bool{}
+		for i := 1; i <= params.PointsCount(); i++ {
+			x, y, _ := params.Point(i)
+			if _, err := gost3410.NewPublicKey(curve, x, y); err != nil {
+				t.Errorf("%s: Q_%d: %v", curve.Name, i, err)
+			}
+			x2, y2, _ := DerivePoint(curve, i)
+			if x.Cmp(x2) != 0 || y.Cmp(y2) != 0 {
+				t.Errorf("%s: Q_%d is not deterministic", curve.Name, i)
+			}
+			seen[x.String()] = true
+		}
+		if len(seen) != params.PointsCount() {
+			t.Errorf("%s: points are not distinct", curve.Name)
+		}
+		if _, _, err := params.Point(0); err == nil {
+			t.Error("point index 0 accepted")
+		}
+	}
+	if _, err := NewParams(gost3410.CurveTC26_256A, MAX_POINTS+1); err == nil {
+		t.Error("too many points accepted")
+	}
+}
+
+func TestInvalidPoint(t *testing.T) {
+	params, err := NewParams(gost3410.CurveTC26_256B, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewVerifier(params, []byte("1234"), 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := func([]byte) (*Verifier, error) { return v, nil }
+
+	// u1 вне кривой
+	s := NewServer(params, []byte("server"), lookup, nil)
+	if _, err := s.Next(&Message{Type: MSG_HELLO, Fields: [][]byte{[]byte("a")}}); err != nil {
+		t.Fatal(err)
+	}
+	bad := make([]byte, 64)
+	bad[0] = 1
+	out, err := s.Next(&Message{Type: MSG_U1, Fields: [][]byte{bad}})
+	if err == nil || out == nil || out.Type != MSG_ABORT {
+		t.Error("point not on curve accepted")
+	}
+
+	// u1 = -Q_PW дает Q_B = O
+	s = NewServer(params, []byte("server"), lookup, nil)
+	s.Next(&Message{Type: MSG_HELLO, Fields: [][]byte{[]byte("a")}})
+	nx, ny := neg(params.Curve, v.X, v.Y)
+	if _, err := s.Next(&Message{Type: MSG_U1, Fields: [][]byte{params.Curve.Marshal(nx, ny)}}); err == nil {
+		t.Error("degenerate u1 accepted")
+	}
+
+	// Сообщение вне очереди
+	s = NewServer(params, []byte("server"), lookup, nil)
+	if _, err := s.Next(&Message{Type: MSG_MAC_A, Fields: [][]byte{bad}}); !errors.Is(err, ErrUnexpected) {
+		t.Error("unexpected message accepted")
+	}
+	if _, err := s.Key(); err == nil {
+		t.Error("key available before completion")
+	}
+}
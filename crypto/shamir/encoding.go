@@ -0,0 +1,66 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"math/big"
+)
+
+// Представление доли:
+// MAGIC || ID || k || keyLen || f(ID) (64, big-endian) || C_0 || ... || C_{k-1} || H,
+// где C_j - точки X || Y (gost3410.Curve.Marshal), H - Стрибог-256
+// от всех предшествующих байт.
+var MAGIC = []byte("GSS1")
+
+const (
+	VALUE_SIZE    = 64
+	CHECKSUM_SIZE = 32
+)
+
+var ErrChecksum = errors.New(PREFIX + "share checksum mismatch")
+
+func checksum(b []byte) []byte {
+	h := streebog.New256()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+func (s *Share) Marshal() []byte {
+	b := append([]byte(nil), MAGIC...)
+	b = append(b, s.ID, s.Threshold, s.KeyLen)
+	b = append(b, s.Value.FillBytes(make([]byte, VALUE_SIZE))...)
+	for j := range s.X {
+		b = append(b, CURVE.Marshal(s.X[j], s.Y[j])...)
+	}
+	return append(b, checksum(b)...)
+}
+
+// Разбор доли с проверкой контрольной суммы. Проверка по
+// обязательствам выполняется Share.Verify.
+func UnmarshalShare(b []byte) (*Share, error) {
+	head := len(MAGIC) + 3
+	point := 2 * CURVE.PointSize
+	if len(b) < head+VALUE_SIZE+CHECKSUM_SIZE || !bytes.Equal(b[:len(MAGIC)], MAGIC) {
+		return nil, errors.New(PREFIX + "invalid share format")
+	}
+	body, sum := b[:len(b)-CHECKSUM_SIZE], b[len(b)-CHECKSUM_SIZE:]
+	if subtle.ConstantTimeCompare(checksum(body), sum) != 1 {
+		return nil, ErrChecksum
+	}
+	s := &Share{ID: b[len(MAGIC)], Threshold: b[len(MAGIC)+1], KeyLen: b[len(MAGIC)+2]}
+	if len(body) != head+VALUE_SIZE+int(s.Threshold)*point {
+		return nil, errors.New(PREFIX + "invalid share length")
+	}
+	s.Value = new(big.Int).SetBytes(body[head : head+VALUE_SIZE])
+	for off := head + VALUE_SIZE; off < len(body); off += point {
+		x, y, err := CURVE.Unmarshal(body[off : off+point])
+		if err != nil {
+			return nil, err
+		}
+		s.X = append(s.X, x)
+		s.Y = append(s.Y, y)
+	}
+	return s, nil
+}
@@ -0,0 +1,235 @@
+package shamir
+
+import (
+	"errors"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/gost3410"
+	"gost_magma_cbc/crypto/models"
+	"io"
+	"math/big"
+)
+
+// Разделение секрета Шамира над простым полем GF(q), где q - порядок
+// подгруппы кривой CURVE, с проверяемыми долями по схеме Фельдмана.
+// Ключ (k, n): f(x) = s + a_1 x + ... + a_{k-1} x^{k-1} mod q,
+// доля i - значение f(i), i = 1..n. Обязательства C_j = a_j P
+// (C_0 = s P) позволяют каждому держателю проверить свою долю:
+// f(i) P = sum(i^j C_j).
+
+const PREFIX = "crypto:shamir: "
+
+// Кривая обязательств; порядок подгруппы больше 2^511, поэтому ключ
+// до MAX_KEY_SIZE байт всегда является элементом поля.
+var CURVE = gost3410.CurveTC26_512A
+
+// C_0 = s P известно каждому держателю доли, поэтому ключ длиной L байт
+// находится по нему методом кенгуру примерно за 2^(4L) операций.
+// MIN_KEY_SIZE ограничивает эту оценку уровнем 2^128.
+const (
+	MIN_KEY_SIZE = 32
+	MAX_KEY_SIZE = 63
+	MAX_SHARES   = 255
+)
+
+var (
+	ErrVerify    = errors.New(PREFIX + "share verification failed")
+	ErrMismatch  = errors.New(PREFIX + "shares belong to different splits")
+	ErrThreshold = errors.New(PREFIX + "not enough shares")
+)
+
+// Доля секрета.
+type Share struct {
+	// Номер доли (точка x = ID).
+	ID byte
+	// Порог восстановления k.
+	Threshold byte
+	// Длина ключа в байтах.
+	KeyLen byte
+	// f(ID) mod q.
+	Value *big.Int
+	// Обязательства Фельдмана C_0..C_{k-1}: X[j], Y[j].
+	X []*big.Int
+	Y []*big.Int
+}
+
+func randomScalar(rand io.Reader) (*big.Int, error) {
+	// Избыточные 16 байт делают смещение по модулю q пренебрежимым
+	b := make([]byte, CURVE.PointSize+16)
+	for {
+		if _, err := io.ReadFull(rand, b); err != nil {
+			return nil, err
+		}
+		v := new(big.Int).SetBytes(b)
+		v.Mod(v, CURVE.Q)
+		if v.Sign() != 0 {
+			return v, nil
+		}
+	}
+}
+
+// f(x) mod q по схеме Горнера.
+func eval(coef []*big.Int, x int64) *big.Int {
+	bx := big.NewInt(x)
+	v := new(big.Int)
+	for j := len(coef) - 1; j >= 0; j-- {
+		v.Mul(v, bx)
+		v.Add(v, coef[j])
+		v.Mod(v, CURVE.Q)
+	}
+	return v
+}
+
+// Разделение ключа на n долей с порогом k. При rand == nil используется
+// hdrbg.Reader.
+func Split(key models.Key, n, k int, rand io.Reader) ([]*Share, error) {
+	if k < 2 || k > n || n > MAX_SHARES {
+		return nil, errors.New(PREFIX + "invalid threshold or shares count")
+	}
+	if key.Len() < MIN_KEY_SIZE || key.Len() > MAX_KEY_SIZE {
+		return nil, errors.New(PREFIX + "unsupported key len")
+	}
+	if rand == nil {
+		rand = hdrbg.Reader
+	}
+	coef := make([]*big.Int, k)
+	coef[0] = new(big.Int).SetBytes(key.Data())
+	defer func() {
+		for _, c := range coef {
+			if c != nil {
+				c.SetInt64(0)
+			}
+		}
+	}()
+	if coef[0].Sign() == 0 {
+		return nil, errors.New(PREFIX + "zero key")
+	}
+
+	var values []*big.Int
+	for values == nil {
+		for j := 1; j < k; j++ {
+			c, err := randomScalar(rand)
+			if err != nil {
+				return nil, err
+			}
+			coef[j] = c
+		}
+		values = make([]*big.Int, n)
+		for i := range values {
+			values[i] = eval(coef, int64(i+1))
+			// Нулевая доля не проверяется обязательствами
+			if values[i].Sign() == 0 {
+				values = nil
+				break
+			}
+		}
+	}
+
+	xs := make([]*big.Int, k)
+	ys := make([]*big.Int, k)
+	for j, c := range coef {
+		xs[j], ys[j] = CURVE.ScalarMultCT(c, CURVE.X, CURVE.Y)
+	}
+	shares := make([]*Share, n)
+	for i := range shares {
+		shares[i] = &Share{ID: byte(i + 1), Threshold: byte(k), KeyLen: byte(key.Len()),
+			Value: values[i], X: xs, Y: ys}
+	}
+	return shares, nil
+}
+
+// Проверка доли по обязательствам: f(ID) P = sum(ID^j C_j).
+func (s *Share) Verify() error {
+	if s.ID == 0 || s.Threshold < 2 || int(s.Threshold) != len(s.X) || len(s.X) != len(s.Y) ||
+		s.KeyLen < MIN_KEY_SIZE || s.KeyLen > MAX_KEY_SIZE {
+		return ErrVerify
+	}
+	if s.Value == nil || s.Value.Sign() <= 0 || s.Value.Cmp(CURVE.Q) >= 0 {
+		return ErrVerify
+	}
+	var rx, ry *big.Int
+	e := big.NewInt(1)
+	id := big.NewInt(int64(s.ID))
+	for j := range s.X {
+		px, py := CURVE.ScalarMult(e, s.X[j], s.Y[j])
+		rx, ry = CURVE.Add(rx, ry, px, py)
+		e.Mul(e, id)
+		e.Mod(e, CURVE.Q)
+	}
+	vx, vy := CURVE.ScalarMultCT(s.Value, CURVE.X, CURVE.Y)
+	if rx == nil || vx == nil || vx.Cmp(rx) != 0 || vy.Cmp(ry) != 0 {
+		return ErrVerify
+	}
+	return nil
+}
+
+// Доли относятся к одному разделению.
+func (s *Share) sameSplit(o *Share) bool {
+	if s.Threshold != o.Threshold || s.KeyLen != o.KeyLen || len(s.X) != len(o.X) {
+		return false
+	}
+	for j := range s.X {
+		if s.X[j].Cmp(o.X[j]) != 0 || s.Y[j].Cmp(o.Y[j]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Восстановление ключа по не менее чем k проверенным долям.
+func Combine(shares []*Share, key models.Key) error {
+	if len(shares) == 0 {
+		return ErrThreshold
+	}
+	first := shares[0]
+	seen := map[byte]bool{}
+	for _, s := range shares {
+		if !s.sameSplit(first) {
+			return ErrMismatch
+		}
+		if seen[s.ID] {
+			return errors.New(PREFIX + "duplicate share id")
+		}
+		seen[s.ID] = true
+		if err := s.Verify(); err != nil {
+			return err
+		}
+	}
+	if len(shares) < int(first.Threshold) {
+		return ErrThreshold
+	}
+	if key.Len() != int(first.KeyLen) {
+		return errors.New(PREFIX + "incorrect key len")
+	}
+
+	// Интерполяция Лагранжа в нуле: s = sum(y_i * prod(x_j / (x_j - x_i)))
+	used := shares[:first.Threshold]
+	secret := new(big.Int)
+	defer secret.SetInt64(0)
+	for i, si := range used {
+		num, den := big.NewInt(1), big.NewInt(1)
+		for j, sj := range used {
+			if i == j {
+				continue
+			}
+			num.Mul(num, big.NewInt(int64(sj.ID)))
+			num.Mod(num, CURVE.Q)
+			den.Mul(den, big.NewInt(int64(sj.ID)-int64(si.ID)))
+			den.Mod(den, CURVE.Q)
+		}
+		num.Mul(num, den.ModInverse(den, CURVE.Q))
+		num.Mul(num, si.Value)
+		secret.Add(secret, num)
+		secret.Mod(secret, CURVE.Q)
+	}
+
+	// Восстановленный секрет должен соответствовать C_0
+	x, y := CURVE.ScalarMultCT(secret, CURVE.X, CURVE.Y)
+	if x == nil || x.Cmp(first.X[0]) != 0 || y.Cmp(first.Y[0]) != 0 {
+		return ErrVerify
+	}
+	if secret.BitLen() > 8*key.Len() {
+		return ErrVerify
+	}
+	secret.FillBytes(key.Data())
+	return nil
+}
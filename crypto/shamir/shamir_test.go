@@ -0,0 +1,192 @@
+package shamir
+
+import (
+	"bytes"
+	"errors"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/manage"
+	"gost_magma_cbc/crypto/models"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Ключ произвольной длины.
+type testKey struct {
+	data []byte
+}
+
+func (k *testKey) GetPart(i int) any { return k.data[i] }
+func (k *testKey) Set(i int, v byte) { k.data[i] = v }
+func (k *testKey) PartLen() int      { return 1 }
+func (k *testKey) Len() int          { return len(k.data) }
+func (k *testKey) Data() []byte      { return k.data }
+func (k *testKey) Clear()            { clear(k.data) }
+
+func masterKey(t *testing.T) models.Key {
+	t.Helper()
+	km := manage.NewKeysManager(0)
+	key, err := km.GetNextKey(magma.NewMagma(), &manage.BuildData{}, manage.BuildFromRandom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSplitCombine(t *testing.T) {
+	key := masterKey(t)
+	shares, err := Split(key, 5, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range shares {
+		if err := s.Verify(); err != nil {
+			t.Fatalf("share %d: %v", s.ID, err)
+		}
+	}
+
+	// Любые 3 доли из 5
+	for a := 0; a < 5; a++ {
+		for b := a + 1; b < 5; b++ {
+			for c := b + 1; c < 5; c++ {
+				res := magma.NewMagma().NewKey()
+				if err := Combine([]*Share{shares[c], shares[a], shares[b]}, res); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(res.Data(), key.Data()) {
+					t.Fatalf("shares %d,%d,%d: incorrect key", a+1, b+1, c+1)
+				}
+			}
+		}
+	}
+	res := magma.NewMagma().NewKey()
+	if err := Combine(shares, res); err != nil || !bytes.Equal(res.Data(), key.Data()) {
+		t.Errorf("all shares: %v", err)
+	}
+	if err := Combine(shares[:2], res); !errors.Is(err, ErrThreshold) {
+		t.Errorf("two shares: %v", err)
+	}
+	if err := Combine([]*Share{shares[0], shares[0], shares[1]}, res); err == nil {
+		t.Error("duplicate shares accepted")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key := masterKey(t)
+	shares, err := Split(key, 3, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Подменённое значение доли обнаруживается по обязательствам
+	bad := *shares[1]
+	bad.Value = new(big.Int).Add(bad.Value, big.NewInt(1))
+	if err := bad.Verify(); !errors.Is(err, ErrVerify) {
+		t.Errorf("modified value: %v", err)
+	}
+	if err := Combine([]*Share{shares[0], &bad}, magma.NewMagma().NewKey()); !errors.Is(err, ErrVerify) {
+		t.Errorf("combine with modified value: %v", err)
+	}
+	bad = *shares[1]
+	bad.ID = 3
+	if err := bad.Verify(); !errors.Is(err, ErrVerify) {
+		t.Errorf("modified id: %v", err)
+	}
+
+	// Доли разных разделений
+	other, err := Split(key, 3, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Combine([]*Share{shares[0], other[1]}, magma.NewMagma().NewKey()); !errors.Is(err, ErrMismatch) {
+		t.Errorf("mixed splits: %v", err)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	key := masterKey(t)
+	shares, err := Split(key, 4, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := shares[2].Marshal()
+	if len(raw) != len(MAGIC)+3+VALUE_SIZE+3*2*CURVE.PointSize+CHECKSUM_SIZE {
+		t.Fatalf("share len %d", len(raw))
+	}
+	s, err := UnmarshalShare(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.sameSplit(shares[2]) || s.ID != 3 || s.Value.Cmp(shares[2].Value) != 0 {
+		t.Error("incorrect decoded share")
+	}
+
+	raw[len(MAGIC)+5] ^= 1
+	if _, err := UnmarshalShare(raw); !errors.Is(err, ErrChecksum) {
+		t.Errorf("modified share: %v", err)
+	}
+	if _, err := UnmarshalShare(raw[:40]); err == nil {
+		t.Error("short share accepted")
+	}
+	// Изменённая доля с пересчитанной контрольной суммой
+	raw[len(MAGIC)+5] ^= 1
+	raw[len(MAGIC)+10] ^= 1
+	body := raw[:len(raw)-CHECKSUM_SIZE]
+	s, err = UnmarshalShare(append(body, checksum(body)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(); !errors.Is(err, ErrVerify) {
+		t.Errorf("forged share: %v", err)
+	}
+}
+
+// Восстановленный ключ записывается в файл и загружается BuildFromFile.
+func TestKeyFile(t *testing.T) {
+	key := masterKey(t)
+	shares, err := Split(key, 3, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded []*Share
+	for _, s := range shares[1:] {
+		d, err := UnmarshalShare(s.Marshal())
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded = append(loaded, d)
+	}
+	res := magma.NewMagma().NewKey()
+	if err := Combine(loaded, res); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "master.key")
+	if err := os.WriteFile(path, res.Data(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	km := manage.NewKeysManager(0)
+	k, err := km.GetNextKey(magma.NewMagma(), &manage.BuildData{File: &path}, manage.BuildFromFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k.Data(), key.Data()) {
+		t.Error("incorrect key from file")
+	}
+}
+
+func TestSplitParams(t *testing.T) {
+	key := masterKey(t)
+	for _, p := range [][2]int{{3, 1}, {2, 3}, {256, 2}} {
+		if _, err := Split(key, p[0], p[1], nil); err == nil {
+			t.Errorf("n=%d, k=%d accepted", p[0], p[1])
+		}
+	}
+	if _, err := Split(magma.NewMagma().NewKey(), 3, 2, nil); err == nil {
+		t.Error("zero key accepted")
+	}
+	short := &testKey{data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}}
+	if _, err := Split(short, 3, 2, nil); err == nil {
+		t.Error("short key accepted")
+	}
+}
@@ -0,0 +1,29 @@
+package stattest
+
+import "errors"
+
+// Преобразование байтов в последовательность бит (старший бит первым).
+func BitsFromBytes(data []byte) []byte {
+	bits := make([]byte, len(data)*8)
+	for i, b := range data {
+		for j := 0; j < 8; j++ {
+			bits[i*8+j] = (b >> (7 - j)) & 1
+		}
+	}
+	return bits
+}
+
+// Разбор строки из символов '0' и '1'.
+func ParseBits(s string) ([]byte, error) {
+	bits := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '0':
+		case '1':
+			bits[i] = 1
+		default:
+			return nil, errors.New(PREFIX + "invalid bit string")
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,78 @@
+package stattest
+
+import "math"
+
+var linearComplexityProbs = []float64{0.010417, 0.03125, 0.125, 0.5, 0.25, 0.0625, 0.020833}
+
+// Тест линейной сложности в блоках длины m (SP 800-22, 2.10).
+func LinearComplexity(bits []byte, m int) (float64, error) {
+	if m <= 0 {
+		return 0, ErrInvalidParam
+	}
+	n := len(bits) / m
+	if n == 0 {
+		return 0, ErrTooShort
+	}
+	sign := 1.0
+	if m%2 == 1 {
+		sign = -1
+	}
+	mu := float64(m)/2 + (9-sign)/36 - (float64(m)/3+2.0/9)/math.Pow(2, float64(m))
+
+	v := make([]int, len(linearComplexityProbs))
+	for i := 0; i < n; i++ {
+		t := sign*(float64(berlekampMassey(bits[i*m:(i+1)*m]))-mu) + 2.0/9
+		switch {
+		case t <= -2.5:
+			v[0]++
+		case t <= -1.5:
+			v[1]++
+		case t <= -0.5:
+			v[2]++
+		case t <= 0.5:
+			v[3]++
+		case t <= 1.5:
+			v[4]++
+		case t <= 2.5:
+			v[5]++
+		default:
+			v[6]++
+		}
+	}
+
+	chi2 := 0.0
+	for i := range v {
+		e := float64(n) * linearComplexityProbs[i]
+		chi2 += (float64(v[i]) - e) * (float64(v[i]) - e) / e
+	}
+	return igamc(float64(len(v)-1)/2, chi2/2), nil
+}
+
+// Линейная сложность последовательности (алгоритм Берлекэмпа-Месси).
+func berlekampMassey(s []byte) int {
+	n := len(s)
+	c := make([]byte, n+1)
+	b := make([]byte, n+1)
+	t := make([]byte, n+1)
+	c[0], b[0] = 1, 1
+	l, m := 0, -1
+	for i := 0; i < n; i++ {
+		d := s[i]
+		for j := 1; j <= l; j++ {
+			d ^= c[j] & s[i-j]
+		}
+		if d == 0 {
+			continue
+		}
+		copy(t, c)
+		for j := 0; j+i-m <= n; j++ {
+			c[j+i-m] ^= b[j]
+		}
+		if l <= i/2 {
+			l = i + 1 - l
+			m = i
+			copy(b, t)
+		}
+	}
+	return l
+}
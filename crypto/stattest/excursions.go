@@ -0,0 +1,104 @@
+package stattest
+
+import "math"
+
+// Минимальное число циклов случайного блуждания для тестов случайных
+// отклонений (SP 800-22, 2.14.7).
+const MIN_EXCURSION_CYCLES = 500
+
+// Случайное блуждание S' = 0, S_1, ..., S_n, 0 и число циклов J
+// (завершающий ноль не добавляется, если S_n = 0).
+func randomWalk(bits []byte) ([]int, int) {
+	walk := make([]int, len(bits)+1, len(bits)+2)
+	for i, b := range bits {
+		walk[i+1] = walk[i] + 2*int(b) - 1
+	}
+	if walk[len(bits)] != 0 {
+		walk = append(walk, 0)
+	}
+	cycles := 0
+	for _, s := range walk[1:] {
+		if s == 0 {
+			cycles++
+		}
+	}
+	return walk, cycles
+}
+
+func checkCycles(bits []byte, cycles int) error {
+	if cycles < max(MIN_EXCURSION_CYCLES, int(0.005*math.Sqrt(float64(len(bits))))) {
+		return ErrNotApplicable
+	}
+	return nil
+}
+
+// Тест случайных отклонений для состояний x = -4..-1, 1..4
+// (SP 800-22, 2.14). Возвращает восемь P-значений.
+func RandomExcursions(bits []byte) ([]float64, error) {
+	walk, cycles := randomWalk(bits)
+	if err := checkCycles(bits, cycles); err != nil {
+		return nil, err
+	}
+	states := []int{-4, -3, -2, -1, 1, 2, 3, 4}
+	res := make([]float64, len(states))
+	for i, x := range states {
+		res[i] = excursion(walk, cycles, x)
+	}
+	return res, nil
+}
+
+func excursion(walk []int, cycles, x int) float64 {
+	// v[k] - число циклов, в которых состояние x встретилось k раз (5 - не менее 5)
+	var v [6]int
+	visits := 0
+	for _, s := range walk[1:] {
+		if s == x {
+			visits++
+		} else if s == 0 {
+			v[min(visits, 5)]++
+			visits = 0
+		}
+	}
+
+	ax := math.Abs(float64(x))
+	var pi [6]float64
+	pi[0] = 1 - 1/(2*ax)
+	for k := 1; k < 5; k++ {
+		pi[k] = 1 / (4 * ax * ax) * math.Pow(1-1/(2*ax), float64(k-1))
+	}
+	pi[5] = 1 / (2 * ax) * math.Pow(1-1/(2*ax), 4)
+
+	chi2 := 0.0
+	for k := range v {
+		e := float64(cycles) * pi[k]
+		chi2 += (float64(v[k]) - e) * (float64(v[k]) - e) / e
+	}
+	return igamc(2.5, chi2/2)
+}
+
+// Вариант теста случайных отклонений для состояний x = -9..-1, 1..9
+// (SP 800-22, 2.15). Возвращает восемнадцать P-значений.
+func RandomExcursionsVariant(bits []byte) ([]float64, error) {
+	walk, cycles := randomWalk(bits)
+	if err := checkCycles(bits, cycles); err != nil {
+		return nil, err
+	}
+	var res []float64
+	for x := -9; x <= 9; x++ {
+		if x != 0 {
+			res = append(res, excursionVariant(walk, cycles, x))
+		}
+	}
+	return res, nil
+}
+
+func excursionVariant(walk []int, cycles, x int) float64 {
+	visits := 0
+	for _, s := range walk {
+		if s == x {
+			visits++
+		}
+	}
+	ax := math.Abs(float64(x))
+	return math.Erfc(math.Abs(float64(visits-cycles)) / math.Sqrt(2*float64(cycles)*(4*ax-2)))
+}
@@ -0,0 +1,150 @@
+package stattest
+
+import "math"
+
+// Частотный (монобитный) тест (SP 800-22, 2.1).
+func Frequency(bits []byte) (float64, error) {
+	n := len(bits)
+	if n == 0 {
+		return 0, ErrTooShort
+	}
+	s := 0
+	for _, b := range bits {
+		s += 2*int(b) - 1
+	}
+	sObs := math.Abs(float64(s)) / math.Sqrt(float64(n))
+	return math.Erfc(sObs / math.Sqrt2), nil
+}
+
+// Частотный тест в блоках длины m (SP 800-22, 2.2).
+func BlockFrequency(bits []byte, m int) (float64, error) {
+	if m <= 0 {
+		return 0, ErrInvalidParam
+	}
+	n := len(bits) / m
+	if n == 0 {
+		return 0, ErrTooShort
+	}
+	chi2 := 0.0
+	for i := 0; i < n; i++ {
+		ones := 0
+		for _, b := range bits[i*m : (i+1)*m] {
+			ones += int(b)
+		}
+		pi := float64(ones)/float64(m) - 0.5
+		chi2 += pi * pi
+	}
+	chi2 *= 4 * float64(m)
+	return igamc(float64(n)/2, chi2/2), nil
+}
+
+// Тест на последовательности одинаковых бит (SP 800-22, 2.3).
+func Runs(bits []byte) (float64, error) {
+	n := len(bits)
+	if n < 2 {
+		return 0, ErrTooShort
+	}
+	ones := 0
+	for _, b := range bits {
+		ones += int(b)
+	}
+	pi := float64(ones) / float64(n)
+	// Предварительный частотный тест
+	if math.Abs(pi-0.5) >= 2/math.Sqrt(float64(n)) {
+		return 0, nil
+	}
+	v := 1
+	for i := 1; i < n; i++ {
+		if bits[i] != bits[i-1] {
+			v++
+		}
+	}
+	num := math.Abs(float64(v) - 2*float64(n)*pi*(1-pi))
+	den := 2 * math.Sqrt(2*float64(n)) * pi * (1 - pi)
+	return math.Erfc(num / den), nil
+}
+
+// Тест на самую длинную последовательность единиц в блоке (SP 800-22, 2.4).
+func LongestRun(bits []byte) (float64, error) {
+	n := len(bits)
+	var m, low int
+	var pi []float64
+	switch {
+	case n < 128:
+		return 0, ErrTooShort
+	case n < 6272:
+		m, low = 8, 1
+		pi = []float64{0.2148, 0.3672, 0.2305, 0.1875}
+	case n < 750000:
+		m, low = 128, 4
+		pi = []float64{0.1174, 0.2430, 0.2493, 0.1752, 0.1027, 0.1124}
+	default:
+		m, low = 10000, 10
+		pi = []float64{0.0882, 0.2092, 0.2483, 0.1933, 0.1208, 0.0675, 0.0727}
+	}
+	k := len(pi) - 1
+	blocks := n / m
+
+	v := make([]int, len(pi))
+	for i := 0; i < blocks; i++ {
+		longest, run := 0, 0
+		for _, b := range bits[i*m : (i+1)*m] {
+			if b == 1 {
+				run++
+				longest = max(longest, run)
+			} else {
+				run = 0
+			}
+		}
+		v[min(max(longest-low, 0), k)]++
+	}
+
+	chi2 := 0.0
+	for i := range v {
+		e := float64(blocks) * pi[i]
+		chi2 += (float64(v[i]) - e) * (float64(v[i]) - e) / e
+	}
+	return igamc(float64(k)/2, chi2/2), nil
+}
+
+// Тест кумулятивных сумм в прямом и обратном направлениях (SP 800-22, 2.13).
+func CumulativeSums(bits []byte) ([]float64, error) {
+	n := len(bits)
+	if n == 0 {
+		return nil, ErrTooShort
+	}
+	forward, backward := 0, 0
+	zf, zb := 0, 0
+	for i := 0; i < n; i++ {
+		forward += 2*int(bits[i]) - 1
+		backward += 2*int(bits[n-1-i]) - 1
+		zf = max(zf, abs(forward))
+		zb = max(zb, abs(backward))
+	}
+	return []float64{cusumPValue(n, zf), cusumPValue(n, zb)}, nil
+}
+
+func cusumPValue(n, z int) float64 {
+	if z == 0 {
+		return 0
+	}
+	sqrtN := math.Sqrt(float64(n))
+	sum1 := 0.0
+	for k := (-n/z + 1) / 4; k <= (n/z-1)/4; k++ {
+		sum1 += normal(float64((4*k+1)*z) / sqrtN)
+		sum1 -= normal(float64((4*k-1)*z) / sqrtN)
+	}
+	sum2 := 0.0
+	for k := (-n/z - 3) / 4; k <= (n/z-1)/4; k++ {
+		sum2 += normal(float64((4*k+3)*z) / sqrtN)
+		sum2 -= normal(float64((4*k+1)*z) / sqrtN)
+	}
+	return 1 - sum1 + sum2
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
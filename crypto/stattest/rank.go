@@ -0,0 +1,70 @@
+package stattest
+
+import "math"
+
+// Вероятности полного ранга, ранга на единицу меньше и остальных рангов
+// для матриц 32x32 (SP 800-22, 3.5).
+var rankProbs = [3]float64{0.2888, 0.5776, 0.1336}
+
+// Тест рангов двоичных матриц 32x32 (SP 800-22, 2.5).
+func Rank(bits []byte) (float64, error) {
+	if len(bits)/(32*32) < 38 {
+		return 0, ErrTooShort
+	}
+	return rankTest(bits, 32, 32), nil
+}
+
+func rankTest(bits []byte, m, q int) float64 {
+	n := len(bits) / (m * q)
+	var f [3]int
+	rows := make([]uint64, m)
+	for i := 0; i < n; i++ {
+		block := bits[i*m*q:]
+		for r := 0; r < m; r++ {
+			rows[r] = 0
+			for c := 0; c < q; c++ {
+				rows[r] = rows[r]<<1 | uint64(block[r*q+c])
+			}
+		}
+		switch rank(rows, q) {
+		case min(m, q):
+			f[0]++
+		case min(m, q) - 1:
+			f[1]++
+		default:
+			f[2]++
+		}
+	}
+	chi2 := 0.0
+	for i := range f {
+		e := rankProbs[i] * float64(n)
+		chi2 += (float64(f[i]) - e) * (float64(f[i]) - e) / e
+	}
+	return math.Exp(-chi2 / 2)
+}
+
+// Ранг двоичной матрицы над GF(2); строки задаются q младшими битами.
+func rank(rows []uint64, q int) int {
+	r := 0
+	for c := q - 1; c >= 0 && r < len(rows); c-- {
+		bit := uint64(1) << c
+		pivot := -1
+		for i := r; i < len(rows); i++ {
+			if rows[i]&bit != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		rows[r], rows[pivot] = rows[pivot], rows[r]
+		for i := range rows {
+			if i != r && rows[i]&bit != 0 {
+				rows[i] ^= rows[r]
+			}
+		}
+		r++
+	}
+	return r
+}
@@ -0,0 +1,81 @@
+package stattest
+
+import "math"
+
+// Частоты всех пересекающихся m-битовых шаблонов в последовательности,
+// дополненной циклически первыми m-1 битами.
+func patternCounts(bits []byte, m int) []int {
+	counts := make([]int, 1<<m)
+	if m == 0 {
+		return counts
+	}
+	n := len(bits)
+	mask := 1<<m - 1
+	v := 0
+	for i := 0; i < m-1; i++ {
+		v = v<<1 | int(bits[i])
+	}
+	for i := 0; i < n; i++ {
+		v = (v<<1 | int(bits[(i+m-1)%n])) & mask
+		counts[v]++
+	}
+	return counts
+}
+
+func psi2(bits []byte, m int) float64 {
+	if m <= 0 {
+		return 0
+	}
+	n := float64(len(bits))
+	sum := 0.0
+	for _, c := range patternCounts(bits, m) {
+		sum += float64(c) * float64(c)
+	}
+	return sum*math.Pow(2, float64(m))/n - n
+}
+
+// Последовательный тест для шаблонов длины m (SP 800-22, 2.11).
+// Возвращает два P-значения.
+func Serial(bits []byte, m int) ([]float64, error) {
+	if m < 2 {
+		return nil, ErrInvalidParam
+	}
+	if len(bits) < m {
+		return nil, ErrTooShort
+	}
+	p0 := psi2(bits, m)
+	p1 := psi2(bits, m-1)
+	p2 := psi2(bits, m-2)
+	del1 := p0 - p1
+	del2 := p0 - 2*p1 + p2
+	return []float64{
+		igamc(math.Pow(2, float64(m-2)), del1/2),
+		igamc(math.Pow(2, float64(m-3)), del2/2),
+	}, nil
+}
+
+func phi(bits []byte, m int) float64 {
+	n := float64(len(bits))
+	sum := 0.0
+	for _, c := range patternCounts(bits, m) {
+		if c > 0 {
+			p := float64(c) / n
+			sum += p * math.Log(p)
+		}
+	}
+	return sum
+}
+
+// Тест приближённой энтропии для шаблонов длины m (SP 800-22, 2.12).
+func ApproximateEntropy(bits []byte, m int) (float64, error) {
+	if m < 1 {
+		return 0, ErrInvalidParam
+	}
+	n := len(bits)
+	if n <= m {
+		return 0, ErrTooShort
+	}
+	apEn := phi(bits, m) - phi(bits, m+1)
+	chi2 := 2 * float64(n) * (math.Ln2 - apEn)
+	return igamc(math.Pow(2, float64(m-1)), chi2/2), nil
+}
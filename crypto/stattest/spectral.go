@@ -0,0 +1,28 @@
+package stattest
+
+import "math"
+
+// Спектральный тест на основе дискретного преобразования Фурье
+// (SP 800-22, 2.6).
+func Spectral(bits []byte) (float64, error) {
+	n := len(bits)
+	if n < 2 {
+		return 0, ErrTooShort
+	}
+	x := make([]complex128, n)
+	for i, b := range bits {
+		x[i] = complex(2*float64(b)-1, 0)
+	}
+	s := dft(x)
+
+	t := math.Sqrt(math.Log(1/0.05) * float64(n))
+	n0 := 0.95 * float64(n) / 2
+	n1 := 0
+	for j := 0; j < n/2; j++ {
+		if math.Hypot(real(s[j]), imag(s[j])) < t {
+			n1++
+		}
+	}
+	d := (float64(n1) - n0) / math.Sqrt(float64(n)*0.95*0.05/4)
+	return math.Erfc(math.Abs(d) / math.Sqrt2), nil
+}
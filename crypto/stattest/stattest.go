@@ -0,0 +1,182 @@
+// Набор статистических тестов NIST SP 800-22 rev. 1a для проверки
+// выхода генераторов псевдослучайных последовательностей.
+package stattest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const PREFIX = "crypto:stattest: "
+
+// Уровень значимости по умолчанию.
+const DEFAULT_ALPHA = 0.01
+
+var (
+	ErrTooShort      = errors.New(PREFIX + "sequence is too short")
+	ErrNotApplicable = errors.New(PREFIX + "test is not applicable")
+	ErrInvalidParam  = errors.New(PREFIX + "invalid test parameter")
+)
+
+// Статистический тест над последовательностью бит (по одному биту в байте).
+type Test struct {
+	Name string
+	Run  func(bits []byte) ([]float64, error)
+}
+
+// Результат выполнения теста. Err != nil означает, что тест не выполнялся
+// (например, последовательность слишком коротка).
+type Result struct {
+	Name    string
+	PValues []float64
+	Err     error
+}
+
+// Число P-значений не меньше alpha.
+func (r *Result) PassedCount(alpha float64) int {
+	n := 0
+	for _, p := range r.PValues {
+		if p >= alpha {
+			n++
+		}
+	}
+	return n
+}
+
+// Тест пройден, если доля P-значений не меньше alpha попадает в
+// доверительный интервал (1 - alpha) - 3 * sqrt(alpha * (1 - alpha) / k)
+// (SP 800-22, 4.2.1). Для одного P-значения это означает p >= alpha.
+func (r *Result) Passed(alpha float64) bool {
+	if r.Err != nil || len(r.PValues) == 0 {
+		return false
+	}
+	k := float64(len(r.PValues))
+	min := (1 - alpha) - 3*math.Sqrt(alpha*(1-alpha)/k)
+	return float64(r.PassedCount(alpha))/k >= min
+}
+
+// Наименьшее P-значение теста.
+func (r *Result) MinPValue() float64 {
+	min := math.Inf(1)
+	for _, p := range r.PValues {
+		min = math.Min(min, p)
+	}
+	return min
+}
+
+// Итог выполнения набора тестов.
+type Report struct {
+	Alpha   float64
+	Bits    int
+	Results []Result
+}
+
+// Все тесты выполнены и пройдены. Невыполненный тест (Err != nil) считается
+// непройденным.
+func (r *Report) Passed() bool {
+	for i := range r.Results {
+		if !r.Results[i].Passed(r.Alpha) {
+			return false
+		}
+	}
+	return true
+}
+
+// Хотя бы один тест не выполнялся, итог набора не определён.
+func (r *Report) Inconclusive() bool {
+	for i := range r.Results {
+		if r.Results[i].Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Вывод таблицы результатов.
+func (r *Report) Summary(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "NIST SP 800-22: %d bits, alpha = %g\n", r.Bits, r.Alpha); err != nil {
+		return err
+	}
+	passed, total := 0, 0
+	for i := range r.Results {
+		res := &r.Results[i]
+		var err error
+		switch {
+		case res.Err != nil:
+			total++
+			_, err = fmt.Fprintf(w, "%-28s %-10s SKIP (%s)\n", res.Name, "-", res.Err.Error())
+		case len(res.PValues) == 1:
+			total++
+			status := "FAIL"
+			if res.Passed(r.Alpha) {
+				status = "PASS"
+				passed++
+			}
+			_, err = fmt.Fprintf(w, "%-28s %-10.6f %s\n", res.Name, res.PValues[0], status)
+		default:
+			total++
+			status := "FAIL"
+			if res.Passed(r.Alpha) {
+				status = "PASS"
+				passed++
+			}
+			_, err = fmt.Fprintf(w, "%-28s %-10.6f %s (%d/%d, min p-value)\n", res.Name, res.MinPValue(), status,
+				res.PassedCount(r.Alpha), len(res.PValues))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "passed %d/%d\n", passed, total)
+	return err
+}
+
+// Выполнение тестов над последовательностью бит.
+func Run(bits []byte, alpha float64, tests []Test) *Report {
+	if alpha <= 0 || alpha >= 1 {
+		alpha = DEFAULT_ALPHA
+	}
+	report := &Report{Alpha: alpha, Bits: len(bits)}
+	for _, t := range tests {
+		p, err := t.Run(bits)
+		report.Results = append(report.Results, Result{Name: t.Name, PValues: p, Err: err})
+	}
+	return report
+}
+
+// Полный набор из 15 тестов с параметрами, рекомендованными SP 800-22
+// для последовательностей длиной порядка 10^6 бит.
+func DefaultTests() []Test {
+	return []Test{
+		{"Frequency", single(Frequency)},
+		{"BlockFrequency", func(b []byte) ([]float64, error) { return one(BlockFrequency(b, 128)) }},
+		{"Runs", single(Runs)},
+		{"LongestRun", single(LongestRun)},
+		{"Rank", single(Rank)},
+		{"FFT", single(Spectral)},
+		{"NonOverlappingTemplate", func(b []byte) ([]float64, error) { return NonOverlappingTemplate(b, 9) }},
+		{"OverlappingTemplate", func(b []byte) ([]float64, error) { return one(OverlappingTemplate(b, 9)) }},
+		{"Universal", single(Universal)},
+		{"LinearComplexity", func(b []byte) ([]float64, error) { return one(LinearComplexity(b, 500)) }},
+		{"Serial", func(b []byte) ([]float64, error) { return Serial(b, 16) }},
+		{"ApproximateEntropy", func(b []byte) ([]float64, error) { return one(ApproximateEntropy(b, 10)) }},
+		{"CumulativeSums", CumulativeSums},
+		{"RandomExcursions", RandomExcursions},
+		{"RandomExcursionsVariant", RandomExcursionsVariant},
+	}
+}
+
+func single(f func([]byte) (float64, error)) func([]byte) ([]float64, error) {
+	return func(b []byte) ([]float64, error) {
+		return one(f(b))
+	}
+}
+
+func one(p float64, err error) ([]float64, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []float64{p}, nil
+}
@@ -0,0 +1,186 @@
+package stattest
+
+import (
+	"bytes"
+	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/hash/streebog"
+	"math"
+	"strings"
+	"testing"
+)
+
+func mustBits(t *testing.T, s string) []byte {
+	b, err := ParseBits(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func checkP(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	checkPWithin(t, name, got, want, 1e-5)
+}
+
+func checkPWithin(t *testing.T, name string, got, want, eps float64) {
+	t.Helper()
+	if math.Abs(got-want) > eps {
+		t.Errorf("%s: got p-value %f, want %f", name, got, want)
+	}
+}
+
+// Примеры из описания тестов SP 800-22 rev. 1a.
+func TestExamples(t *testing.T) {
+	p, _ := Frequency(mustBits(t, "1011010101"))
+	checkP(t, "Frequency", p, 0.527089)
+
+	p, _ = BlockFrequency(mustBits(t, "0110011010"), 3)
+	checkP(t, "BlockFrequency", p, 0.801252)
+
+	p, _ = Runs(mustBits(t, "1001101011"))
+	checkP(t, "Runs", p, 0.147232)
+
+	p, _ = LongestRun(mustBits(t, "11001100000101010110110001001100111000000000001001001101010100010001001111010110100000001101011111001100111001101101100010110010"))
+	// В документе χ² округлён до 4.882457
+	checkPWithin(t, "LongestRun", p, 0.180609, 1e-4)
+
+	checkP(t, "Rank", rankTest(mustBits(t, "01011001001010101101"), 3, 3), 0.741948)
+
+	// Все пять модулей |S_j| = 0, 2, 4.47, 2, 4.47 меньше T = 5.473, поэтому
+	// N1 = 5 (в разобранном примере документа указано N1 = 4)
+	p, _ = Spectral(mustBits(t, "1001010011"))
+	checkP(t, "FFT", p, math.Erfc(0.25/math.Sqrt(10*0.95*0.05/4)/math.Sqrt2))
+
+	checkP(t, "NonOverlappingTemplate", nonOverlapping(mustBits(t, "10100100101110010110"), 0b001, 3, 2), 0.344154)
+
+	ps, _ := Serial(mustBits(t, "0011011101"), 3)
+	checkP(t, "Serial 1", ps[0], 0.808792)
+	checkP(t, "Serial 2", ps[1], 0.670320)
+
+	p, _ = ApproximateEntropy(mustBits(t, "0100110101"), 3)
+	checkP(t, "ApproximateEntropy", p, 0.261961)
+
+	ps, _ = CumulativeSums(mustBits(t, "1011010111"))
+	checkP(t, "CumulativeSums", ps[0], 0.4116588)
+
+	walk, cycles := randomWalk(mustBits(t, "0110110101"))
+	if cycles != 3 {
+		t.Errorf("incorrect cycles count %d", cycles)
+	}
+	checkP(t, "RandomExcursions", excursion(walk, cycles, 1), 0.502488)
+	checkP(t, "RandomExcursionsVariant", excursionVariant(walk, cycles, 1), 0.683091)
+}
+
+func TestUniversalStatistic(t *testing.T) {
+	fn, k := universalStatistic(mustBits(t, "01011010011101010111"), 2, 4)
+	if k != 6 || math.Abs(fn-1.1949875) > 1e-6 {
+		t.Errorf("incorrect statistic %f for %d blocks", fn, k)
+	}
+}
+
+func TestBerlekampMassey(t *testing.T) {
+	if l := berlekampMassey(mustBits(t, "1101011110001")); l != 4 {
+		t.Errorf("incorrect linear complexity %d", l)
+	}
+}
+
+func TestAperiodicTemplates(t *testing.T) {
+	// Число шаблонов из набора NIST для m = 2..9
+	counts := map[int]int{2: 2, 3: 4, 4: 6, 5: 12, 6: 20, 7: 40, 8: 74, 9: 148}
+	for m, c := range counts {
+		if n := len(AperiodicTemplates(m)); n != c {
+			t.Errorf("m = %d: got %d templates, want %d", m, n, c)
+		}
+	}
+}
+
+func TestOverlappingProbs(t *testing.T) {
+	sum := 0.0
+	for _, p := range overlappingProbs(10, OVERLAPPING_BLOCK_SIZE, 5) {
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-12 {
+		t.Errorf("probabilities sum %f", sum)
+	}
+}
+
+func TestDFT(t *testing.T) {
+	x := []complex128{1, -1, 1, 1, -1, 1, -1, -1, 1, 1, 1}
+	got := dft(x)
+	for k := range x {
+		var want complex128
+		for j := range x {
+			angle := -2 * math.Pi * float64(j*k) / float64(len(x))
+			want += x[j] * complex(math.Cos(angle), math.Sin(angle))
+		}
+		if math.Abs(real(got[k]-want)) > 1e-9 || math.Abs(imag(got[k]-want)) > 1e-9 {
+			t.Errorf("[%d] got %v, want %v", k, got[k], want)
+		}
+	}
+}
+
+func TestReportDrbg(t *testing.T) {
+	if testing.Short() {
+		t.Skip("long test")
+	}
+	// Детерминированный выход Hash_DRBG, чтобы результат не зависел от запуска
+	hd, err := hdrbg.NewHashDrbg(streebog.New256, hdrbg.SECURITY_LEVEL_ONE,
+		bytes.Repeat([]byte{0x5a}, 32), bytes.Repeat([]byte{0xa5}, 16), []byte("stattest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1<<17)
+	for i := 0; i < len(data); i += hdrbg.MAX_BYTES_PER_GENERATE {
+		if err := hd.Generate(data[i:i+hdrbg.MAX_BYTES_PER_GENERATE], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report := Run(BitsFromBytes(data), DEFAULT_ALPHA, DefaultTests())
+	var out strings.Builder
+	if err := report.Summary(&out); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + out.String())
+	if !report.Passed() {
+		t.Errorf("drbg output fails tests")
+	}
+	for _, r := range report.Results {
+		if r.Err != nil && r.Err != ErrTooShort && r.Err != ErrNotApplicable {
+			t.Errorf("%s: %v", r.Name, r.Err)
+		}
+	}
+}
+
+func TestReportBiased(t *testing.T) {
+	data := bytes.Repeat([]byte{0x55}, 1<<12)
+	report := Run(BitsFromBytes(data), DEFAULT_ALPHA, DefaultTests())
+	if report.Passed() {
+		t.Error("periodic sequence passes tests")
+	}
+}
+
+func TestReportInconclusive(t *testing.T) {
+	// Для 1024 бит часть тестов не выполняется
+	data := make([]byte, 128)
+	for i := range data {
+		data[i] = byte(i*167 + 13)
+	}
+	report := Run(BitsFromBytes(data), DEFAULT_ALPHA, DefaultTests())
+	if !report.Inconclusive() {
+		t.Fatal("short sequence is not inconclusive")
+	}
+	if report.Passed() {
+		t.Error("skipped tests are counted as passed")
+	}
+}
+
+func TestBits(t *testing.T) {
+	bits := BitsFromBytes([]byte{0x81, 0x40})
+	if !bytes.Equal(bits, mustBits(t, "1000000101000000")) {
+		t.Errorf("incorrect bits %v", bits)
+	}
+	if _, err := ParseBits("012"); err == nil {
+		t.Error("invalid bit string accepted")
+	}
+}
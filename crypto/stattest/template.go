@@ -0,0 +1,140 @@
+package stattest
+
+import "math"
+
+// Число блоков в тесте непересекающихся шаблонов.
+const NON_OVERLAPPING_BLOCKS = 8
+
+// Длина блока в тесте пересекающихся шаблонов.
+const OVERLAPPING_BLOCK_SIZE = 1032
+
+// Непериодические шаблоны длины m (не совпадающие ни с одним своим
+// собственным сдвигом), старший бит значения - первый бит шаблона.
+func AperiodicTemplates(m int) []uint32 {
+	var res []uint32
+	for t := uint32(0); t < 1<<m; t++ {
+		aperiodic := true
+		for k := 1; k < m && aperiodic; k++ {
+			// Префикс длины m-k совпадает с суффиксом той же длины
+			if t>>k == t&(1<<(m-k)-1) {
+				aperiodic = false
+			}
+		}
+		if aperiodic {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// Тест непересекающихся шаблонов для всех непериодических шаблонов
+// длины m (SP 800-22, 2.7). Возвращает P-значение для каждого шаблона.
+func NonOverlappingTemplate(bits []byte, m int) ([]float64, error) {
+	if m < 2 || m > 21 {
+		return nil, ErrInvalidParam
+	}
+	if len(bits)/NON_OVERLAPPING_BLOCKS <= m {
+		return nil, ErrTooShort
+	}
+	templates := AperiodicTemplates(m)
+	res := make([]float64, len(templates))
+	for i, t := range templates {
+		res[i] = nonOverlapping(bits, t, m, NON_OVERLAPPING_BLOCKS)
+	}
+	return res, nil
+}
+
+func nonOverlapping(bits []byte, template uint32, m, blocks int) float64 {
+	size := len(bits) / blocks
+	mu := float64(size-m+1) / math.Pow(2, float64(m))
+	sigma2 := float64(size) * (1/math.Pow(2, float64(m)) - float64(2*m-1)/math.Pow(2, float64(2*m)))
+	mask := uint32(1)<<m - 1
+
+	chi2 := 0.0
+	for i := 0; i < blocks; i++ {
+		block := bits[i*size : (i+1)*size]
+		w := 0
+		var window uint32
+		filled := 0
+		for _, b := range block {
+			window = (window<<1 | uint32(b)) & mask
+			filled++
+			if filled >= m && window == template {
+				w++
+				// Поиск продолжается после найденного шаблона
+				filled = 0
+			}
+		}
+		chi2 += (float64(w) - mu) * (float64(w) - mu) / sigma2
+	}
+	return igamc(float64(blocks)/2, chi2/2)
+}
+
+// Вероятности числа вхождений шаблона из m=9 единиц в блок из 1032 бит
+// (уточнённые значения SP 800-22 rev. 1a, 3.8).
+var overlappingProbs9 = []float64{0.364091, 0.185659, 0.139381, 0.100571, 0.070432, 0.139865}
+
+// Тест пересекающихся шаблонов для шаблона из m единиц (SP 800-22, 2.8).
+func OverlappingTemplate(bits []byte, m int) (float64, error) {
+	if m < 2 || m > OVERLAPPING_BLOCK_SIZE {
+		return 0, ErrInvalidParam
+	}
+	blocks := len(bits) / OVERLAPPING_BLOCK_SIZE
+	if blocks == 0 {
+		return 0, ErrTooShort
+	}
+	pi := overlappingProbs9
+	if m != 9 {
+		pi = overlappingProbs(m, OVERLAPPING_BLOCK_SIZE, 5)
+	}
+	k := len(pi) - 1
+
+	v := make([]int, len(pi))
+	for i := 0; i < blocks; i++ {
+		block := bits[i*OVERLAPPING_BLOCK_SIZE : (i+1)*OVERLAPPING_BLOCK_SIZE]
+		w, run := 0, 0
+		for _, b := range block {
+			if b == 1 {
+				run++
+				if run >= m {
+					w++
+				}
+			} else {
+				run = 0
+			}
+		}
+		v[min(w, k)]++
+	}
+
+	chi2 := 0.0
+	for i := range v {
+		e := float64(blocks) * pi[i]
+		chi2 += (float64(v[i]) - e) * (float64(v[i]) - e) / e
+	}
+	return igamc(float64(k)/2, chi2/2), nil
+}
+
+// Приближённые вероятности числа вхождений (0..k-1 и не менее k) шаблона
+// из m единиц в блок длины size (SP 800-22, 3.8).
+func overlappingProbs(m, size, k int) []float64 {
+	lambda := float64(size-m+1) / math.Pow(2, float64(m))
+	eta := lambda / 2
+	pi := make([]float64, k+1)
+	sum := 0.0
+	for u := 0; u < k; u++ {
+		if u == 0 {
+			pi[u] = math.Exp(-eta)
+		} else {
+			for l := 1; l <= u; l++ {
+				lg1, _ := math.Lgamma(float64(l + 1))
+				lg2, _ := math.Lgamma(float64(u))
+				lg3, _ := math.Lgamma(float64(l))
+				lg4, _ := math.Lgamma(float64(u - l + 1))
+				pi[u] += math.Exp(-eta - float64(u)*math.Ln2 + float64(l)*math.Log(eta) - lg1 + lg2 - lg3 - lg4)
+			}
+		}
+		sum += pi[u]
+	}
+	pi[k] = 1 - sum
+	return pi
+}
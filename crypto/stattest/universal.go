@@ -0,0 +1,58 @@
+package stattest
+
+import "math"
+
+// Ожидаемое значение и дисперсия статистики универсального теста
+// для L = 1..16 (SP 800-22, 2.9.4).
+var universalExpected = [17]float64{0, 0.73264948, 1.5374383, 2.40160681, 3.31122472, 4.25342659, 5.2177052,
+	6.1962507, 7.1836656, 8.1764248, 9.1723243, 10.170032, 11.168765, 12.168070, 13.167693, 14.167488, 15.167379}
+var universalVariance = [17]float64{0, 0.690, 1.338, 1.901, 2.358, 2.705, 2.954, 3.125, 3.238, 3.311, 3.356,
+	3.384, 3.401, 3.410, 3.416, 3.419, 3.421}
+
+// Минимальные длины последовательности для L = 6..16.
+var universalMinLength = [...]int{387840, 904960, 2068480, 4654080, 10342400, 22753280, 49643520,
+	107560960, 231669760, 496435200, 1059061760}
+
+// Универсальный статистический тест Маурера (SP 800-22, 2.9) с длиной
+// блока L по длине последовательности и Q = 10 * 2^L.
+func Universal(bits []byte) (float64, error) {
+	l := 0
+	for i, n := range universalMinLength {
+		if len(bits) >= n {
+			l = 6 + i
+		}
+	}
+	if l == 0 {
+		return 0, ErrTooShort
+	}
+	q := 10 << l
+	fn, k := universalStatistic(bits, l, q)
+
+	c := 0.7 - 0.8/float64(l) + (4+32/float64(l))*math.Pow(float64(k), -3/float64(l))/15
+	sigma := c * math.Sqrt(universalVariance[l]/float64(k))
+	return math.Erfc(math.Abs(fn-universalExpected[l]) / (math.Sqrt2 * sigma)), nil
+}
+
+// Статистика f_n для блоков длины l: q блоков инициализации и
+// k = n/l - q проверочных блоков.
+func universalStatistic(bits []byte, l, q int) (float64, int) {
+	k := len(bits)/l - q
+	table := make([]int, 1<<l)
+	block := func(i int) int {
+		v := 0
+		for _, b := range bits[i*l : (i+1)*l] {
+			v = v<<1 | int(b)
+		}
+		return v
+	}
+	for i := 1; i <= q; i++ {
+		table[block(i-1)] = i
+	}
+	sum := 0.0
+	for i := q + 1; i <= q+k; i++ {
+		v := block(i - 1)
+		sum += math.Log2(float64(i - table[v]))
+		table[v] = i
+	}
+	return sum / float64(k), k
+}
@@ -0,0 +1,271 @@
+package tlsgost
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/kdf"
+	"gost_magma_cbc/crypto/mode"
+	"gost_magma_cbc/crypto/models"
+)
+
+// Защита записей TLS 1.2 для наборов RFC 9189 в режиме CTR_OMAC:
+// MAC-then-Encrypt с ключами записи, вырабатываемыми TLSTREE по
+// номеру записи.
+
+const PREFIX = "crypto:tlsgost: "
+
+type CipherSuite uint16
+
+const (
+	TLS_GOSTR341112_256_WITH_KUZNYECHIK_CTR_OMAC CipherSuite = 0xC100
+	TLS_GOSTR341112_256_WITH_MAGMA_CTR_OMAC      CipherSuite = 0xC101
+)
+
+// Версия протокола TLS 1.2.
+const VERSION_TLS12 = 0x0303
+
+// Заголовок записи: type || version || length.
+const HEADER_SIZE = 5
+
+// Максимальная длина фрагмента открытого текста и TLSCiphertext.fragment.
+const (
+	MAX_PLAINTEXT  = 1 << 14
+	MAX_CIPHERTEXT = MAX_PLAINTEXT + 2048
+)
+
+// Длины ключей MAC и шифрования из key_block.
+const KEY_SIZE = 32
+
+var ErrBadRecordMAC = errors.New(PREFIX + "bad record mac")
+
+// Параметры набора: базовый шифр, длина IV (половина блока) и маски TLSTREE.
+type Suite struct {
+	ID      CipherSuite
+	Name    string
+	NewBase func() models.BaseAlgorithm
+	IVLen   int
+	MACLen  int
+	Tree    kdf.TLSTreeParams
+}
+
+var suites = []*Suite{
+	{
+		ID:      TLS_GOSTR341112_256_WITH_KUZNYECHIK_CTR_OMAC,
+		Name:    "TLS_GOSTR341112_256_WITH_KUZNYECHIK_CTR_OMAC",
+		NewBase: kuznyechik.NewKuznyechik,
+		IVLen:   8,
+		MACLen:  16,
+		Tree:    kdf.TLSTreeKuznyechik,
+	},
+	{
+		ID:      TLS_GOSTR341112_256_WITH_MAGMA_CTR_OMAC,
+		Name:    "TLS_GOSTR341112_256_WITH_MAGMA_CTR_OMAC",
+		NewBase: magma.NewMagma,
+		IVLen:   4,
+		MACLen:  8,
+		Tree:    kdf.TLSTreeMagma,
+	},
+}
+
+func SuiteByID(id CipherSuite) (*Suite, error) {
+	for _, s := range suites {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, errors.New(PREFIX + "unsupported cipher suite")
+}
+
+// Состояние защиты одного направления соединения (write или read):
+// корневые ключи client/server_write_MAC_key и _key, IV и номер записи.
+type Protection struct {
+	suite   *Suite
+	base    models.BaseAlgorithm
+	macTree *kdf.TLSTree
+	encTree *kdf.TLSTree
+	iv      []byte
+	seq     uint64
+	done    bool
+}
+
+// macKey и encKey - по KEY_SIZE байт, iv - Suite.IVLen байт из key_block.
+// Номер записи начинается с 0 после ChangeCipherSpec.
+func NewProtection(id CipherSuite, macKey, encKey, iv []byte) (*Protection, error) {
+	suite, err := SuiteByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(macKey) != KEY_SIZE || len(encKey) != KEY_SIZE {
+		return nil, errors.New(PREFIX + "invalid key length")
+	}
+	if len(iv) != suite.IVLen {
+		return nil, errors.New(PREFIX + "invalid iv length")
+	}
+	macTree, err := kdf.NewTLSTree(suite.Tree, macKey)
+	if err != nil {
+		return nil, err
+	}
+	encTree, err := kdf.NewTLSTree(suite.Tree, encKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Protection{
+		suite:   suite,
+		base:    suite.NewBase(),
+		macTree: macTree,
+		encTree: encTree,
+		iv:      append([]byte(nil), iv...),
+	}, nil
+}
+
+func (p *Protection) Suite() *Suite {
+	return p.suite
+}
+
+// Номер следующей записи.
+func (p *Protection) Seq() uint64 {
+	return p.seq
+}
+
+// Ключ шифра из строки байт k (старший байт первым) в представлении
+// базового алгоритма (little-endian).
+func (p *Protection) loadKey(k []byte) models.Key {
+	key := p.base.NewKey()
+	d := key.Data()
+	for i := range k {
+		d[len(k)-1-i] = k[i]
+	}
+	clear(k)
+	return key
+}
+
+// IV^seqnum = (IV + seqnum) mod 2^(n/2).
+func (p *Protection) recordIV() []byte {
+	iv := make([]byte, len(p.iv))
+	var carry uint64
+	seq := p.seq
+	for i := len(iv) - 1; i >= 0; i-- {
+		v := uint64(p.iv[i]) + seq&0xff + carry
+		iv[i] = byte(v)
+		carry = v >> 8
+		seq >>= 8
+	}
+	return iv
+}
+
+// MAC = OMAC(K_MAC^seqnum, seqnum || type || version || length || fragment).
+func (p *Protection) mac(hdr, fragment []byte) ([]byte, error) {
+	k, err := p.macTree.Derive(p.seq)
+	if err != nil {
+		return nil, err
+	}
+	key := p.loadKey(k)
+	defer key.Clear()
+	m, err := mode.NewOMAC(p.base, key, p.suite.MACLen)
+	if err != nil {
+		return nil, err
+	}
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], p.seq)
+	m.Write(seq[:])
+	m.Write(hdr[:3])
+	var l [2]byte
+	binary.BigEndian.PutUint16(l[:], uint16(len(fragment)))
+	m.Write(l[:])
+	m.Write(fragment)
+	return m.Sum(nil), nil
+}
+
+// Шифрование CTR(K_ENC^seqnum, IV^seqnum) на месте.
+func (p *Protection) crypt(data []byte) error {
+	k, err := p.encTree.Derive(p.seq)
+	if err != nil {
+		return err
+	}
+	key := p.loadKey(k)
+	defer key.Clear()
+	ctr, err := mode.NewCTR(p.base, key, p.recordIV())
+	if err != nil {
+		return err
+	}
+	ctr.XORKeyStream(data, data)
+	return nil
+}
+
+func (p *Protection) next() error {
+	if p.done {
+		return errors.New(PREFIX + "sequence number exhausted")
+	}
+	p.seq++
+	if p.seq == 0 {
+		p.done = true
+	}
+	return nil
+}
+
+// Защита фрагмента: TLSCiphertext = type || version || length ||
+// CTR(fragment || MAC).
+func (p *Protection) Seal(typ uint8, version uint16, fragment []byte) ([]byte, error) {
+	if p.done {
+		return nil, errors.New(PREFIX + "sequence number exhausted")
+	}
+	if len(fragment) > MAX_PLAINTEXT {
+		return nil, errors.New(PREFIX + "fragment is too long")
+	}
+	rec := make([]byte, HEADER_SIZE, HEADER_SIZE+len(fragment)+p.suite.MACLen)
+	rec[0] = typ
+	binary.BigEndian.PutUint16(rec[1:], version)
+	mac, err := p.mac(rec, fragment)
+	if err != nil {
+		return nil, err
+	}
+	rec = append(rec, fragment...)
+	rec = append(rec, mac...)
+	binary.BigEndian.PutUint16(rec[3:], uint16(len(rec)-HEADER_SIZE))
+	if err := p.crypt(rec[HEADER_SIZE:]); err != nil {
+		return nil, err
+	}
+	return rec, p.next()
+}
+
+// Проверка и расшифрование записи. При ошибке номер записи не
+// изменяется, соединение следует закрыть с bad_record_mac.
+func (p *Protection) Open(record []byte) (uint8, []byte, error) {
+	if p.done {
+		return 0, nil, errors.New(PREFIX + "sequence number exhausted")
+	}
+	if len(record) < HEADER_SIZE {
+		return 0, nil, errors.New(PREFIX + "truncated record")
+	}
+	n := int(binary.BigEndian.Uint16(record[3:]))
+	if n != len(record)-HEADER_SIZE || n > MAX_CIPHERTEXT {
+		return 0, nil, errors.New(PREFIX + "invalid record length")
+	}
+	if n < p.suite.MACLen || n-p.suite.MACLen > MAX_PLAINTEXT {
+		return 0, nil, ErrBadRecordMAC
+	}
+	data := append([]byte(nil), record[HEADER_SIZE:]...)
+	if err := p.crypt(data); err != nil {
+		return 0, nil, err
+	}
+	fragment, tag := data[:n-p.suite.MACLen], data[n-p.suite.MACLen:]
+	mac, err := p.mac(record, fragment)
+	if err != nil {
+		return 0, nil, err
+	}
+	if subtle.ConstantTimeCompare(mac, tag) != 1 {
+		clear(data)
+		return 0, nil, ErrBadRecordMAC
+	}
+	return record[0], fragment, p.next()
+}
+
+// Зануление корневых ключей.
+func (p *Protection) Clear() {
+	p.macTree.Clear()
+	p.encTree.Clear()
+	clear(p.iv)
+}
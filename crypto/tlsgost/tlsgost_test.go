@@ -0,0 +1,168 @@
+package tlsgost
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"gost_magma_cbc/crypto/kdf"
+	"gost_magma_cbc/crypto/mode"
+	"gost_magma_cbc/crypto/models"
+	"testing"
+)
+
+var (
+	testMacKey = bytes.Repeat([]byte{0x11}, KEY_SIZE)
+	testEncKey = bytes.Repeat([]byte{0x22}, KEY_SIZE)
+)
+
+func newPair(t *testing.T, id CipherSuite, iv []byte) (*Protection, *Protection) {
+	t.Helper()
+	w, err := NewProtection(id, testMacKey, testEncKey, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewProtection(id, testMacKey, testEncKey, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, r
+}
+
+// Ключ записи по определению TLSTREE без кэширования промежуточных ключей.
+func treeKey(t *testing.T, params kdf.TLSTreeParams, root []byte, seq uint64) []byte {
+	t.Helper()
+	key := root
+	for j, c := range []uint64{params.C1, params.C2, params.C3} {
+		var d [8]byte
+		binary.BigEndian.PutUint64(d[:], seq&c)
+		k, err := kdf.NewKDF256().Create(key, []byte{'l', 'e', 'v', 'e', 'l', byte('1' + j)}, d[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		key = k
+	}
+	return key
+}
+
+func cipherKey(base models.BaseAlgorithm, k []byte) models.Key {
+	key := base.NewKey()
+	for i := range k {
+		key.Data()[len(k)-1-i] = k[i]
+	}
+	return key
+}
+
+// Эталонная запись по описанию RFC 9189: MAC-then-Encrypt, TLSTREE,
+// IV^seqnum = IV + seqnum.
+func referenceRecord(t *testing.T, s *Suite, iv []byte, seq uint64, typ uint8, fragment []byte) []byte {
+	t.Helper()
+	base := s.NewBase()
+	m, err := mode.NewOMAC(base, cipherKey(base, treeKey(t, s.Tree, testMacKey, seq)), s.MACLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	macInput := binary.BigEndian.AppendUint64(nil, seq)
+	macInput = append(macInput, typ, 0x03, 0x03)
+	macInput = binary.BigEndian.AppendUint16(macInput, uint16(len(fragment)))
+	m.Write(append(macInput, fragment...))
+	data := append(append([]byte(nil), fragment...), m.Sum(nil)...)
+
+	ivSeq := make([]byte, 8)
+	copy(ivSeq[8-len(iv):], iv)
+	v := binary.BigEndian.Uint64(ivSeq) + seq
+	binary.BigEndian.PutUint64(ivSeq, v)
+	ctr, err := mode.NewCTR(base, cipherKey(base, treeKey(t, s.Tree, testEncKey, seq)), ivSeq[8-len(iv):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctr.XORKeyStream(data, data)
+	rec := []byte{typ, 0x03, 0x03}
+	rec = binary.BigEndian.AppendUint16(rec, uint16(len(data)))
+	return append(rec, data...)
+}
+
+func TestSealOpen(t *testing.T) {
+	for _, s := range suites {
+		iv := bytes.Repeat([]byte{0xa5}, s.IVLen)
+		w, r := newPair(t, s.ID, iv)
+		// Номера записей проходят границу маски C3 набора с Кузнечиком
+		for seq := uint64(0); seq < 80; seq++ {
+			fragment := bytes.Repeat([]byte{byte(seq)}, int(seq*7%50))
+			rec, err := w.Seal(23, VERSION_TLS12, fragment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := referenceRecord(t, s, iv, seq, 23, fragment); !bytes.Equal(rec, want) {
+				t.Fatalf("%s: record %d differs from reference", s.Name, seq)
+			}
+			typ, got, err := r.Open(rec)
+			if err != nil {
+				t.Fatalf("%s: record %d: %v", s.Name, seq, err)
+			}
+			if typ != 23 || !bytes.Equal(got, fragment) {
+				t.Fatalf("%s: record %d mismatch", s.Name, seq)
+			}
+		}
+		if w.Seq() != 80 || r.Seq() != 80 {
+			t.Errorf("%s: incorrect sequence numbers", s.Name)
+		}
+	}
+}
+
+func TestBadRecord(t *testing.T) {
+	for _, s := range suites {
+		w, r := newPair(t, s.ID, make([]byte, s.IVLen))
+		first, _ := w.Seal(23, VERSION_TLS12, []byte("first record"))
+		second, _ := w.Seal(23, VERSION_TLS12, []byte("second record"))
+
+		// Пропуск записи: номер 0 не совпадает с номером записи 1
+		if _, _, err := r.Open(second); !errors.Is(err, ErrBadRecordMAC) {
+			t.Errorf("%s: reordered record accepted", s.Name)
+		}
+		for i := 0; i < len(first); i++ {
+			bad := append([]byte(nil), first...)
+			bad[i] ^= 0x40
+			if _, _, err := r.Open(bad); err == nil {
+				t.Errorf("%s: modified byte %d accepted", s.Name, i)
+			}
+		}
+		if r.Seq() != 0 {
+			t.Errorf("%s: sequence number changed on error", s.Name)
+		}
+		if _, _, err := r.Open(first); err != nil {
+			t.Errorf("%s: %v", s.Name, err)
+		}
+		// Повтор записи
+		if _, _, err := r.Open(first); !errors.Is(err, ErrBadRecordMAC) {
+			t.Errorf("%s: replayed record accepted", s.Name)
+		}
+		if _, _, err := r.Open(second[:HEADER_SIZE+2]); err == nil {
+			t.Errorf("%s: truncated record accepted", s.Name)
+		}
+	}
+}
+
+func TestRecordIV(t *testing.T) {
+	p, err := NewProtection(TLS_GOSTR341112_256_WITH_MAGMA_CTR_OMAC, testMacKey, testEncKey, []byte{0xff, 0xff, 0xff, 0xfe})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.seq = 3
+	if iv := p.recordIV(); !bytes.Equal(iv, []byte{0, 0, 0, 1}) {
+		t.Errorf("incorrect iv wrap %x", iv)
+	}
+	p.seq = 1<<32 + 1
+	if iv := p.recordIV(); !bytes.Equal(iv, []byte{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("incorrect iv %x", iv)
+	}
+
+	if _, err := NewProtection(0xC102, testMacKey, testEncKey, make([]byte, 8)); err == nil {
+		t.Error("unsupported suite accepted")
+	}
+	if _, err := NewProtection(TLS_GOSTR341112_256_WITH_KUZNYECHIK_CTR_OMAC, testMacKey, testEncKey, make([]byte, 4)); err == nil {
+		t.Error("invalid iv length accepted")
+	}
+	if _, err := p.Seal(23, VERSION_TLS12, make([]byte, MAX_PLAINTEXT+1)); err == nil {
+		t.Error("too long fragment accepted")
+	}
+}
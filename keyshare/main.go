@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"gost_magma_cbc/crypto/base/kuznyechik"
+	"gost_magma_cbc/crypto/base/magma"
+	"gost_magma_cbc/crypto/manage"
+	"gost_magma_cbc/crypto/models"
+	"gost_magma_cbc/crypto/shamir"
+	"os"
+	"path/filepath"
+)
+
+func usage() {
+	fmt.Println("Usage: " + os.Args[0] + " split [-n N] [-k K] [-base magma|kuznyechik] KEY OUTDIR")
+	fmt.Println("       " + os.Args[0] + " combine [-base magma|kuznyechik] KEY SHARE...")
+	fmt.Println("       " + os.Args[0] + " verify SHARE...")
+	os.Exit(1)
+}
+
+func baseAlgorithm(name string) (models.BaseAlgorithm, error) {
+	switch name {
+	case "magma":
+		return magma.NewMagma(), nil
+	case "kuznyechik":
+		return kuznyechik.NewKuznyechik(), nil
+	}
+	return nil, errors.New("unknown base algorithm: " + name)
+}
+
+// Зануление значений долей.
+func clearShares(shares []*shamir.Share) {
+	for _, s := range shares {
+		s.Value.SetInt64(0)
+	}
+}
+
+func readShares(paths []string) ([]*shamir.Share, error) {
+	shares := make([]*shamir.Share, 0, len(paths))
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			clearShares(shares)
+			return nil, err
+		}
+		s, err := shamir.UnmarshalShare(b)
+		clear(b)
+		if err == nil {
+			err = s.Verify()
+		}
+		if err != nil {
+			clearShares(shares)
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		fmt.Printf("%s: share %d of threshold %d OK\n", p, s.ID, s.Threshold)
+		shares = append(shares, s)
+	}
+	return shares, nil
+}
+
+func split(base models.BaseAlgorithm, path, dir string, n, k int) error {
+	km := manage.NewKeysManager(0)
+	key, err := km.GetNextKey(base, &manage.BuildData{File: &path}, manage.BuildFromFile)
+	if err != nil {
+		return err
+	}
+	defer km.Clear(key)
+	shares, err := shamir.Split(key, n, k, nil)
+	if err != nil {
+		return err
+	}
+	defer clearShares(shares)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for _, s := range shares {
+		p := filepath.Join(dir, fmt.Sprintf("share-%03d.bin", s.ID))
+		b := s.Marshal()
+		err := os.WriteFile(p, b, 0600)
+		clear(b)
+		if err != nil {
+			return err
+		}
+		fmt.Println(p)
+	}
+	fmt.Printf("Shares: %d, threshold: %d\n", n, k)
+	return nil
+}
+
+func combine(base models.BaseAlgorithm, path string, paths []string) error {
+	shares, err := readShares(paths)
+	if err != nil {
+		return err
+	}
+	defer clearShares(shares)
+	key := base.NewKey()
+	defer key.Clear()
+	if err := shamir.Combine(shares, key); err != nil {
+		return err
+	}
+	// Существующий файл ключа не перезаписывается
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(key.Data())
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// Частично записанный ключ удаляется
+		os.Remove(path)
+		return err
+	}
+	fmt.Println("OK")
+	return nil
+}
+
+// Разделение файла ключа на доли и восстановление ключа из долей.
+// Файл ключа содержит необработанные байты ключа и загружается
+// manage.BuildFromFile.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	n := flags.Int("n", 5, "number of shares")
+	k := flags.Int("k", 3, "threshold")
+	baseName := flags.String("base", "magma", "base algorithm of the key (magma or kuznyechik)")
+	flags.Parse(os.Args[2:])
+	base, err := baseAlgorithm(*baseName)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "split":
+		if flags.NArg() != 2 {
+			usage()
+		}
+		err = split(base, flags.Arg(0), flags.Arg(1), *n, *k)
+	case "combine":
+		if flags.NArg() < 2 {
+			usage()
+		}
+		err = combine(base, flags.Arg(0), flags.Args()[1:])
+	case "verify":
+		if flags.NArg() < 1 {
+			usage()
+		}
+		var shares []*shamir.Share
+		shares, err = readShares(flags.Args())
+		clearShares(shares)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+}
@@ -4,13 +4,38 @@ import (
 	"crypto/rand"
 	"fmt"
 	"gost_magma_cbc/crypto/drbg/hdrbg"
+	"gost_magma_cbc/crypto/drbg/seedfile"
 	"gost_magma_cbc/crypto/hash/streebog"
 	"gost_magma_cbc/crypto/manage"
+	"gost_magma_cbc/crypto/stattest"
 	"gost_magma_cbc/utils"
 	"os"
 	"time"
 )
 
+// Генератор режимов 1 и 3: с файлом заполнения, если он задан в конфигурации.
+func newDrbg(conf *utils.Config) (*hdrbg.DrbgPrng, *seedfile.SeedFile, error) {
+	newPrng := func(personalization []byte) (*hdrbg.DrbgPrng, error) {
+		return hdrbg.NewHashDrbgPrng(streebog.New256, rand.Reader, 32, hdrbg.SECURITY_LEVEL_TWO, personalization)
+	}
+	if len(conf.Lab3.SeedFile) == 0 {
+		d, err := newPrng(nil)
+		return d, nil, err
+	}
+
+	key_bdata := manage.BuildData{LEString: &conf.Lab3.SeedKey}
+	key, err := manage.BuildFrom(&key_bdata, manage.BuildFromLEString, seedfile.KEY_SIZE)
+	if err != nil {
+		return nil, nil, err
+	}
+	sf, err := seedfile.NewSeedFile(conf.Lab3.SeedFile, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := seedfile.Instantiate(sf, newPrng, nil)
+	return d, sf, err
+}
+
 func main() {
 	if len(os.Args) == 1 {
 		fmt.Println("Usage: " + os.Args[0] + " CONFIG")
@@ -55,7 +80,7 @@ func main() {
 		mod := conf.Lab3.BytesCount % conf.Lab3.Buffer
 
 		buff := make([]byte, conf.Lab3.Buffer)
-		d, err := hdrbg.NewHashDrbgPrng(streebog.New256, rand.Reader, 32, hdrbg.SECURITY_LEVEL_TWO, nil)
+		d, sf, err := newDrbg(conf)
 		if err != nil {
 			l.Fatal(err.Error())
 		}
@@ -70,6 +95,11 @@ func main() {
 		end := time.Now().UnixNano()
 		fmt.Printf("Processed time for %d: %f s -> %f s\n", conf.Lab3.BytesCount,
 			float64(end-start)/1000000000, float64(end-start)/(1000000000*float64(count)))
+		if sf != nil {
+			if err := sf.Save(d); err != nil {
+				l.Error("lab3: seed file: " + err.Error())
+			}
+		}
 	} else if conf.Lab3.Mode == 2 {
 		min := 1000
 		max := 10000
@@ -89,6 +119,40 @@ func main() {
 		end := time.Now().UnixNano()
 		fmt.Printf("Processed time for %d: %f s -> %f s\n", count,
 			float64(end-start)/1000000000, float64(end-start)/(1000000000*float64(count)))
+	} else if conf.Lab3.Mode == 3 {
+		d, sf, err := newDrbg(conf)
+		if err != nil {
+			l.Fatal(err.Error())
+		}
+		data := make([]byte, conf.Lab3.BytesCount)
+		_, err = d.Read(data)
+		if err != nil {
+			l.Fatal(err.Error())
+		}
+		if sf != nil {
+			if err := sf.Save(d); err != nil {
+				l.Error("lab3: seed file: " + err.Error())
+			}
+		}
+		if len(conf.Lab3.FileName) > 0 {
+			err = os.WriteFile(conf.Lab3.FileName, data, 0666)
+			if err != nil {
+				l.Fatal(err.Error())
+			}
+		}
+
+		start := time.Now().UnixNano()
+		report := stattest.Run(stattest.BitsFromBytes(data), conf.Lab3.Alpha, stattest.DefaultTests())
+		end := time.Now().UnixNano()
+		report.Summary(os.Stdout)
+		fmt.Printf("Processed time for %d: %f s\n", conf.Lab3.BytesCount, float64(end-start)/1000000000)
+		switch {
+		case report.Passed():
+		case report.Inconclusive():
+			l.Error("lab3: statistical tests inconclusive")
+		default:
+			l.Error("lab3: statistical tests failed")
+		}
 	} else {
 		l.Error("main: unknown method")
 
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"gost_magma_cbc/crypto/manage"
+	"gost_magma_cbc/utils"
+	"os"
+	"path/filepath"
+)
+
+func usage() {
+	fmt.Println("Usage: " + os.Args[0] + " generate [-size 256|512] [-key HEX] ROOT MANIFEST")
+	fmt.Println("       " + os.Args[0] + " verify [-key HEX] ROOT MANIFEST")
+	os.Exit(1)
+}
+
+// Путь к файлу списка относительно root, если он лежит внутри root.
+func excludeManifest(root, manifest string) []string {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	absManifest, err := filepath.Abs(manifest)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(absRoot, absManifest)
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.ToSlash(rel)}
+}
+
+func buildKey(s string) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	bdata := manage.BuildData{LEString: &s}
+	key, err := manage.BuildFrom(&bdata, manage.BuildFromLEString, 32)
+	if err != nil {
+		fmt.Println("key: " + err.Error())
+		os.Exit(2)
+	}
+	return key
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	size := flags.Int("size", 256, "hash size in bits (256 or 512)")
+	keyHex := flags.String("key", "", "HMAC key as little endian hex string (32 bytes)")
+	flags.Parse(os.Args[2:])
+	if flags.NArg() != 2 {
+		usage()
+	}
+	root, path := flags.Arg(0), flags.Arg(1)
+	key := buildKey(*keyHex)
+	// Размер хэша при проверке берётся из заголовка списка
+	if os.Args[1] == "verify" {
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name == "size" {
+				fmt.Println("verify: -size is not supported, hash size is read from the manifest")
+				os.Exit(1)
+			}
+		})
+	}
+
+	switch os.Args[1] {
+	case "generate":
+		m, err := utils.BuildManifest(root, *size, excludeManifest(root, path))
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		err = m.Encode(file, key)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		fmt.Printf("Files: %d\n", len(m.Entries))
+	case "verify":
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		m, err := utils.ReadManifest(file, key)
+		file.Close()
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		diff, err := utils.VerifyManifest(root, m, excludeManifest(root, path))
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(2)
+		}
+		for _, p := range diff.Added {
+			fmt.Println("added: " + p)
+		}
+		for _, p := range diff.Missing {
+			fmt.Println("missing: " + p)
+		}
+		for _, p := range diff.Modified {
+			fmt.Println("modified: " + p)
+		}
+		if !diff.Empty() {
+			os.Exit(3)
+		}
+		fmt.Println("OK")
+	default:
+		usage()
+	}
+}
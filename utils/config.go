@@ -37,6 +37,11 @@ type LabThird struct {
 	FileName   string
 	Buffer     int
 	Mode       int
+	// Уровень значимости статистических тестов (режим 3)
+	Alpha float64
+	// Файл заполнения генератора и его ключ (LE), пусто - не используется
+	SeedFile string
+	SeedKey  string
 }
 
 type Config struct {
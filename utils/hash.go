@@ -8,19 +8,32 @@ import (
 )
 
 func CheckFile(ht []byte, path string) (bool, error) {
-	file, err := os.Open(path)
+	h, _, err := HashFile(path, 256)
 	if err != nil {
 		return false, err
 	}
-	defer file.Close()
 
-	hash := streebog.New256()
+	return subtle.ConstantTimeCompare(h, ht) == 1, nil
+}
 
-	if _, err := io.Copy(hash, file); err != nil {
-		return false, err
+// Вычисление хэша Стрибог (256 или 512 бит) содержимого файла.
+// Возвращает хэш и размер файла в байтах.
+func HashFile(path string, hashSize int) ([]byte, int64, error) {
+	hash, err := streebog.New(hashSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
 	}
+	defer file.Close()
 
-	h := hash.Sum(nil)
+	n, err := io.Copy(hash, file)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return subtle.ConstantTimeCompare(h, ht) == 1, nil
+	return hash.Sum(nil), n, nil
 }
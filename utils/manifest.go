@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gost_magma_cbc/crypto/hash/hmac"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	manifestHeader = "# streebog"
+	manifestHMAC   = "# hmac "
+)
+
+// Запись списка контрольных сумм.
+type ManifestEntry struct {
+	Path   string
+	Size   int64
+	Digest []byte
+}
+
+// Список контрольных сумм файлов дерева каталогов.
+// Пути хранятся относительно корня и с разделителем '/'.
+type Manifest struct {
+	HashSize int
+	Entries  []ManifestEntry
+}
+
+// Результат сравнения двух списков контрольных сумм.
+type ManifestDiff struct {
+	Added    []string
+	Missing  []string
+	Modified []string
+}
+
+func (d *ManifestDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Missing) == 0 && len(d.Modified) == 0
+}
+
+// Построение списка контрольных сумм для всех обычных файлов в root.
+// Файлы из exclude (пути относительно root) пропускаются.
+func BuildManifest(root string, hashSize int, exclude []string) (*Manifest, error) {
+	if hashSize != 256 && hashSize != 512 {
+		return nil, errors.New("manifest: incorrect hash size")
+	}
+	m := &Manifest{HashSize: hashSize}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if slices.Contains(exclude, rel) {
+			return nil
+		}
+
+		digest, size, err := HashFile(path, hashSize)
+		if err != nil {
+			return err
+		}
+		m.Entries = append(m.Entries, ManifestEntry{Path: rel, Size: size, Digest: digest})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Запись списка в текстовом виде. Если key не пуст, в конец добавляется
+// HMAC (Стрибог-256) от всего предыдущего содержимого.
+// Пути с переводом строки не могут быть записаны однозначно и отклоняются.
+func (m *Manifest) Encode(w io.Writer, key []byte) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s%d\n", manifestHeader, m.HashSize)
+	for _, e := range m.Entries {
+		if strings.ContainsAny(e.Path, "\r\n") {
+			return fmt.Errorf("manifest: line break in path %q", e.Path)
+		}
+		fmt.Fprintf(&buf, "%x %d %s\n", e.Digest, e.Size, e.Path)
+	}
+
+	if len(key) > 0 {
+		mac, err := hmac.NewHMAC256().Sum(key, buf.Bytes())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "%s%x\n", manifestHMAC, mac)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Чтение списка контрольных сумм. Если key не пуст, список обязан
+// содержать корректный HMAC.
+func ReadManifest(r io.Reader, key []byte) (*Manifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	body := data
+	if i := bytes.LastIndex(data, []byte(manifestHMAC)); i >= 0 && (i == 0 || data[i-1] == '\n') {
+		body = data[:i]
+		if len(key) > 0 {
+			mac, err := hex.DecodeString(strings.TrimSpace(string(data[i+len(manifestHMAC):])))
+			if err != nil {
+				return nil, errors.New("manifest: incorrect hmac")
+			}
+			check, err := hmac.NewHMAC256().Sum(key, body)
+			if err != nil {
+				return nil, err
+			}
+			if subtle.ConstantTimeCompare(mac, check) != 1 {
+				return nil, errors.New("manifest: hmac check fail")
+			}
+		}
+	} else if len(key) > 0 {
+		return nil, errors.New("manifest: hmac not found")
+	}
+
+	m := &Manifest{}
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	line := 0
+	for sc.Scan() {
+		line++
+		s := sc.Text()
+		if line == 1 {
+			if !strings.HasPrefix(s, manifestHeader) {
+				return nil, errors.New("manifest: unknown format")
+			}
+			m.HashSize, err = strconv.Atoi(s[len(manifestHeader):])
+			if err != nil || (m.HashSize != 256 && m.HashSize != 512) {
+				return nil, errors.New("manifest: incorrect hash size")
+			}
+			continue
+		}
+		if len(s) == 0 {
+			continue
+		}
+
+		parts := strings.SplitN(s, " ", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("manifest: incorrect line %d", line)
+		}
+		digest, err := hex.DecodeString(parts[0])
+		if err != nil || len(digest) != m.HashSize/8 {
+			return nil, fmt.Errorf("manifest: incorrect digest in line %d", line)
+		}
+		size, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("manifest: incorrect size in line %d", line)
+		}
+		m.Entries = append(m.Entries, ManifestEntry{Path: parts[2], Size: size, Digest: digest})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, errors.New("manifest: empty data")
+	}
+
+	return m, nil
+}
+
+// Сравнение ожидаемого списка с фактическим.
+func CompareManifest(expected, actual *Manifest) *ManifestDiff {
+	diff := &ManifestDiff{}
+	exp := make(map[string]*ManifestEntry, len(expected.Entries))
+	for i := range expected.Entries {
+		exp[expected.Entries[i].Path] = &expected.Entries[i]
+	}
+
+	for i := range actual.Entries {
+		a := &actual.Entries[i]
+		e, ok := exp[a.Path]
+		if !ok {
+			diff.Added = append(diff.Added, a.Path)
+			continue
+		}
+		delete(exp, a.Path)
+		if e.Size != a.Size || subtle.ConstantTimeCompare(e.Digest, a.Digest) != 1 {
+			diff.Modified = append(diff.Modified, a.Path)
+		}
+	}
+	for p := range exp {
+		diff.Missing = append(diff.Missing, p)
+	}
+
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Missing)
+	slices.Sort(diff.Modified)
+	return diff
+}
+
+// Проверка дерева каталогов root по ожидаемому списку.
+func VerifyManifest(root string, expected *Manifest, exclude []string) (*ManifestDiff, error) {
+	actual, err := BuildManifest(root, expected.HashSize, exclude)
+	if err != nil {
+		return nil, err
+	}
+	return CompareManifest(expected, actual), nil
+}
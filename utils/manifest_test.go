@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path string, data string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "a.txt"), "first file")
+	writeTestFile(t, filepath.Join(root, "dir", "b.txt"), "second file")
+	writeTestFile(t, filepath.Join(root, "dir", "sub", "c d.txt"), "third file")
+
+	for _, size := range []int{256, 512} {
+		m, err := BuildManifest(root, size, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(m.Entries) != 3 {
+			t.Fatalf("[%d] entries count is %d, not 3", size, len(m.Entries))
+		}
+
+		var buf bytes.Buffer
+		if err := m.Encode(&buf, nil); err != nil {
+			t.Fatal(err)
+		}
+		r, err := ReadManifest(&buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := CompareManifest(m, r); !diff.Empty() {
+			t.Errorf("[%d] decoded manifest differs: %+v", size, diff)
+		}
+	}
+}
+
+func TestManifestDiff(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "keep.txt"), "keep")
+	writeTestFile(t, filepath.Join(root, "change.txt"), "before")
+	writeTestFile(t, filepath.Join(root, "dir", "remove.txt"), "remove")
+
+	m, err := BuildManifest(root, 256, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, filepath.Join(root, "change.txt"), "after!")
+	writeTestFile(t, filepath.Join(root, "dir", "new.txt"), "new")
+	if err := os.Remove(filepath.Join(root, "dir", "remove.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := VerifyManifest(root, m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(diff.Added, []string{"dir/new.txt"}) {
+		t.Errorf("added is %v", diff.Added)
+	}
+	if !slices.Equal(diff.Missing, []string{"dir/remove.txt"}) {
+		t.Errorf("missing is %v", diff.Missing)
+	}
+	if !slices.Equal(diff.Modified, []string{"change.txt"}) {
+		t.Errorf("modified is %v", diff.Modified)
+	}
+}
+
+func TestManifestHMAC(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, filepath.Join(root, "a.txt"), "data")
+
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	m, err := BuildManifest(root, 512, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := m.Encode(&buf, key); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	if _, err := ReadManifest(bytes.NewReader(data), key); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := bytes.Clone(data)
+	tampered[len("# streebog512\n")] ^= 0x01
+	if _, err := ReadManifest(bytes.NewReader(tampered), key); err == nil {
+		t.Error("tampered manifest accepted")
+	}
+
+	wrong := bytes.Clone(key)
+	wrong[0] ^= 0xff
+	if _, err := ReadManifest(bytes.NewReader(data), wrong); err == nil {
+		t.Error("manifest accepted with wrong key")
+	}
+
+	var plain bytes.Buffer
+	if err := m.Encode(&plain, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadManifest(&plain, key); err == nil {
+		t.Error("manifest without hmac accepted")
+	}
+}
+
+func TestManifestLineBreak(t *testing.T) {
+	for _, p := range []string{"a\nb.txt", "a.txt\r"} {
+		m := &Manifest{HashSize: 256, Entries: []ManifestEntry{{Path: p, Digest: make([]byte, 32)}}}
+		var buf bytes.Buffer
+		if err := m.Encode(&buf, nil); err == nil {
+			t.Errorf("path %q accepted", p)
+		}
+	}
+}